@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/itstarsun/go-thrift/encoding/thriftbinary"
+	"github.com/itstarsun/go-thrift/encoding/thriftcompact"
+	"github.com/itstarsun/go-thrift/encoding/thriftframed"
+	"github.com/itstarsun/go-thrift/encoding/thriftheader"
+	"github.com/itstarsun/go-thrift/encoding/thriftjson"
+	"github.com/itstarsun/go-thrift/encoding/thriftwire"
+	"github.com/itstarsun/go-thrift/thrift"
+	"github.com/itstarsun/go-thrift/tools/thriftschema"
+)
+
+// A caller calls methods described by a schema.
+type caller struct {
+	schema      *thriftschema.Schema
+	protocol    string
+	transport   string
+	multiplexed bool
+	httpClient  *http.Client // nil for http.DefaultClient
+}
+
+// An exceptionError is returned by caller.call
+// if the method throws a declared exception.
+type exceptionError struct {
+	method    string
+	exception string
+}
+
+func (e *exceptionError) Error() string {
+	return fmt.Sprintf("%s threw %s", e.method, e.exception)
+}
+
+// call calls the method named by name at address with the JSON object args,
+// and prints the result, or the declared exception, as JSON to out.
+func (c *caller) call(ctx context.Context, out io.Writer, address, name, args string) error {
+	m, err := c.schema.Method(name)
+	if err != nil {
+		return err
+	}
+	// The service is registered by its unqualified name,
+	// even if it is defined in an included file.
+	service := name[:strings.LastIndexByte(name, '.')]
+	service = service[strings.LastIndexByte(service, '.')+1:]
+
+	d := json.NewDecoder(strings.NewReader(args))
+	d.UseNumber()
+	var fields map[string]any
+	if err := d.Decode(&fields); err != nil {
+		return fmt.Errorf("invalid args: %w", err)
+	}
+
+	isHTTP := strings.HasPrefix(address, "http://") || strings.HasPrefix(address, "https://")
+	p, err := c.newProtocol(isHTTP)
+	if err != nil {
+		return err
+	}
+	if c.multiplexed {
+		p = thrift.MultiplexedProtocol(p, service)
+	}
+	var client thrift.Client
+	if isHTTP {
+		client = &thrift.HTTPClient{URL: address, Protocol: p, Client: c.httpClient}
+	} else {
+		network := "tcp"
+		if path, ok := strings.CutPrefix(address, "unix:"); ok {
+			network, address = "unix", path
+		}
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, network, address)
+		if err != nil {
+			return err
+		}
+		cc := thrift.NewClient(conn, p)
+		defer cc.Close()
+		client = cc
+	}
+
+	argsValue := &thriftschema.Value{Struct: m.Args(), Fields: fields}
+	if m.OneWay() {
+		return client.Call(ctx, m.Name(), argsValue, nil)
+	}
+	result := &thriftschema.Value{Struct: m.Result()}
+	if err := client.Call(ctx, m.Name(), argsValue, result); err != nil {
+		return err
+	}
+	success := m.Result().FieldByID(0)
+	for k, v := range result.Fields {
+		if success != nil && k == success.Name() {
+			return printJSON(out, v)
+		}
+		if err := printJSON(out, map[string]any{k: v}); err != nil {
+			return err
+		}
+		return &exceptionError{method: name, exception: k}
+	}
+	if success != nil {
+		return fmt.Errorf("reply to %s has no result", name)
+	}
+	return nil // void
+}
+
+func printJSON(out io.Writer, v any) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(out, "%s\n", b)
+	return err
+}
+
+// newProtocol returns the protocol and transport of the caller.
+func (c *caller) newProtocol(isHTTP bool) (thriftwire.Protocol, error) {
+	var p thriftwire.Protocol
+	switch c.protocol {
+	case "binary":
+		p = thriftbinary.Protocol
+	case "compact":
+		p = thriftcompact.Protocol
+	case "json":
+		p = thriftjson.Protocol
+	case "header":
+		return &thriftheader.Protocol{}, nil
+	default:
+		return nil, fmt.Errorf("unknown protocol %s", c.protocol)
+	}
+	switch c.transport {
+	case "buffered":
+	case "framed":
+		if !isHTTP {
+			p = &thriftframed.Protocol{Protocol: p}
+		}
+	default:
+		return nil, fmt.Errorf("unknown transport %s", c.transport)
+	}
+	return p, nil
+}
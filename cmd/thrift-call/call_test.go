@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/itstarsun/go-thrift/encoding/thriftbinary"
+	"github.com/itstarsun/go-thrift/encoding/thriftcompact"
+	"github.com/itstarsun/go-thrift/encoding/thriftframed"
+	"github.com/itstarsun/go-thrift/encoding/thriftheader"
+	"github.com/itstarsun/go-thrift/encoding/thriftwire"
+	"github.com/itstarsun/go-thrift/thrift"
+	"github.com/itstarsun/go-thrift/tools/thriftschema"
+)
+
+const testIDL = "../../tools/thriftschema/testdata/store.thrift"
+
+type item struct {
+	ID   [16]byte `thrift:"1,required"`
+	Name string   `thrift:"2"`
+}
+
+type notFound struct {
+	ID      [16]byte `thrift:"1"`
+	Message string   `thrift:"2"`
+}
+
+type getArgs struct {
+	ID [16]byte `thrift:"1"`
+}
+
+type getResult struct {
+	Success  *item     `thrift:"0"`
+	NotFound *notFound `thrift:"1"`
+}
+
+type putArgs struct {
+	Item *item `thrift:"1"`
+}
+
+type putResult struct{}
+
+var known = [16]byte{15: 1}
+
+type touchArgs struct {
+	ID [16]byte `thrift:"1"`
+}
+
+// newStore returns a processor of the methods get, put and touch of Store,
+// and a channel receiving the items put and the IDs touched.
+func newStore() (*thrift.Processor, <-chan *item) {
+	puts := make(chan *item, 1)
+	p := new(thrift.Processor)
+	p.Handle("get", (*getArgs)(nil), (*getResult)(nil), func(ctx context.Context, args, result any) error {
+		id := args.(*getArgs).ID
+		if id != known {
+			result.(*getResult).NotFound = &notFound{ID: id, Message: "no such item"}
+			return nil
+		}
+		result.(*getResult).Success = &item{ID: id, Name: "known"}
+		return nil
+	})
+	p.Handle("put", (*putArgs)(nil), (*putResult)(nil), func(ctx context.Context, args, result any) error {
+		puts <- args.(*putArgs).Item
+		return nil
+	})
+	p.Handle("touch", (*touchArgs)(nil), nil, func(ctx context.Context, args, result any) error {
+		puts <- &item{ID: args.(*touchArgs).ID}
+		return nil
+	})
+	return p, puts
+}
+
+func serve(t *testing.T, network string, s *thrift.Server) string {
+	t.Helper()
+	addr := "127.0.0.1:0"
+	if network == "unix" {
+		addr = filepath.Join(t.TempDir(), "sock")
+	}
+	l, err := net.Listen(network, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Serve(l)
+	t.Cleanup(func() { s.Close() })
+	if network == "unix" {
+		return "unix:" + addr
+	}
+	return l.Addr().String()
+}
+
+func newCaller(t *testing.T) *caller {
+	t.Helper()
+	s, err := thriftschema.Load(testIDL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &caller{schema: s, protocol: "binary", transport: "buffered"}
+}
+
+const (
+	knownID   = `{"id": "00000000-0000-0000-0000-000000000001"}`
+	unknownID = `{"id": "00000000-0000-0000-0000-000000000002"}`
+)
+
+func TestCall(t *testing.T) {
+	ctx := context.Background()
+	for _, tt := range []struct {
+		name      string
+		network   string
+		protocol  thriftwire.Protocol
+		c         caller
+		multiplex bool
+	}{
+		{"binary", "tcp", thriftbinary.Protocol, caller{protocol: "binary", transport: "buffered"}, false},
+		{"compact framed unix", "unix", &thriftframed.Protocol{Protocol: thriftcompact.Protocol}, caller{protocol: "compact", transport: "framed"}, false},
+		{"header", "tcp", &thriftheader.Protocol{}, caller{protocol: "header", transport: "buffered"}, false},
+		{"multiplexed", "tcp", thriftbinary.Protocol, caller{protocol: "binary", transport: "buffered", multiplexed: true}, true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var p thrift.MessageProcessor
+			store, puts := newStore()
+			p = store
+			if tt.multiplex {
+				m := new(thrift.MultiplexedProcessor)
+				m.Register("Store", store)
+				p = m
+			}
+			addr := serve(t, tt.network, &thrift.Server{Processor: p, Protocol: tt.protocol})
+			c := newCaller(t)
+			c.protocol, c.transport, c.multiplexed = tt.c.protocol, tt.c.transport, tt.c.multiplexed
+
+			var out bytes.Buffer
+			if err := c.call(ctx, &out, addr, "Store.get", knownID); err != nil {
+				t.Fatal(err)
+			}
+			const want = "{\n  \"id\": \"00000000-0000-0000-0000-000000000001\",\n  \"name\": \"known\"\n}\n"
+			if out.String() != want {
+				t.Errorf("got %s, want %s", out.String(), want)
+			}
+
+			out.Reset()
+			err := c.call(ctx, &out, addr, "Store.get", unknownID)
+			var exc *exceptionError
+			if !errors.As(err, &exc) || exc.exception != "notFound" {
+				t.Fatalf("got %v, want notFound", err)
+			}
+			const wantExc = "{\n  \"notFound\": {\n    \"id\": \"00000000-0000-0000-0000-000000000002\",\n    \"message\": \"no such item\"\n  }\n}\n"
+			if out.String() != wantExc {
+				t.Errorf("got %s, want %s", out.String(), wantExc)
+			}
+
+			out.Reset()
+			if err := c.call(ctx, &out, addr, "Store.put", `{"item": {"id": "00000000-0000-0000-0000-000000000003", "name": "new"}}`); err != nil {
+				t.Fatal(err)
+			}
+			if out.Len() != 0 {
+				t.Errorf("void method printed %s", out.String())
+			}
+			if got := <-puts; got.Name != "new" || got.ID != [16]byte{15: 3} {
+				t.Errorf("put %+v", got)
+			}
+
+			if err := c.call(ctx, &out, addr, "Store.touch", knownID); err != nil {
+				t.Fatal(err)
+			}
+			if got := <-puts; got.ID != known {
+				t.Errorf("touched %x", got.ID)
+			}
+		})
+	}
+}
+
+func TestCallHTTP(t *testing.T) {
+	store, _ := newStore()
+	srv := httptest.NewServer(&thrift.HTTPHandler{Processor: store, Protocol: thriftbinary.Protocol})
+	defer srv.Close()
+
+	c := newCaller(t)
+	var out bytes.Buffer
+	if err := c.call(context.Background(), &out, srv.URL, "Store.get", knownID); err != nil {
+		t.Fatal(err)
+	}
+	if out.Len() == 0 {
+		t.Errorf("no result")
+	}
+}
+
+func TestCallErrors(t *testing.T) {
+	store, _ := newStore()
+	addr := serve(t, "tcp", &thrift.Server{Processor: store, Protocol: thriftbinary.Protocol})
+	c := newCaller(t)
+	ctx := context.Background()
+	for _, tt := range []struct {
+		method, args string
+	}{
+		{"Store.nope", `{}`},
+		{"get", `{}`},
+		{"Store.get", `[]`},
+		{"Store.get", `{"id": 1}`},
+		{"Store.ping", `{}`}, // unknown to the server
+	} {
+		if err := c.call(ctx, new(bytes.Buffer), addr, tt.method, tt.args); err == nil {
+			t.Errorf("%s(%s) succeeded", tt.method, tt.args)
+		}
+	}
+}
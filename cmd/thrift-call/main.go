@@ -0,0 +1,120 @@
+// Thrift-call calls a method of a Thrift service described by a .thrift file.
+//
+// Usage:
+//
+//	thrift-call [flags] address Service.method [args]
+//
+// Thrift-call builds the arguments of the method from the JSON object args,
+// or the standard input if args is "-", keyed by the names of the parameters
+// as described by the thriftschema package, calls the method at address,
+// and prints the result as JSON:
+//
+//	thrift-call -idl store.thrift localhost:9090 Store.get '{"id": "..."}'
+//
+// The address is either host:port for TCP, unix:path for a Unix socket,
+// or an http:// or https:// URL. If the method throws an exception declared
+// by the .thrift file, it is printed as a JSON object keyed by the name of
+// the exception, and thrift-call exits with status 1.
+//
+// The flags are:
+//
+//	-idl
+//		.thrift file declaring the service; required
+//	-I
+//		directory to search for included files; may be repeated
+//	-protocol
+//		binary, compact, json or header; default binary
+//	-transport
+//		buffered or framed, ignored by header and HTTP; default buffered
+//	-multiplexed
+//		prefix the method with the service name for multiplexed servers
+//	-timeout
+//		timeout of the call; default 10s
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/itstarsun/go-thrift/tools/thriftschema"
+)
+
+var (
+	idl         = flag.String("idl", "", ".thrift file declaring the service")
+	protocol    = flag.String("protocol", "binary", "binary, compact, json or header")
+	transport   = flag.String("transport", "buffered", "buffered or framed, ignored by header and HTTP")
+	multiplexed = flag.Bool("multiplexed", false, "prefix the method with the service name for multiplexed servers")
+	timeout     = flag.Duration("timeout", 10*time.Second, "timeout of the call")
+	includeDirs stringsFlag
+)
+
+func init() {
+	flag.Var(&includeDirs, "I", "directory to search for included files; may be repeated")
+}
+
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return fmt.Sprint(*f)
+}
+
+func (f *stringsFlag) Set(s string) error {
+	*f = append(*f, s)
+	return nil
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: thrift-call [flags] address Service.method [args]\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("thrift-call: ")
+	flag.Usage = usage
+	flag.Parse()
+	if *idl == "" || flag.NArg() < 2 || flag.NArg() > 3 {
+		usage()
+		os.Exit(2)
+	}
+
+	schema, err := thriftschema.Load(*idl, includeDirs...)
+	if err != nil {
+		log.Fatal(err)
+	}
+	c := &caller{
+		schema:      schema,
+		protocol:    *protocol,
+		transport:   *transport,
+		multiplexed: *multiplexed,
+	}
+	args := "{}"
+	switch {
+	case flag.Arg(2) == "-":
+		b, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			log.Fatal(err)
+		}
+		args = string(b)
+	case flag.NArg() == 3:
+		args = flag.Arg(2)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	err = c.call(ctx, os.Stdout, flag.Arg(0), flag.Arg(1), args)
+	var exc *exceptionError
+	if errors.As(err, &exc) {
+		log.Print(err)
+		os.Exit(1)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
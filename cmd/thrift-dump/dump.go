@@ -0,0 +1,349 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/itstarsun/go-thrift/encoding/thriftbinary"
+	"github.com/itstarsun/go-thrift/encoding/thriftcompact"
+	"github.com/itstarsun/go-thrift/encoding/thriftframed"
+	"github.com/itstarsun/go-thrift/encoding/thriftheader"
+	"github.com/itstarsun/go-thrift/encoding/thriftjson"
+	"github.com/itstarsun/go-thrift/encoding/thriftsimplejson"
+	"github.com/itstarsun/go-thrift/encoding/thriftwire"
+	"github.com/itstarsun/go-thrift/thrift"
+	"github.com/itstarsun/go-thrift/tools/thriftschema"
+)
+
+// protocolByName returns the protocol with the given name,
+// or nil for "auto".
+func protocolByName(name string, framed bool) (thriftwire.Protocol, error) {
+	var p thriftwire.Protocol
+	switch name {
+	case "auto":
+		if framed {
+			return nil, errors.New("-framed requires -protocol")
+		}
+		return nil, nil
+	case "binary":
+		p = thriftbinary.Protocol
+	case "compact":
+		p = thriftcompact.Protocol
+	case "json":
+		p = thriftjson.Protocol
+	case "simplejson":
+		p = thriftsimplejson.Protocol
+	case "header":
+		if framed {
+			return nil, errors.New("the header protocol is already framed")
+		}
+		return &thriftheader.Protocol{}, nil
+	default:
+		return nil, fmt.Errorf("unknown protocol %s", name)
+	}
+	if framed {
+		p = &thriftframed.Protocol{Protocol: p}
+	}
+	return p, nil
+}
+
+// A dumper decodes and encodes payloads.
+type dumper struct {
+	protocol thriftwire.Protocol // nil to detect the protocol
+	schema   *thriftschema.Schema
+	service  string
+	bare     bool
+	typ      *thriftschema.Struct // of bare structs
+	json     bool
+}
+
+// schemaless reports whether the payloads are not described by the schema,
+// and JSON is in the Thrift JSON protocol.
+func (d *dumper) schemaless() bool {
+	return d.schema == nil || (d.bare && d.typ == nil)
+}
+
+// forEach calls f until the end of the input read by br.
+func forEach(p thriftwire.Protocol, br *bufio.Reader, f func() error) error {
+	// The JSON decoder of the simple JSON protocol reads ahead,
+	// but reports io.EOF only at the end of the input between values.
+	buffered := p == thriftsimplejson.Protocol
+	for {
+		if !buffered && atEOF(br, p == thriftjson.Protocol) {
+			return nil
+		}
+		switch err := f(); {
+		case err == io.EOF && buffered:
+			return nil
+		case err == io.EOF:
+			return io.ErrUnexpectedEOF
+		case err != nil:
+			return err
+		}
+	}
+}
+
+// atEOF reports whether br is at the end of the input,
+// skipping whitespace if space is true.
+func atEOF(br *bufio.Reader, space bool) bool {
+	for {
+		c, err := br.ReadByte()
+		if err != nil {
+			return err == io.EOF
+		}
+		if !space || (c != ' ' && c != '\t' && c != '\n' && c != '\r') {
+			br.UnreadByte()
+			return false
+		}
+	}
+}
+
+// dump prints the payloads read from in to out.
+func (d *dumper) dump(out *bufio.Writer, in io.Reader) error {
+	br := bufio.NewReader(in)
+	p := d.protocol
+	if p == nil {
+		if d.bare {
+			return errors.New("bare structs require -protocol")
+		}
+		var err error
+		if p, err = thriftwire.DetectProtocol(br); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+	}
+	r := p.NewReader(br)
+	var jw thriftwire.Writer // for printing in the Thrift JSON protocol
+	if d.json && d.schemaless() {
+		jw = thriftjson.Protocol.NewWriter(out)
+	}
+	return forEach(p, br, func() error {
+		if d.bare {
+			return d.dumpStruct(out, r, jw)
+		}
+		return d.dumpMessage(out, r, jw)
+	})
+}
+
+func (d *dumper) dumpStruct(out *bufio.Writer, r thriftwire.Reader, jw thriftwire.Writer) error {
+	switch {
+	case jw != nil:
+		if err := thriftwire.Copy(jw, r, thriftwire.Struct); err != nil {
+			return err
+		}
+		if err := jw.Flush(); err != nil {
+			return err
+		}
+		return out.WriteByte('\n')
+	case d.json:
+		v, err := d.typ.Unmarshal(r)
+		if err != nil {
+			return err
+		}
+		return printJSON(out, v)
+	}
+	v, err := thriftwire.ReadValue(r, thriftwire.Struct)
+	if err != nil {
+		return err
+	}
+	name := "struct"
+	if d.typ != nil {
+		name = d.typ.Name()
+	}
+	fmt.Fprintln(out, name)
+	printFields(out, 1, v, d.typ)
+	return nil
+}
+
+func (d *dumper) dumpMessage(out *bufio.Writer, r thriftwire.Reader, jw thriftwire.Writer) error {
+	if jw != nil {
+		if err := thriftwire.CopyMessage(jw, r); err != nil {
+			return err
+		}
+		return out.WriteByte('\n')
+	}
+	h, err := r.ReadMessageBegin()
+	if err != nil {
+		return err
+	}
+	st, err := d.messageStruct(h)
+	if err != nil && (d.json || !errors.Is(err, errUnknownMethod)) {
+		return err
+	}
+	if d.json {
+		var body any
+		if st == nil { // an exception
+			var exc thrift.ApplicationError
+			if err := thrift.Unmarshal(r, &exc); err != nil {
+				return err
+			}
+			body = map[string]any{"message": exc.Message, "type": int32(exc.Type)}
+		} else if body, err = st.Unmarshal(r); err != nil {
+			return err
+		}
+		if err := r.ReadMessageEnd(); err != nil {
+			return err
+		}
+		return printJSON(out, message{
+			Name:  h.Name,
+			Type:  strings.ToLower(h.Type.String()),
+			SeqID: h.ID,
+			Body:  body,
+		})
+	}
+
+	v, err := thriftwire.ReadValue(r, thriftwire.Struct)
+	if err != nil {
+		return err
+	}
+	if err := r.ReadMessageEnd(); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "%s %s seqid=%d\n", strings.ToLower(h.Type.String()), h.Name, h.ID)
+	printFields(out, 1, v, st)
+	return nil
+}
+
+// A message is the JSON form of a message with -idl.
+type message struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	SeqID int32  `json:"seqid"`
+	Body  any    `json:"body"`
+}
+
+func printJSON(out *bufio.Writer, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	out.Write(b)
+	return out.WriteByte('\n')
+}
+
+var errUnknownMethod = errors.New("unknown method")
+
+// messageStruct returns the struct of the body of a message with the header h
+// from the schema, or nil for exceptions.
+func (d *dumper) messageStruct(h thriftwire.MessageHeader) (*thriftschema.Struct, error) {
+	if d.schema == nil || h.Type == thriftwire.Exception {
+		return nil, nil
+	}
+	name, service := h.Name, d.service
+	if s, m, ok := strings.Cut(name, ":"); ok { // multiplexed
+		service, name = s, m
+	}
+	var m *thriftschema.Method
+	if service != "" {
+		svc, err := d.schema.Service(service)
+		if err != nil {
+			return nil, err
+		}
+		m = svc.Method(name)
+	} else {
+		for _, svc := range d.schema.Services() {
+			if m = svc.Method(name); m != nil {
+				break
+			}
+		}
+	}
+	if m == nil {
+		return nil, fmt.Errorf("%w %s", errUnknownMethod, h.Name)
+	}
+	switch h.Type {
+	case thriftwire.Call, thriftwire.OneWay:
+		return m.Args(), nil
+	case thriftwire.Reply:
+		if m.Result() == nil {
+			return nil, fmt.Errorf("reply to one-way method %s", h.Name)
+		}
+		return m.Result(), nil
+	}
+	return nil, thriftwire.InvalidMessageTypeError(h.Type)
+}
+
+// encode encodes the JSON read from in into out.
+func (d *dumper) encode(out *bufio.Writer, in io.Reader) error {
+	w := d.protocol.NewWriter(out)
+	if d.schemaless() {
+		br := bufio.NewReader(in)
+		r := thriftjson.Protocol.NewReader(br)
+		return forEach(thriftjson.Protocol, br, func() error {
+			if d.bare {
+				if err := thriftwire.Copy(w, r, thriftwire.Struct); err != nil {
+					return err
+				}
+				return w.Flush()
+			}
+			return thriftwire.CopyMessage(w, r)
+		})
+	}
+
+	dec := json.NewDecoder(in)
+	dec.UseNumber()
+	for {
+		var err error
+		if d.bare {
+			var v any
+			if err = dec.Decode(&v); err == nil {
+				err = d.typ.Marshal(w, v)
+			}
+		} else {
+			var m message
+			if err = dec.Decode(&m); err == nil {
+				err = d.encodeMessage(w, m)
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+}
+
+func (d *dumper) encodeMessage(w thriftwire.Writer, m message) error {
+	h := thriftwire.MessageHeader{Name: m.Name, ID: m.SeqID}
+	for t := thriftwire.Call; t <= thriftwire.OneWay; t++ {
+		if strings.ToLower(t.String()) == m.Type {
+			h.Type = t
+		}
+	}
+	if h.Type == 0 {
+		return fmt.Errorf("invalid message type %q", m.Type)
+	}
+	st, err := d.messageStruct(h)
+	if err != nil {
+		return err
+	}
+	if err := w.WriteMessageBegin(h); err != nil {
+		return err
+	}
+	if st == nil { // an exception
+		var exc thrift.ApplicationError
+		if body, ok := m.Body.(map[string]any); ok {
+			exc.Message, _ = body["message"].(string)
+			if n, ok := body["type"].(json.Number); ok {
+				t, _ := n.Int64()
+				exc.Type = thrift.ApplicationErrorType(t)
+			}
+		}
+		err = thrift.Marshal(w, &exc)
+	} else {
+		err = st.Marshal(w, m.Body)
+	}
+	if err != nil {
+		return err
+	}
+	return w.WriteMessageEnd()
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/itstarsun/go-thrift/encoding/thriftbinary"
+	"github.com/itstarsun/go-thrift/encoding/thriftcompact"
+	"github.com/itstarsun/go-thrift/encoding/thriftframed"
+	"github.com/itstarsun/go-thrift/encoding/thriftwire"
+	"github.com/itstarsun/go-thrift/tools/thriftschema"
+)
+
+const testIDL = "../../tools/thriftschema/testdata/store.thrift"
+
+const callJSON = `{"name":"put","type":"call","seqid":7,"body":{"item":{"color":"BLUE","id":"00112233-4455-6677-8899-aabbccddeeff","tags":["a"],"weights":{"RED":0.5}},"value":{"number":3}}}
+{"name":"get","type":"exception","seqid":8,"body":{"message":"boom","type":6}}
+`
+
+func loadSchema(t *testing.T) *thriftschema.Schema {
+	t.Helper()
+	s, err := thriftschema.Load(testIDL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func run(t *testing.T, f func(*bufio.Writer) error) string {
+	t.Helper()
+	var b bytes.Buffer
+	out := bufio.NewWriter(&b)
+	if err := f(out); err != nil {
+		t.Fatal(err)
+	}
+	if err := out.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+// encodeCalls encodes callJSON in the protocol p.
+func encodeCalls(t *testing.T, p thriftwire.Protocol) string {
+	d := &dumper{protocol: p, schema: loadSchema(t)}
+	return run(t, func(out *bufio.Writer) error {
+		return d.encode(out, strings.NewReader(callJSON))
+	})
+}
+
+func TestDumpTree(t *testing.T) {
+	in := encodeCalls(t, &thriftframed.Protocol{Protocol: thriftcompact.Protocol})
+
+	d := &dumper{}
+	got := run(t, func(out *bufio.Writer) error {
+		return d.dump(out, strings.NewReader(in))
+	})
+	const want = `call put seqid=7
+  1: struct
+    1: 00112233-4455-6677-8899-aabbccddeeff
+    3: 16
+    4: set<string>[1]
+      [0]: "a"
+    6: map<i32,double>[1]
+      1: 0.5
+  2: struct
+    2: 3
+exception get seqid=8
+  1: "boom"
+  2: 6
+`
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	d.schema = loadSchema(t)
+	got = run(t, func(out *bufio.Writer) error {
+		return d.dump(out, strings.NewReader(in))
+	})
+	const wantIDL = `call put seqid=7
+  1 item: Item
+    1 id: 00112233-4455-6677-8899-aabbccddeeff
+    3 color: 16 (BLUE)
+    4 tags: set<string>[1]
+      [0]: "a"
+    6 weights: map<Color,double>[1]
+      1 (RED): 0.5
+  2 value: Value
+    2 number: 3
+exception get seqid=8
+  1: "boom"
+  2: 6
+`
+	if got != wantIDL {
+		t.Errorf("got:\n%s\nwant:\n%s", got, wantIDL)
+	}
+}
+
+func TestDumpJSON(t *testing.T) {
+	in := encodeCalls(t, thriftbinary.Protocol)
+	d := &dumper{schema: loadSchema(t), json: true}
+	got := run(t, func(out *bufio.Writer) error {
+		return d.dump(out, strings.NewReader(in))
+	})
+	if got != callJSON {
+		t.Errorf("got:\n%s\nwant:\n%s", got, callJSON)
+	}
+
+	// Without an IDL, the Thrift JSON protocol is printed, and encoded back.
+	d = &dumper{json: true}
+	tjson := run(t, func(out *bufio.Writer) error {
+		return d.dump(out, strings.NewReader(in))
+	})
+	if !strings.HasPrefix(tjson, `[1,"put",1,7,{"1":{"rec":`) {
+		t.Errorf("got %s", tjson)
+	}
+	d = &dumper{protocol: thriftbinary.Protocol}
+	got = run(t, func(out *bufio.Writer) error {
+		return d.encode(out, strings.NewReader(tjson))
+	})
+	if got != in {
+		t.Errorf("got %q, want %q", got, in)
+	}
+}
+
+func TestDumpBare(t *testing.T) {
+	s := loadSchema(t)
+	st, err := s.Struct("shared.Owner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &dumper{protocol: thriftcompact.Protocol, schema: s, bare: true, typ: st}
+	const in = `{"level":"HIGH","name":"a"}
+{"name":"b"}
+`
+	bin := run(t, func(out *bufio.Writer) error {
+		return d.encode(out, strings.NewReader(in))
+	})
+	got := run(t, func(out *bufio.Writer) error {
+		return d.dump(out, strings.NewReader(bin))
+	})
+	const want = `Owner
+  1 name: "a"
+  2 level: 1 (HIGH)
+Owner
+  1 name: "b"
+`
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	d.json = true
+	got = run(t, func(out *bufio.Writer) error {
+		return d.dump(out, strings.NewReader(bin))
+	})
+	if got != in {
+		t.Errorf("got:\n%s\nwant:\n%s", got, in)
+	}
+
+	d = &dumper{bare: true}
+	if err := d.dump(bufio.NewWriter(new(bytes.Buffer)), strings.NewReader(bin)); err == nil {
+		t.Errorf("detected the protocol of bare structs")
+	}
+}
+
+func TestDumpTruncated(t *testing.T) {
+	in := encodeCalls(t, thriftbinary.Protocol)
+	d := &dumper{}
+	err := d.dump(bufio.NewWriter(new(bytes.Buffer)), strings.NewReader(in[:len(in)-3]))
+	if err == nil {
+		t.Errorf("dumped a truncated message")
+	}
+}
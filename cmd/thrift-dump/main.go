@@ -0,0 +1,161 @@
+// Thrift-dump decodes and encodes Thrift payloads.
+//
+// Usage:
+//
+//	thrift-dump [flags] [file...]
+//
+// Thrift-dump reads Thrift messages from the given files, or the standard
+// input if there are none, and prints them as indented trees:
+//
+//	call get seqid=1
+//	  1 id: 00112233-4455-6677-8899-aabbccddeeff
+//	  2 tags: list<string>[2]
+//	    [0]: "a"
+//	    [1]: "b"
+//
+// Fields are labeled by their IDs. With -idl, the messages are matched with
+// the methods of the services of the .thrift file by their names, and the
+// fields are also labeled by their names, and enum values by their names.
+// The protocol is detected from the first bytes of each file, unless it is
+// given by -protocol, which is required for bare structs.
+//
+// With -json, each message is printed on a line as JSON. Without -idl, the
+// messages are printed in the Thrift JSON protocol, which keeps the types of
+// the values. With -idl, they are printed as objects of the form
+//
+//	{"name": "get", "type": "call", "seqid": 1, "body": {"id": "..."}}
+//
+// where the body is keyed by field names, as described by the thriftschema
+// package. Exception messages have a body of the form
+// {"message": "...", "type": 1}.
+//
+// With -encode, thrift-dump does the reverse: it reads the JSON printed
+// by -json, and writes it in the protocol given by -protocol,
+// which defaults to binary.
+//
+// The flags are:
+//
+//	-protocol
+//		binary, compact, json, simplejson, header or auto; default auto
+//	-framed
+//		use the framed transport with the protocol
+//	-idl
+//		.thrift file describing the payloads
+//	-I
+//		directory to search for included files; may be repeated
+//	-service
+//		service of the messages; default the service with the method
+//	-bare
+//		the payloads are bare structs instead of messages
+//	-type
+//		struct of the bare structs in the .thrift file; implies -bare
+//	-json
+//		print JSON instead of trees
+//	-encode
+//		encode JSON into the protocol
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/itstarsun/go-thrift/tools/thriftschema"
+)
+
+var (
+	protocolName = flag.String("protocol", "auto", "binary, compact, json, simplejson, header or auto")
+	framed       = flag.Bool("framed", false, "use the framed transport with the protocol")
+	idl          = flag.String("idl", "", ".thrift file describing the payloads")
+	service      = flag.String("service", "", "service of the messages; default the service with the method")
+	bare         = flag.Bool("bare", false, "the payloads are bare structs instead of messages")
+	typeName     = flag.String("type", "", "struct of the bare structs in the .thrift file; implies -bare")
+	jsonOutput   = flag.Bool("json", false, "print JSON instead of trees")
+	encodeMode   = flag.Bool("encode", false, "encode JSON into the protocol")
+	includeDirs  stringsFlag
+)
+
+func init() {
+	flag.Var(&includeDirs, "I", "directory to search for included files; may be repeated")
+}
+
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return fmt.Sprint(*f)
+}
+
+func (f *stringsFlag) Set(s string) error {
+	*f = append(*f, s)
+	return nil
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: thrift-dump [flags] [file...]\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("thrift-dump: ")
+	flag.Usage = usage
+	flag.Parse()
+
+	d := &dumper{
+		service: *service,
+		bare:    *bare || *typeName != "",
+		json:    *jsonOutput,
+	}
+	name := *protocolName
+	if *encodeMode && name == "auto" {
+		name = "binary"
+	}
+	var err error
+	if d.protocol, err = protocolByName(name, *framed); err != nil {
+		log.Fatal(err)
+	}
+	if *idl != "" {
+		if d.schema, err = thriftschema.Load(*idl, includeDirs...); err != nil {
+			log.Fatal(err)
+		}
+		if *typeName != "" {
+			if d.typ, err = d.schema.Struct(*typeName); err != nil {
+				log.Fatal(err)
+			}
+		}
+	} else if *typeName != "" {
+		log.Fatal("-type requires -idl")
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	run := func(in io.Reader) error {
+		if *encodeMode {
+			return d.encode(out, in)
+		}
+		return d.dump(out, in)
+	}
+	if flag.NArg() == 0 {
+		err = run(os.Stdin)
+	}
+	for _, path := range flag.Args() {
+		var f *os.File
+		if f, err = os.Open(path); err != nil {
+			break
+		}
+		err = run(f)
+		f.Close()
+		if err != nil {
+			err = fmt.Errorf("%s: %w", path, err)
+			break
+		}
+	}
+	if ferr := out.Flush(); err == nil {
+		err = ferr
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
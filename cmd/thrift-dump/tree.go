@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/itstarsun/go-thrift/encoding/thriftwire"
+	"github.com/itstarsun/go-thrift/tools/thriftschema"
+)
+
+// printFields prints the fields of the struct v at the given depth,
+// labeled by the names of the fields of st, which may be nil.
+func printFields(w io.Writer, depth int, v thriftwire.Value, st *thriftschema.Struct) {
+	for _, f := range v.Fields() {
+		var sf *thriftschema.Field
+		label := strconv.Itoa(int(f.ID))
+		if f.Name != "" && f.ID == 0 { // from a reader of named fields
+			label = f.Name
+			if st != nil {
+				sf = st.FieldByName(f.Name)
+			}
+		} else if st != nil {
+			if sf = st.FieldByID(f.ID); sf != nil {
+				label += " " + sf.Name()
+			}
+		}
+		var t *thriftschema.Type
+		if sf != nil {
+			t = sf.Type()
+		}
+		printValue(w, depth, label, f.Value, t)
+	}
+}
+
+// printValue prints the value v labeled by label at the given depth.
+// If t is not nil, it is the type of v in the schema.
+func printValue(w io.Writer, depth int, label string, v thriftwire.Value, t *thriftschema.Type) {
+	if t != nil && t.Wire() != v.Type() {
+		t = nil // the value does not match the schema
+	}
+	fmt.Fprintf(w, "%s%s: ", strings.Repeat("  ", depth), label)
+	switch v.Type() {
+	case thriftwire.Struct:
+		var st *thriftschema.Struct
+		if t != nil {
+			st = t.Struct()
+			fmt.Fprintln(w, st.Name())
+		} else {
+			fmt.Fprintln(w, "struct")
+		}
+		printFields(w, depth+1, v, st)
+	case thriftwire.Map:
+		entries := v.Entries()
+		fmt.Fprintf(w, "%s[%d]\n", typeString(v, t), len(entries))
+		var kt, et *thriftschema.Type
+		if t != nil {
+			kt, et = t.Key(), t.Elem()
+		}
+		for i, e := range entries {
+			switch e.Key.Type() {
+			case thriftwire.Struct, thriftwire.Map, thriftwire.Set, thriftwire.List:
+				printValue(w, depth+1, fmt.Sprintf("[%d] key", i), e.Key, kt)
+				printValue(w, depth+1, fmt.Sprintf("[%d] value", i), e.Value, et)
+			default:
+				printValue(w, depth+1, scalar(e.Key, kt), e.Value, et)
+			}
+		}
+	case thriftwire.Set, thriftwire.List:
+		elems := v.Elems()
+		fmt.Fprintf(w, "%s[%d]\n", typeString(v, t), len(elems))
+		var et *thriftschema.Type
+		if t != nil {
+			et = t.Elem()
+		}
+		for i, e := range elems {
+			printValue(w, depth+1, "["+strconv.Itoa(i)+"]", e, et)
+		}
+	default:
+		fmt.Fprintln(w, scalar(v, t))
+	}
+}
+
+// scalar formats the scalar value v of the type t, which may be nil.
+// Enum values are followed by their names.
+func scalar(v thriftwire.Value, t *thriftschema.Type) string {
+	if t != nil && t.Wire() == v.Type() && v.Type() == thriftwire.I32 {
+		if name, ok := t.EnumName(v.I32()); ok {
+			return fmt.Sprintf("%d (%s)", v.I32(), name)
+		}
+	}
+	return v.String()
+}
+
+// typeString returns the type of the container v, as declared by t
+// if it is not nil.
+func typeString(v thriftwire.Value, t *thriftschema.Type) string {
+	if t != nil {
+		return t.String()
+	}
+	name := func(t thriftwire.Type) string {
+		return strings.ToLower(t.String())
+	}
+	switch v.Type() {
+	case thriftwire.Map:
+		return "map<" + name(v.KeyType()) + "," + name(v.ElemType()) + ">"
+	case thriftwire.Set:
+		return "set<" + name(v.ElemType()) + ">"
+	}
+	return "list<" + name(v.ElemType()) + ">"
+}
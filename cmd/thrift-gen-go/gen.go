@@ -0,0 +1,714 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"math"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/itstarsun/go-thrift/tools/thriftfile"
+)
+
+const thriftPath = "github.com/itstarsun/go-thrift/thrift"
+
+// generate returns the Go source generated from f.
+func generate(f *file, importPrefix string) ([]byte, error) {
+	g := &generator{
+		f:            f,
+		importPrefix: importPrefix,
+		imports:      make(map[string]string),
+		names:        make(map[string]string),
+	}
+	for _, d := range f.ast.Defs {
+		switch d := d.(type) {
+		case *thriftfile.Const:
+			g.genConst(d)
+		case *thriftfile.Typedef:
+			g.genTypedef(d)
+		case *thriftfile.Enum:
+			g.genEnum(d)
+		case *thriftfile.Struct:
+			g.genStruct(d)
+		case *thriftfile.Service:
+			g.genService(d)
+		}
+	}
+	if g.err != nil {
+		return nil, g.err
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by %q; DO NOT EDIT.\n", "thrift-gen-go")
+	fmt.Fprintf(&buf, "// source: %s\n\n", filepath.Base(f.path))
+	fmt.Fprintf(&buf, "package %s\n\n", f.pkgName)
+	if len(g.imports) > 0 {
+		paths := make([]string, 0, len(g.imports))
+		for p := range g.imports {
+			paths = append(paths, p)
+		}
+		sort.Strings(paths)
+		// Group standard packages before others like goimports.
+		sort.SliceStable(paths, func(i, j int) bool {
+			return isStd(paths[i]) && !isStd(paths[j])
+		})
+		buf.WriteString("import (\n")
+		for i, p := range paths {
+			if i > 0 && isStd(paths[i-1]) != isStd(p) {
+				buf.WriteString("\n")
+			}
+			if name := g.imports[p]; name != path.Base(p) {
+				fmt.Fprintf(&buf, "%s %q\n", name, p)
+			} else {
+				fmt.Fprintf(&buf, "%q\n", p)
+			}
+		}
+		buf.WriteString(")\n\n")
+	}
+	buf.Write(g.buf.Bytes())
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return buf.Bytes(), fmt.Errorf("%s: invalid generated code: %w", f.path, err)
+	}
+	return src, nil
+}
+
+func isStd(path string) bool {
+	return !strings.Contains(strings.SplitN(path, "/", 2)[0], ".")
+}
+
+type generator struct {
+	f            *file
+	importPrefix string
+	imports      map[string]string // import path to package name
+	names        map[string]string // package name to import path
+	buf          bytes.Buffer
+	err          error
+}
+
+func (g *generator) printf(format string, args ...any) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+func (g *generator) errorf(format string, args ...any) {
+	if g.err == nil {
+		g.err = fmt.Errorf("%s: %s", g.f.path, fmt.Sprintf(format, args...))
+	}
+}
+
+func (g *generator) importPackage(path, name string) string {
+	if name, ok := g.imports[path]; ok {
+		return name
+	}
+	base := name
+	for i := 2; g.names[name] != ""; i++ {
+		name = base + strconv.Itoa(i)
+	}
+	g.imports[path] = name
+	g.names[name] = path
+	return name
+}
+
+func (g *generator) thrift() string {
+	return g.importPackage(thriftPath, "thrift")
+}
+
+func (g *generator) fmt() string {
+	return g.importPackage("fmt", "fmt")
+}
+
+// qualified returns the Go name of a definition of f named name.
+func (g *generator) qualified(f *file, name string) string {
+	if f == g.f {
+		return name
+	}
+	return g.importPackage(path.Join(g.importPrefix, f.pkgDir), f.pkgName) + "." + name
+}
+
+// exportName returns the exported Go name of a Thrift identifier.
+func exportName(name string) string {
+	r, n := utf8.DecodeRuneInString(name)
+	return string(unicode.ToUpper(r)) + name[n:]
+}
+
+// fieldName returns the Go name of a Thrift field name,
+// converting snake_case into CamelCase.
+func fieldName(name string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(name, "_") {
+		if part != "" {
+			b.WriteString(exportName(part))
+		}
+	}
+	s := b.String()
+	if r, _ := utf8.DecodeRuneInString(s); !unicode.IsLetter(r) {
+		s = "X" + s
+	}
+	return s
+}
+
+func enumValueName(e *thriftfile.Enum, v *thriftfile.EnumValue) string {
+	return exportName(e.Name.Name) + "_" + v.Name.Name
+}
+
+var baseTypes = map[string]string{
+	"bool":   "bool",
+	"byte":   "int8",
+	"i8":     "int8",
+	"i16":    "int16",
+	"i32":    "int32",
+	"i64":    "int64",
+	"double": "float64",
+	"string": "string",
+	"binary": "[]byte",
+	"uuid":   "[16]byte",
+}
+
+// A kind is the category of a Thrift type.
+type kind int
+
+const (
+	invalidKind kind = iota
+	baseKind         // bool, integers, double and string
+	binaryKind
+	uuidKind
+	enumKind
+	structKind
+	mapKind
+	setKind
+	listKind
+)
+
+// resolve follows the typedefs of the type t declared in f,
+// returning the resolved type and the file it is declared in.
+func (g *generator) resolve(f *file, t thriftfile.Type) (*file, thriftfile.Type) {
+	for i := 0; i < 100; i++ {
+		ref, ok := t.(*thriftfile.TypeRef)
+		if !ok {
+			return f, t
+		}
+		if _, ok := baseTypes[ref.Name.Name]; ok {
+			return f, t
+		}
+		df, d := f.lookup(ref.Name.Name)
+		td, ok := d.(*thriftfile.Typedef)
+		if !ok {
+			return f, t
+		}
+		f, t = df, td.Type
+	}
+	g.errorf("typedef cycle")
+	return f, t
+}
+
+func (g *generator) kindOf(f *file, t thriftfile.Type) kind {
+	f, t = g.resolve(f, t)
+	switch t := t.(type) {
+	case *thriftfile.Map:
+		return mapKind
+	case *thriftfile.Set:
+		return setKind
+	case *thriftfile.List:
+		return listKind
+	case *thriftfile.TypeRef:
+		switch t.Name.Name {
+		case "binary":
+			return binaryKind
+		case "uuid":
+			return uuidKind
+		}
+		if _, ok := baseTypes[t.Name.Name]; ok {
+			return baseKind
+		}
+		switch _, d := f.lookup(t.Name.Name); d.(type) {
+		case *thriftfile.Enum:
+			return enumKind
+		case *thriftfile.Struct:
+			return structKind
+		case nil:
+			g.errorf("undefined type %s", t.Name.Name)
+		default:
+			g.errorf("%s is not a type", t.Name.Name)
+		}
+	}
+	return invalidKind
+}
+
+// goType returns the Go type of the type t declared in f.
+// Structs are referred to by pointers.
+func (g *generator) goType(f *file, t thriftfile.Type) string {
+	switch t := t.(type) {
+	case *thriftfile.Map:
+		return "map[" + g.keyType(f, t.Key) + "]" + g.goType(f, t.Value)
+	case *thriftfile.Set:
+		return g.thrift() + ".Set[" + g.goType(f, t.Element) + "]"
+	case *thriftfile.List:
+		return g.thrift() + ".List[" + g.goType(f, t.Element) + "]"
+	case *thriftfile.TypeRef:
+		if typ, ok := baseTypes[t.Name.Name]; ok {
+			return typ
+		}
+		df, d := f.lookup(t.Name.Name)
+		var name string
+		switch d := d.(type) {
+		case *thriftfile.Typedef:
+			name = g.qualified(df, exportName(d.Name.Name))
+		case *thriftfile.Enum:
+			name = g.qualified(df, exportName(d.Name.Name))
+		case *thriftfile.Struct:
+			name = g.qualified(df, exportName(d.Name.Name))
+		default:
+			g.kindOf(f, t) // report the error
+			return "any"
+		}
+		if g.kindOf(f, t) == structKind {
+			name = "*" + name
+		}
+		return name
+	}
+	panic("unreachable")
+}
+
+// keyType returns the Go type of a map key of the type t declared in f.
+// Types that are not comparable in Go are referred to by pointers.
+func (g *generator) keyType(f *file, t thriftfile.Type) string {
+	switch g.kindOf(f, t) {
+	case binaryKind, mapKind, setKind, listKind:
+		return "*" + g.goType(f, t)
+	}
+	return g.goType(f, t)
+}
+
+// isPointerField reports whether the field of the type t declared in f
+// is a pointer to distinguish unset fields from zero values.
+// Fields with default values are pointers, since a zero value would be
+// omitted when marshaling, and read as the default value by peers.
+func (g *generator) isPointerField(f *file, field *thriftfile.Field, union bool) bool {
+	hasDefault := field.Default != nil && !field.Required
+	if !field.Optional && !field.Reference && !union && !hasDefault {
+		return false
+	}
+	switch g.kindOf(f, field.Type) {
+	case baseKind, uuidKind, enumKind:
+		return true
+	}
+	return false
+}
+
+func (g *generator) genTypedef(d *thriftfile.Typedef) {
+	name := exportName(d.Name.Name)
+	switch g.kindOf(g.f, d.Type) {
+	case baseKind, binaryKind:
+		g.printf("type %s %s\n\n", name, g.goType(g.f, d.Type))
+	case structKind:
+		// Refer to the struct itself rather than a pointer.
+		typ := strings.TrimPrefix(g.goType(g.f, d.Type), "*")
+		g.printf("type %s = %s\n\n", name, typ)
+	default:
+		// Use an alias to keep the encoding and methods of the type.
+		g.printf("type %s = %s\n\n", name, g.goType(g.f, d.Type))
+	}
+}
+
+func (g *generator) genEnum(d *thriftfile.Enum) {
+	name := exportName(d.Name.Name)
+	fmtName := g.fmt()
+	g.printf("type %s int32\n\n", name)
+
+	values := make([]int64, len(d.Values.List))
+	var next int64
+	for i, v := range d.Values.List {
+		if v.Value != nil {
+			n, err := thriftfile.ParseInt(v.Value.Value, 32)
+			if err != nil {
+				g.errorf("invalid value of %s.%s: %v", d.Name.Name, v.Name.Name, err)
+			}
+			next = n
+		}
+		values[i] = next
+		next++
+	}
+
+	g.printf("const (\n")
+	for i, v := range d.Values.List {
+		g.printf("%s %s = %d\n", enumValueName(d, v), name, values[i])
+	}
+	g.printf(")\n\n")
+
+	seen := make(map[int64]bool)
+	g.printf("// String returns the name of x.\n")
+	g.printf("func (x %s) String() string {\n", name)
+	g.printf("switch x {\n")
+	for i, v := range d.Values.List {
+		if seen[values[i]] {
+			continue
+		}
+		seen[values[i]] = true
+		g.printf("case %s:\nreturn %q\n", enumValueName(d, v), v.Name.Name)
+	}
+	g.printf("}\n")
+	g.printf("return %s.Sprintf(\"%s(%%d)\", int32(x))\n", fmtName, name)
+	g.printf("}\n\n")
+
+	g.printf("// %sFromString returns the value of %[1]s named s.\n", name)
+	g.printf("func %sFromString(s string) (%[1]s, error) {\n", name)
+	g.printf("switch s {\n")
+	for _, v := range d.Values.List {
+		g.printf("case %q:\nreturn %s, nil\n", v.Name.Name, enumValueName(d, v))
+	}
+	g.printf("}\n")
+	g.printf("return 0, %s.Errorf(\"invalid %s name %%q\", s)\n", fmtName, name)
+	g.printf("}\n\n")
+}
+
+// A structField is a field of a generated struct.
+type structField struct {
+	*thriftfile.Field
+	id      int64
+	name    string // Go name
+	pointer bool
+}
+
+// structFields returns the fields of the struct d declared in f.
+func (g *generator) structFields(f *file, d *thriftfile.Struct) []structField {
+	reserved := make(map[string]bool)
+	switch {
+	case d.Exception:
+		reserved["Error"] = true
+	case d.Union:
+		reserved["Validate"] = true
+	}
+	fields := make([]structField, len(d.Fields.List))
+	nextID := int64(-1) // implicit field IDs are negative like Apache Thrift
+	for i, field := range d.Fields.List {
+		sf := structField{Field: field}
+		if field.ID != nil {
+			id, err := thriftfile.ParseInt(field.ID.Value, 16)
+			if err != nil {
+				g.errorf("invalid ID of field %s.%s: %v", d.Name.Name, field.Name.Name, err)
+			}
+			sf.id = id
+		} else {
+			sf.id = nextID
+			nextID--
+		}
+		sf.name = fieldName(field.Name.Name)
+		for reserved[sf.name] {
+			sf.name += "_"
+		}
+		reserved[sf.name] = true
+		sf.pointer = g.isPointerField(f, field, d.Union)
+		fields[i] = sf
+	}
+	return fields
+}
+
+func (g *generator) genStruct(d *thriftfile.Struct) {
+	name := exportName(d.Name.Name)
+	fields := g.structFields(g.f, d)
+
+	if len(fields) == 0 {
+		g.printf("type %s struct{}\n\n", name)
+	} else {
+		g.printf("type %s struct {\n", name)
+	}
+	g.printStructFields(fields)
+	if len(fields) > 0 {
+		g.printf("}\n\n")
+	}
+
+	g.printf("// New%s returns a new %[1]s with the default values of its fields.\n", name)
+	g.printf("func New%s() *%[1]s {\n", name)
+	g.printf("return &%s{\n", name)
+	for _, f := range fields {
+		if f.Default == nil || f.Optional || d.Union {
+			continue
+		}
+		expr := g.value(g.f, f.Type, f.Default)
+		if f.pointer {
+			expr = g.pointerTo(expr, g.goType(g.f, f.Type))
+		}
+		g.printf("%s: %s,\n", f.name, expr)
+	}
+	g.printf("}\n")
+	g.printf("}\n\n")
+
+	switch {
+	case d.Exception:
+		g.printf("// Error implements the error interface.\n")
+		g.printf("func (x *%s) Error() string {\n", name)
+		g.printf("return %s.Format(x)\n", g.thrift())
+		g.printf("}\n\n")
+	case d.Union:
+		g.printf("// Validate implements [thrift.Validator] by checking that exactly one field is set.\n")
+		g.printf("func (x *%s) Validate() error {\n", name)
+		g.printf("n := 0\n")
+		for _, f := range fields {
+			g.printf("if x.%s != nil {\nn++\n}\n", f.name)
+		}
+		g.printf("if n != 1 {\n")
+		g.printf("return %s.Errorf(\"%s: exactly one field must be set, got %%d\", n)\n", g.fmt(), name)
+		g.printf("}\n")
+		g.printf("return nil\n")
+		g.printf("}\n\n")
+	}
+}
+
+func (g *generator) printStructFields(fields []structField) {
+	for _, f := range fields {
+		typ := g.goType(g.f, f.Type)
+		if f.pointer {
+			typ = "*" + typ
+		}
+		tag := strconv.FormatInt(f.id, 10)
+		if f.Required {
+			tag += ",required"
+		}
+		g.printf("%s %s `thrift:%q`\n", f.name, typ, tag)
+	}
+}
+
+func (g *generator) genConst(d *thriftfile.Const) {
+	name := exportName(d.Name.Name)
+	value := g.value(g.f, d.Type, d.Value)
+	switch g.kindOf(g.f, d.Type) {
+	case baseKind, enumKind:
+		g.printf("const %s %s = %s\n\n", name, g.goType(g.f, d.Type), value)
+	case binaryKind:
+		// Keep the type, which may be a named type of []byte.
+		g.printf("var %s %s = %s\n\n", name, g.goType(g.f, d.Type), value)
+	default:
+		g.printf("var %s = %s\n\n", name, value)
+	}
+}
+
+// value returns a Go expression of the value v of the type t declared in f.
+func (g *generator) value(f *file, t thriftfile.Type, v thriftfile.Value) string {
+	if v, ok := v.(*thriftfile.Ident); ok {
+		return g.identValue(f, t, v.Name)
+	}
+	rf, rt := g.resolve(f, t)
+	invalid := func() string {
+		g.errorf("invalid value for type %s", g.goType(f, t))
+		return "nil"
+	}
+	switch g.kindOf(f, t) {
+	case baseKind:
+		base := rt.(*thriftfile.TypeRef).Name.Name
+		switch v := v.(type) {
+		case *thriftfile.Int:
+			n, err := thriftfile.ParseInt(v.Value, 64)
+			if err != nil {
+				g.errorf("invalid integer %s: %v", v.Value, err)
+			}
+			switch base {
+			case "bool":
+				return strconv.FormatBool(n != 0)
+			case "double", "i64":
+				return strconv.FormatInt(n, 10)
+			case "byte", "i8":
+				return g.checkInt(n, math.MinInt8, math.MaxInt8)
+			case "i16":
+				return g.checkInt(n, math.MinInt16, math.MaxInt16)
+			case "i32":
+				return g.checkInt(n, math.MinInt32, math.MaxInt32)
+			}
+		case *thriftfile.Float:
+			if base == "double" {
+				if _, err := strconv.ParseFloat(v.Value, 64); err != nil {
+					g.errorf("invalid double %s", v.Value)
+				}
+				return v.Value
+			}
+		case *thriftfile.String:
+			if base == "string" {
+				return strconv.Quote(g.unquote(v))
+			}
+		}
+	case binaryKind:
+		if v, ok := v.(*thriftfile.String); ok {
+			return "[]byte(" + strconv.Quote(g.unquote(v)) + ")"
+		}
+	case uuidKind:
+		if v, ok := v.(*thriftfile.String); ok {
+			u, err := thriftfile.ParseUUID(g.unquote(v))
+			if err != nil {
+				g.errorf("invalid uuid %s", v.Value)
+			}
+			var b strings.Builder
+			b.WriteString("[16]byte{")
+			for i, c := range u {
+				if i > 0 {
+					b.WriteString(", ")
+				}
+				fmt.Fprintf(&b, "0x%02x", c)
+			}
+			b.WriteString("}")
+			return b.String()
+		}
+	case enumKind:
+		if v, ok := v.(*thriftfile.Int); ok {
+			n, err := thriftfile.ParseInt(v.Value, 32)
+			if err != nil {
+				g.errorf("invalid integer %s: %v", v.Value, err)
+			}
+			return g.goType(f, t) + "(" + strconv.FormatInt(n, 10) + ")"
+		}
+	case structKind:
+		if v, ok := v.(*thriftfile.KeyValuePairList); ok {
+			sf, d := rf.lookup(rt.(*thriftfile.TypeRef).Name.Name)
+			return g.structValue(sf, d.(*thriftfile.Struct), v)
+		}
+	case mapKind:
+		if v, ok := v.(*thriftfile.KeyValuePairList); ok {
+			m := rt.(*thriftfile.Map)
+			var b strings.Builder
+			b.WriteString(g.goType(f, t) + "{\n")
+			for _, kv := range v.List {
+				k := g.value(rf, m.Key, kv.Key)
+				if g.keyType(rf, m.Key) != g.goType(rf, m.Key) {
+					k = g.pointerTo(k, g.goType(rf, m.Key))
+				}
+				v := elide(g.value(rf, m.Value, kv.Value), g.goType(rf, m.Value))
+				b.WriteString(k + ": " + v + ",\n")
+			}
+			b.WriteString("}")
+			return b.String()
+		}
+	case setKind, listKind:
+		if v, ok := v.(*thriftfile.ValueList); ok {
+			var elem thriftfile.Type
+			switch rt := rt.(type) {
+			case *thriftfile.Set:
+				elem = rt.Element
+			case *thriftfile.List:
+				elem = rt.Element
+			}
+			var b strings.Builder
+			b.WriteString(g.goType(f, t) + "{\n")
+			for _, e := range v.List {
+				b.WriteString(elide(g.value(rf, elem, e), g.goType(rf, elem)) + ",\n")
+			}
+			b.WriteString("}")
+			return b.String()
+		}
+	}
+	return invalid()
+}
+
+// elide elides the type of a composite literal expr of the Go type typ
+// as an element of another composite literal.
+func elide(expr, typ string) string {
+	switch {
+	case strings.HasPrefix(expr, typ+"{"):
+		return expr[len(typ):]
+	case strings.HasPrefix(typ, "*") && strings.HasPrefix(expr, "&"+typ[1:]+"{"):
+		return expr[len(typ):]
+	}
+	return expr
+}
+
+func (g *generator) checkInt(n, min, max int64) string {
+	if n < min || n > max {
+		g.errorf("integer %d out of range", n)
+	}
+	return strconv.FormatInt(n, 10)
+}
+
+func (g *generator) unquote(v *thriftfile.String) string {
+	s, err := thriftfile.Unquote(v.Value)
+	if err != nil {
+		g.errorf("invalid string %s", v.Value)
+	}
+	return s
+}
+
+// pointerTo returns a Go expression of a pointer to a new variable
+// of the Go type typ initialized with expr.
+func (g *generator) pointerTo(expr, typ string) string {
+	return "func() *" + typ + " {\nvar v " + typ + " = " + expr + "\nreturn &v\n}()"
+}
+
+func (g *generator) structValue(f *file, d *thriftfile.Struct, v *thriftfile.KeyValuePairList) string {
+	fields := g.structFields(f, d)
+	var b strings.Builder
+	b.WriteString("&" + g.qualified(f, exportName(d.Name.Name)) + "{\n")
+	for _, kv := range v.List {
+		k, ok := kv.Key.(*thriftfile.String)
+		if !ok {
+			g.errorf("invalid field name for struct %s", d.Name.Name)
+			continue
+		}
+		name := g.unquote(k)
+		i := 0
+		for i < len(fields) && fields[i].Name.Name != name {
+			i++
+		}
+		if i == len(fields) {
+			g.errorf("unknown field %s of struct %s", name, d.Name.Name)
+			continue
+		}
+		field := fields[i]
+		expr := g.value(f, field.Type, kv.Value)
+		if field.pointer {
+			expr = g.pointerTo(expr, g.goType(f, field.Type))
+		}
+		b.WriteString(field.name + ": " + expr + ",\n")
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+// identValue returns a Go expression of the value named by name
+// for the type t declared in f.
+func (g *generator) identValue(f *file, t thriftfile.Type, name string) string {
+	k := g.kindOf(f, t)
+	if k == baseKind && (name == "true" || name == "false") {
+		if _, rt := g.resolve(f, t); rt.(*thriftfile.TypeRef).Name.Name == "bool" {
+			return name
+		}
+	}
+
+	var expr, typ string
+	if df, d := f.lookup(name); d != nil {
+		c, ok := d.(*thriftfile.Const)
+		if !ok {
+			g.errorf("%s is not a constant", name)
+			return "nil"
+		}
+		expr = g.qualified(df, exportName(c.Name.Name))
+		typ = g.goType(df, c.Type)
+	} else if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		df, d := f.lookup(name[:i])
+		e, ok := d.(*thriftfile.Enum)
+		if !ok {
+			g.errorf("undefined: %s", name)
+			return "nil"
+		}
+		for _, v := range e.Values.List {
+			if v.Name.Name == name[i+1:] {
+				expr = g.qualified(df, enumValueName(e, v))
+				typ = g.qualified(df, exportName(e.Name.Name))
+			}
+		}
+		if expr == "" {
+			g.errorf("undefined: %s", name)
+			return "nil"
+		}
+	} else {
+		g.errorf("undefined: %s", name)
+		return "nil"
+	}
+
+	if want := g.goType(f, t); typ != want && (k == baseKind || k == enumKind) {
+		return want + "(" + expr + ")"
+	}
+	return expr
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testdata = "../../tools/thriftfile/testdata/thrift"
+
+// A typeChecker is an importer that type-checks the Go packages generated
+// under dir, whose import paths are prefixed by prefix.
+type typeChecker struct {
+	fset   *token.FileSet
+	dir    string
+	prefix string
+	pkgs   map[string]*types.Package
+	source types.Importer
+}
+
+func (c *typeChecker) Import(importPath string) (*types.Package, error) {
+	if !strings.HasPrefix(importPath, c.prefix+"/") {
+		return c.source.Import(importPath)
+	}
+	if pkg, ok := c.pkgs[importPath]; ok {
+		return pkg, nil
+	}
+	dir := filepath.Join(c.dir, filepath.FromSlash(strings.TrimPrefix(importPath, c.prefix+"/")))
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []*ast.File
+	for _, e := range entries {
+		f, err := parser.ParseFile(c.fset, filepath.Join(dir, e.Name()), nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	conf := types.Config{Importer: c}
+	pkg, err := conf.Check(importPath, c.fset, files, nil)
+	if err != nil {
+		return nil, err
+	}
+	c.pkgs[importPath] = pkg
+	return pkg, nil
+}
+
+func TestGenerate(t *testing.T) {
+	files := []string{
+		"test/ThriftTest.thrift",
+		"test/ConstantsDemo.thrift",
+		"test/Identifiers.thrift",
+		"test/NameConflictTest.thrift",
+		"test/Recursive.thrift",
+		"test/TypedefTest.thrift",
+		"tutorial/tutorial.thrift",
+	}
+	l := newLoader(nil)
+	for _, name := range files {
+		if _, err := l.load(filepath.Join(testdata, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkGenerated(t, l)
+}
+
+// TestGenerateLocalNames tests that the locals of the generated code
+// do not shadow the packages of included files.
+func TestGenerateLocalNames(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.thrift":   "struct Args { 1: string name }",
+		"e.thrift":   "exception Ex { 1: string message }",
+		"h.thrift":   "exception Ex { 1: string message }",
+		"p.thrift":   "struct P { 1: i32 n }",
+		"r.thrift":   "exception Ex { 1: string message }",
+		"res.thrift": "struct Item { 1: string name }",
+		"main.thrift": `include "a.thrift"
+include "e.thrift"
+include "h.thrift"
+include "p.thrift"
+include "r.thrift"
+include "res.thrift"
+
+service Store {
+  res.Item get(1: a.Args args, 2: p.P point) throws (1: e.Ex e, 2: r.Ex r, 3: h.Ex h)
+  void put(1: res.Item item) throws (1: e.Ex e)
+  oneway void log(1: a.Args args)
+}
+`,
+	}
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	l := newLoader(nil)
+	if _, err := l.load(filepath.Join(dir, "main.thrift")); err != nil {
+		t.Fatal(err)
+	}
+	checkGenerated(t, l)
+}
+
+// checkGenerated type-checks the code generated for the files loaded by l.
+func checkGenerated(t *testing.T, l *loader) {
+	t.Helper()
+	const prefix = "example.com/gen"
+	out := t.TempDir()
+	dirs := make(map[string]bool)
+	for _, f := range l.files {
+		src, err := generate(f, prefix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		name := filepath.Join(out, filepath.FromSlash(f.pkgDir), f.name+".go")
+		if err := os.MkdirAll(filepath.Dir(name), 0o777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, src, 0o666); err != nil {
+			t.Fatal(err)
+		}
+		dirs[f.pkgDir] = true
+	}
+
+	c := &typeChecker{
+		fset:   token.NewFileSet(),
+		dir:    out,
+		prefix: prefix,
+		pkgs:   make(map[string]*types.Package),
+		source: importer.ForCompiler(token.NewFileSet(), "source", nil),
+	}
+	for dir := range dirs {
+		if _, err := c.Import(path.Join(prefix, dir)); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestExample(t *testing.T) {
+	const prefix = "github.com/itstarsun/go-thrift/cmd/thrift-gen-go/internal"
+	l := newLoader(nil)
+	for _, name := range []string{"example.thrift", "shared.thrift"} {
+		f, err := l.load(filepath.Join("internal", "example", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := generate(f, prefix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		name := filepath.Join("internal", filepath.FromSlash(f.pkgDir), f.name+".go")
+		want, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s is out of date; run go generate", name)
+		}
+	}
+}
@@ -0,0 +1,4 @@
+// Package example contains code generated by thrift-gen-go for testing.
+package example
+
+//go:generate go run github.com/itstarsun/go-thrift/cmd/thrift-gen-go -out .. -import-prefix github.com/itstarsun/go-thrift/cmd/thrift-gen-go/internal example.thrift shared.thrift
@@ -0,0 +1,327 @@
+// Code generated by "thrift-gen-go"; DO NOT EDIT.
+// source: example.thrift
+
+package example
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/itstarsun/go-thrift/cmd/thrift-gen-go/internal/example/shared"
+	"github.com/itstarsun/go-thrift/thrift"
+)
+
+type Timestamp int64
+
+type Blob []byte
+
+type ID = [16]byte
+
+type Tags = thrift.Set[string]
+
+type Color int32
+
+const (
+	Color_RED   Color = 1
+	Color_GREEN Color = 2
+	Color_BLUE  Color = 16
+)
+
+// String returns the name of x.
+func (x Color) String() string {
+	switch x {
+	case Color_RED:
+		return "RED"
+	case Color_GREEN:
+		return "GREEN"
+	case Color_BLUE:
+		return "BLUE"
+	}
+	return fmt.Sprintf("Color(%d)", int32(x))
+}
+
+// ColorFromString returns the value of Color named s.
+func ColorFromString(s string) (Color, error) {
+	switch s {
+	case "RED":
+		return Color_RED, nil
+	case "GREEN":
+		return Color_GREEN, nil
+	case "BLUE":
+		return Color_BLUE, nil
+	}
+	return 0, fmt.Errorf("invalid Color name %q", s)
+}
+
+const DEFAULT_COLOR Color = Color_GREEN
+
+const MAX_ITEMS int32 = 100
+
+const RATIO float64 = 0.5
+
+const GREETING string = "say \"hello\"\n"
+
+var DEFAULT_TAGS = Tags{
+	"a",
+	"b",
+}
+
+var GROUPS = map[string]thrift.List[int16]{
+	"even": {
+		2,
+		4,
+	},
+	"odd": {
+		1,
+		3,
+	},
+}
+
+var NIL_ID = [16]byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}
+
+type Item struct {
+	Id       ID                 `thrift:"1,required"`
+	Name     *string            `thrift:"2"`
+	Color    *Color             `thrift:"3"`
+	Tags     Tags               `thrift:"4"`
+	Children thrift.List[*Item] `thrift:"5"`
+	Weights  map[Color]float64  `thrift:"6"`
+	Created  Timestamp          `thrift:"7"`
+	Data     Blob               `thrift:"8"`
+	Owner    *shared.Owner      `thrift:"9"`
+	Count    *int32             `thrift:"10"`
+	Level    *shared.Level      `thrift:"11"`
+}
+
+// NewItem returns a new Item with the default values of its fields.
+func NewItem() *Item {
+	return &Item{
+		Name: func() *string {
+			var v string = "unnamed"
+			return &v
+		}(),
+		Count: func() *int32 {
+			var v int32 = MAX_ITEMS
+			return &v
+		}(),
+	}
+}
+
+type Entry = Item
+
+var SAMPLE = &Item{
+	Id: [16]byte{0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88, 0x99, 0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0xff},
+	Name: func() *string {
+		var v string = "sample"
+		return &v
+	}(),
+	Color: func() *Color {
+		var v Color = Color_BLUE
+		return &v
+	}(),
+	Children: thrift.List[*Item]{
+		{
+			Name: func() *string {
+				var v string = "child"
+				return &v
+			}(),
+		},
+	},
+}
+
+type Value struct {
+	Flag   *bool   `thrift:"1"`
+	Number *int64  `thrift:"2"`
+	Text   *string `thrift:"3"`
+	Item   *Item   `thrift:"4"`
+}
+
+// NewValue returns a new Value with the default values of its fields.
+func NewValue() *Value {
+	return &Value{}
+}
+
+// Validate implements [thrift.Validator] by checking that exactly one field is set.
+func (x *Value) Validate() error {
+	n := 0
+	if x.Flag != nil {
+		n++
+	}
+	if x.Number != nil {
+		n++
+	}
+	if x.Text != nil {
+		n++
+	}
+	if x.Item != nil {
+		n++
+	}
+	if n != 1 {
+		return fmt.Errorf("Value: exactly one field must be set, got %d", n)
+	}
+	return nil
+}
+
+type NotFound struct {
+	Id      ID     `thrift:"1"`
+	Message string `thrift:"2"`
+}
+
+// NewNotFound returns a new NotFound with the default values of its fields.
+func NewNotFound() *NotFound {
+	return &NotFound{}
+}
+
+// Error implements the error interface.
+func (x *NotFound) Error() string {
+	return thrift.Format(x)
+}
+
+type StoreGetArgs struct {
+	Id ID `thrift:"1"`
+}
+
+type StoreGetResult struct {
+	Success  *Item     `thrift:"0"`
+	NotFound *NotFound `thrift:"1"`
+}
+
+type StorePutArgs struct {
+	Item *Item `thrift:"1"`
+}
+
+type StorePutResult struct{}
+
+type StoreFindArgs struct {
+	Name  string `thrift:"1"`
+	Color *Color `thrift:"2"`
+}
+
+type StoreFindResult struct {
+	Success *thrift.List[*Item] `thrift:"0"`
+}
+
+type StoreTouchArgs struct {
+	Id ID `thrift:"1"`
+}
+
+// Store is the interface of the Store service.
+type Store interface {
+	shared.Pinger
+	Get(ctx context.Context, id ID) (*Item, error)
+	Put(ctx context.Context, item *Item) error
+	Find(ctx context.Context, name string, color *Color) (thrift.List[*Item], error)
+	Touch(ctx context.Context, id ID) error
+}
+
+// StoreClient is a client of the Store service.
+type StoreClient struct {
+	*shared.PingerClient
+	c thrift.Client
+}
+
+var _ Store = (*StoreClient)(nil)
+
+// NewStoreClient returns a new StoreClient making calls with c.
+func NewStoreClient(c thrift.Client) *StoreClient {
+	return &StoreClient{
+		PingerClient: shared.NewPingerClient(c),
+		c:            c,
+	}
+}
+
+func (x *StoreClient) Get(ctx context.Context, id ID) (*Item, error) {
+	args := StoreGetArgs{
+		Id: id,
+	}
+	var result StoreGetResult
+	if err := x.c.Call(ctx, "get", &args, &result); err != nil {
+		return nil, err
+	}
+	if result.NotFound != nil {
+		return nil, result.NotFound
+	}
+	if result.Success == nil {
+		return nil, &thrift.ApplicationError{Message: "get failed: missing result", Type: thrift.MissingResult}
+	}
+	return result.Success, nil
+}
+
+func (x *StoreClient) Put(ctx context.Context, item *Item) error {
+	args := StorePutArgs{
+		Item: item,
+	}
+	var result StorePutResult
+	if err := x.c.Call(ctx, "put", &args, &result); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (x *StoreClient) Find(ctx context.Context, name string, color *Color) (thrift.List[*Item], error) {
+	args := StoreFindArgs{
+		Name:  name,
+		Color: color,
+	}
+	var result StoreFindResult
+	if err := x.c.Call(ctx, "find", &args, &result); err != nil {
+		return nil, err
+	}
+	if result.Success == nil {
+		return nil, &thrift.ApplicationError{Message: "find failed: missing result", Type: thrift.MissingResult}
+	}
+	return *result.Success, nil
+}
+
+func (x *StoreClient) Touch(ctx context.Context, id ID) error {
+	args := StoreTouchArgs{
+		Id: id,
+	}
+	return x.c.Call(ctx, "touch", &args, nil)
+}
+
+// RegisterStore registers the methods of the Store service to p,
+// dispatching calls to h.
+func RegisterStore(p *thrift.Processor, h Store) {
+	shared.RegisterPinger(p, h)
+	p.Handle("get", (*StoreGetArgs)(nil), (*StoreGetResult)(nil), func(ctx context.Context, args, result any) error {
+		a := args.(*StoreGetArgs)
+		r := result.(*StoreGetResult)
+		res, err := h.Get(ctx, a.Id)
+		if err != nil {
+			if e := (*NotFound)(nil); errors.As(err, &e) {
+				r.NotFound = e
+				return nil
+			}
+			return err
+		}
+		r.Success = res
+		return nil
+	})
+	p.Handle("put", (*StorePutArgs)(nil), (*StorePutResult)(nil), func(ctx context.Context, args, result any) error {
+		a := args.(*StorePutArgs)
+		return h.Put(ctx, a.Item)
+	})
+	p.Handle("find", (*StoreFindArgs)(nil), (*StoreFindResult)(nil), func(ctx context.Context, args, result any) error {
+		a := args.(*StoreFindArgs)
+		r := result.(*StoreFindResult)
+		res, err := h.Find(ctx, a.Name, a.Color)
+		if err != nil {
+			return err
+		}
+		r.Success = &res
+		return nil
+	})
+	p.Handle("touch", (*StoreTouchArgs)(nil), nil, func(ctx context.Context, args, result any) error {
+		a := args.(*StoreTouchArgs)
+		return h.Touch(ctx, a.Id)
+	})
+}
+
+// NewStoreProcessor returns a new processor dispatching calls to h.
+func NewStoreProcessor(h Store) *thrift.Processor {
+	p := new(thrift.Processor)
+	RegisterStore(p, h)
+	return p
+}
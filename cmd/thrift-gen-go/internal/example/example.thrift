@@ -0,0 +1,59 @@
+namespace go example
+
+include "shared.thrift"
+
+typedef i64 Timestamp
+typedef binary Blob
+typedef uuid ID
+typedef set<string> Tags
+
+enum Color {
+  RED = 1,
+  GREEN,
+  BLUE = 0x10,
+}
+
+const Color DEFAULT_COLOR = Color.GREEN
+const i32 MAX_ITEMS = 100
+const double RATIO = 0.5
+const string GREETING = 'say "hello"\n'
+const Tags DEFAULT_TAGS = ["a", "b"]
+const map<string, list<i16>> GROUPS = {"even": [2, 4], "odd": [1, 3]}
+const ID NIL_ID = "00000000-0000-0000-0000-000000000000"
+
+struct Item {
+  1: required ID id,
+  2: string name = "unnamed",
+  3: optional Color color,
+  4: Tags tags,
+  5: list<Item> children,
+  6: map<Color, double> weights,
+  7: Timestamp created,
+  8: optional Blob data,
+  9: shared.Owner owner,
+  10: i32 count = MAX_ITEMS,
+  11: optional shared.Level level = shared.Level.HIGH,
+}
+
+typedef Item Entry
+
+const Entry SAMPLE = {"id": "00112233-4455-6677-8899-aabbccddeeff", "name": "sample", "color": Color.BLUE, "children": [{"name": "child"}]}
+
+union Value {
+  1: bool flag,
+  2: i64 number,
+  3: string text,
+  4: Item item,
+}
+
+exception NotFound {
+  1: ID id,
+  2: string message,
+}
+
+service Store extends shared.Pinger {
+  Item get(1: ID id) throws (1: NotFound notFound),
+  void put(1: Item item),
+  list<Item> find(1: string name, 2: optional Color color),
+  oneway void touch(1: ID id),
+}
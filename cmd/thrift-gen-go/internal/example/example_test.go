@@ -0,0 +1,269 @@
+package example
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/itstarsun/go-thrift/cmd/thrift-gen-go/internal/example/shared"
+	"github.com/itstarsun/go-thrift/encoding/thriftbinary"
+	"github.com/itstarsun/go-thrift/encoding/thriftwire"
+	"github.com/itstarsun/go-thrift/thrift"
+)
+
+func roundTrip(t *testing.T, in, out any) error {
+	t.Helper()
+	var buf bytes.Buffer
+	w := thriftbinary.Protocol.NewWriter(&buf)
+	if err := thrift.Marshal(w, in); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	return thrift.Unmarshal(thriftbinary.Protocol.NewReader(&buf), out)
+}
+
+func TestItem(t *testing.T) {
+	in := NewItem()
+	in.Id = SAMPLE.Id
+	in.Tags = DEFAULT_TAGS
+	in.Children = SAMPLE.Children
+	in.Weights = map[Color]float64{Color_RED: RATIO}
+	in.Data = Blob(GREETING)
+	in.Owner = &shared.Owner{Name: "gopher"}
+	level := shared.Level_HIGH
+	in.Level = &level
+
+	var out Item
+	if err := roundTrip(t, in, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !thrift.Equal(in, &out) {
+		t.Errorf("round trip mismatch: %v", thrift.Diff(in, &out))
+	}
+	if *out.Name != "unnamed" || *out.Count != MAX_ITEMS {
+		t.Errorf("got Name %q and Count %d, want default values", *out.Name, *out.Count)
+	}
+}
+
+func TestItemZeroDefaults(t *testing.T) {
+	// Fields with default values set to zero values are still written,
+	// such that peers do not read them as the default values.
+	in := NewItem()
+	*in.Name = ""
+	*in.Count = 0
+
+	var out Item
+	if err := roundTrip(t, in, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Name == nil || *out.Name != "" || out.Count == nil || *out.Count != 0 {
+		t.Errorf("got %s, want zero values", thrift.Format(&out))
+	}
+}
+
+func TestEnum(t *testing.T) {
+	if got := DEFAULT_COLOR.String(); got != "GREEN" {
+		t.Errorf("String() = %q, want GREEN", got)
+	}
+	if got := Color(3).String(); got != "Color(3)" {
+		t.Errorf("String() = %q, want Color(3)", got)
+	}
+	if c, err := ColorFromString("BLUE"); err != nil || c != Color_BLUE {
+		t.Errorf("ColorFromString(BLUE) = (%v, %v), want BLUE", c, err)
+	}
+	if _, err := ColorFromString("PINK"); err == nil {
+		t.Errorf("ColorFromString(PINK) succeeded")
+	}
+}
+
+func TestConstants(t *testing.T) {
+	want := map[string]thrift.List[int16]{"even": {2, 4}, "odd": {1, 3}}
+	if !reflect.DeepEqual(GROUPS, want) {
+		t.Errorf("GROUPS = %v, want %v", GROUPS, want)
+	}
+	if GREETING != "say \"hello\"\n" {
+		t.Errorf("GREETING = %q", GREETING)
+	}
+	if SAMPLE.Id[15] != 0xff || *SAMPLE.Color != Color_BLUE || *SAMPLE.Children[0].Name != "child" {
+		t.Errorf("unexpected SAMPLE: %s", thrift.Format(SAMPLE))
+	}
+}
+
+func TestUnion(t *testing.T) {
+	text := "hello"
+	var out Value
+	if err := roundTrip(t, &Value{Text: &text}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Text == nil || *out.Text != text {
+		t.Errorf("got %s, want text %q", thrift.Format(&out), text)
+	}
+
+	for _, in := range []*Value{{}, {Text: &text, Item: &Item{}}} {
+		if err := roundTrip(t, in, &out); err == nil {
+			t.Errorf("marshaling %s succeeded", thrift.Format(in))
+		}
+	}
+}
+
+func TestException(t *testing.T) {
+	var err error = &NotFound{Message: "missing"}
+	var nf *NotFound
+	if !errors.As(err, &nf) {
+		t.Fatalf("errors.As(%v) failed", err)
+	}
+	if got, want := err.Error(), `NotFound{Message(2): "missing"}`; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
+
+// processorClient is a [thrift.Client] calling a processor directly.
+type processorClient struct {
+	p *thrift.Processor
+}
+
+func (c processorClient) Call(ctx context.Context, method string, args, result any) error {
+	var req, resp bytes.Buffer
+	mh := thriftwire.MessageHeader{Name: method, Type: thriftwire.Call, ID: 1}
+	if result == nil {
+		mh.Type = thriftwire.OneWay
+	}
+	w := thriftbinary.Protocol.NewWriter(&req)
+	if err := w.WriteMessageBegin(mh); err != nil {
+		return err
+	}
+	if err := thrift.Marshal(w, args); err != nil {
+		return err
+	}
+	if err := w.WriteMessageEnd(); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if err := c.p.Process(ctx, thriftbinary.Protocol.NewReader(&req), thriftbinary.Protocol.NewWriter(&resp)); err != nil {
+		return err
+	}
+	if result == nil {
+		return nil
+	}
+
+	r := thriftbinary.Protocol.NewReader(&resp)
+	mh, err := r.ReadMessageBegin()
+	if err != nil {
+		return err
+	}
+	if mh.Type != thriftwire.Reply {
+		return fmt.Errorf("%s: got message type %v", method, mh.Type)
+	}
+	if err := thrift.Unmarshal(r, result); err != nil {
+		return err
+	}
+	return r.ReadMessageEnd()
+}
+
+type store struct {
+	items   map[ID]*Item
+	touched []ID
+}
+
+func (s *store) Ping(ctx context.Context) (int64, error) {
+	return int64(len(s.items)), nil
+}
+
+func (s *store) Get(ctx context.Context, id ID) (*Item, error) {
+	item, ok := s.items[id]
+	if !ok {
+		return nil, &NotFound{Id: id, Message: "no such item"}
+	}
+	return item, nil
+}
+
+func (s *store) Put(ctx context.Context, item *Item) error {
+	if item.Name == nil || *item.Name == "" {
+		return errors.New("empty name")
+	}
+	s.items[item.Id] = item
+	return nil
+}
+
+func (s *store) Find(ctx context.Context, name string, color *Color) (thrift.List[*Item], error) {
+	var items thrift.List[*Item]
+	for _, item := range s.items {
+		if item.Name != nil && *item.Name == name && (color == nil || item.Color != nil && *item.Color == *color) {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+func (s *store) Touch(ctx context.Context, id ID) error {
+	s.touched = append(s.touched, id)
+	return nil
+}
+
+func TestService(t *testing.T) {
+	ctx := context.Background()
+	s := &store{items: make(map[ID]*Item)}
+	c := NewStoreClient(processorClient{NewStoreProcessor(s)})
+
+	if err := c.Put(ctx, SAMPLE); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Put(ctx, &Item{}); err == nil {
+		t.Error("Put of an invalid item succeeded")
+	}
+	if n, err := c.Ping(ctx); err != nil || n != 1 {
+		t.Errorf("Ping() = (%d, %v), want 1", n, err)
+	}
+
+	item, err := c.Get(ctx, SAMPLE.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !thrift.Equal(item, SAMPLE) {
+		t.Errorf("Get mismatch: %v", thrift.Diff(SAMPLE, item))
+	}
+	_, err = c.Get(ctx, NIL_ID)
+	var nf *NotFound
+	if !errors.As(err, &nf) || nf.Message != "no such item" {
+		t.Errorf("Get(NIL_ID) = %v, want NotFound", err)
+	}
+
+	blue, red := Color_BLUE, Color_RED
+	if items, err := c.Find(ctx, "sample", &blue); err != nil || len(items) != 1 {
+		t.Errorf("Find(sample, BLUE) = (%v, %v), want 1 item", items, err)
+	}
+	if items, err := c.Find(ctx, "sample", &red); err != nil || len(items) != 0 {
+		t.Errorf("Find(sample, RED) = (%v, %v), want no items", items, err)
+	}
+
+	if err := c.Touch(ctx, SAMPLE.Id); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.touched) != 1 || s.touched[0] != SAMPLE.Id {
+		t.Errorf("got touched %v, want [%v]", s.touched, SAMPLE.Id)
+	}
+}
+
+func TestMissingResult(t *testing.T) {
+	// A processor replying without the results, unlike one registered by RegisterStore.
+	p := new(thrift.Processor)
+	p.Handle("get", (*StoreGetArgs)(nil), (*StoreGetResult)(nil), func(context.Context, any, any) error { return nil })
+	p.Handle("find", (*StoreFindArgs)(nil), (*StoreFindResult)(nil), func(context.Context, any, any) error { return nil })
+	c := NewStoreClient(processorClient{p})
+	ctx := context.Background()
+
+	var exc *thrift.ApplicationError
+	if _, err := c.Get(ctx, SAMPLE.Id); !errors.As(err, &exc) || exc.Type != thrift.MissingResult {
+		t.Errorf("Get() returned %v, want a missing result error", err)
+	}
+	if _, err := c.Find(ctx, "sample", nil); !errors.As(err, &exc) || exc.Type != thrift.MissingResult {
+		t.Errorf("Find() returned %v, want a missing result error", err)
+	}
+}
@@ -0,0 +1,15 @@
+namespace go example.shared
+
+enum Level {
+  LOW = 1,
+  HIGH,
+}
+
+struct Owner {
+  1: required string name,
+  2: optional Level level,
+}
+
+service Pinger {
+  i64 ping(),
+}
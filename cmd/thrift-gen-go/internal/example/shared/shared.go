@@ -0,0 +1,108 @@
+// Code generated by "thrift-gen-go"; DO NOT EDIT.
+// source: shared.thrift
+
+package shared
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/itstarsun/go-thrift/thrift"
+)
+
+type Level int32
+
+const (
+	Level_LOW  Level = 1
+	Level_HIGH Level = 2
+)
+
+// String returns the name of x.
+func (x Level) String() string {
+	switch x {
+	case Level_LOW:
+		return "LOW"
+	case Level_HIGH:
+		return "HIGH"
+	}
+	return fmt.Sprintf("Level(%d)", int32(x))
+}
+
+// LevelFromString returns the value of Level named s.
+func LevelFromString(s string) (Level, error) {
+	switch s {
+	case "LOW":
+		return Level_LOW, nil
+	case "HIGH":
+		return Level_HIGH, nil
+	}
+	return 0, fmt.Errorf("invalid Level name %q", s)
+}
+
+type Owner struct {
+	Name  string `thrift:"1,required"`
+	Level *Level `thrift:"2"`
+}
+
+// NewOwner returns a new Owner with the default values of its fields.
+func NewOwner() *Owner {
+	return &Owner{}
+}
+
+type PingerPingArgs struct{}
+
+type PingerPingResult struct {
+	Success *int64 `thrift:"0"`
+}
+
+// Pinger is the interface of the Pinger service.
+type Pinger interface {
+	Ping(ctx context.Context) (int64, error)
+}
+
+// PingerClient is a client of the Pinger service.
+type PingerClient struct {
+	c thrift.Client
+}
+
+var _ Pinger = (*PingerClient)(nil)
+
+// NewPingerClient returns a new PingerClient making calls with c.
+func NewPingerClient(c thrift.Client) *PingerClient {
+	return &PingerClient{
+		c: c,
+	}
+}
+
+func (x *PingerClient) Ping(ctx context.Context) (int64, error) {
+	args := PingerPingArgs{}
+	var result PingerPingResult
+	if err := x.c.Call(ctx, "ping", &args, &result); err != nil {
+		return 0, err
+	}
+	if result.Success == nil {
+		return 0, &thrift.ApplicationError{Message: "ping failed: missing result", Type: thrift.MissingResult}
+	}
+	return *result.Success, nil
+}
+
+// RegisterPinger registers the methods of the Pinger service to p,
+// dispatching calls to h.
+func RegisterPinger(p *thrift.Processor, h Pinger) {
+	p.Handle("ping", (*PingerPingArgs)(nil), (*PingerPingResult)(nil), func(ctx context.Context, args, result any) error {
+		r := result.(*PingerPingResult)
+		res, err := h.Ping(ctx)
+		if err != nil {
+			return err
+		}
+		r.Success = &res
+		return nil
+	})
+}
+
+// NewPingerProcessor returns a new processor dispatching calls to h.
+func NewPingerProcessor(h Pinger) *thrift.Processor {
+	p := new(thrift.Processor)
+	RegisterPinger(p, h)
+	return p
+}
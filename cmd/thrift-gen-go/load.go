@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"go/token"
+	"path/filepath"
+	"strings"
+
+	"github.com/itstarsun/go-thrift/tools/thriftfile"
+)
+
+// A file is a loaded .thrift file.
+type file struct {
+	path     string
+	ast      *thriftfile.File
+	name     string // base name without extension, used by including files
+	pkgName  string // Go package name
+	pkgDir   string // slash-separated directory of the Go package
+	includes map[string]*file
+	defs     map[string]thriftfile.Def
+}
+
+type loader struct {
+	l     thriftfile.Loader
+	files map[*thriftfile.LoadedFile]*file
+}
+
+func newLoader(includeDirs []string) *loader {
+	return &loader{
+		l:     thriftfile.Loader{IncludeDirs: includeDirs},
+		files: make(map[*thriftfile.LoadedFile]*file),
+	}
+}
+
+// load loads the .thrift file at path and the files it includes.
+func (l *loader) load(path string) (*file, error) {
+	lf, err := l.l.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	return l.file(lf)
+}
+
+// file returns the file of the loaded file lf and the files it includes.
+func (l *loader) file(lf *thriftfile.LoadedFile) (*file, error) {
+	if f, ok := l.files[lf]; ok {
+		return f, nil
+	}
+	path, ast := lf.Path, lf.File
+	f := &file{
+		path:     path,
+		ast:      ast,
+		name:     strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)),
+		includes: make(map[string]*file),
+		defs:     make(map[string]thriftfile.Def),
+	}
+	for name, inc := range lf.Includes {
+		incf, err := l.file(inc)
+		if err != nil {
+			return nil, err
+		}
+		f.includes[name] = incf
+	}
+	var namespace, fallback string
+	for _, h := range ast.Headers {
+		if h, ok := h.(*thriftfile.Namespace); ok {
+			switch {
+			case h.Scope == nil:
+				fallback = h.Name.Name
+			case h.Scope.Name == "go":
+				namespace = h.Name.Name
+			}
+		}
+	}
+	if namespace == "" {
+		namespace = fallback
+	}
+	if namespace == "" {
+		namespace = f.name
+	}
+	f.pkgDir = strings.ReplaceAll(namespace, ".", "/")
+	f.pkgName = namespace[strings.LastIndexByte(namespace, '.')+1:]
+	if token.IsKeyword(f.pkgName) || !token.IsIdentifier(f.pkgName) {
+		f.pkgName = strings.Map(func(r rune) rune {
+			if r == '_' || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9') {
+				return r
+			}
+			return '_'
+		}, f.pkgName) + "_"
+	}
+
+	for _, d := range ast.Defs {
+		var name string
+		switch d := d.(type) {
+		case *thriftfile.Const:
+			name = d.Name.Name
+		case *thriftfile.Typedef:
+			name = d.Name.Name
+		case *thriftfile.Enum:
+			name = d.Name.Name
+		case *thriftfile.Struct:
+			name = d.Name.Name
+		case *thriftfile.Service:
+			name = d.Name.Name
+		default:
+			continue
+		}
+		if _, ok := f.defs[name]; ok {
+			return nil, fmt.Errorf("%s: %s redeclared", path, name)
+		}
+		f.defs[name] = d
+	}
+
+	l.files[lf] = f
+	return f, nil
+}
+
+// lookup returns the definition named by name, which may be qualified by
+// the name of an included file, and the file it belongs to.
+func (f *file) lookup(name string) (*file, thriftfile.Def) {
+	if d, ok := f.defs[name]; ok {
+		return f, d
+	}
+	if i := strings.IndexByte(name, '.'); i >= 0 {
+		if inc, ok := f.includes[name[:i]]; ok {
+			if d, ok := inc.defs[name[i+1:]]; ok {
+				return inc, d
+			}
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,116 @@
+// Thrift-gen-go generates Go code from .thrift files.
+//
+// Usage:
+//
+//	thrift-gen-go [flags] file.thrift...
+//
+// For each given .thrift file, thrift-gen-go generates a Go file declaring
+// the types and constants of the .thrift file for use with the thrift
+// package. The Go package is determined by the "namespace go" header,
+// falling back to the "namespace *" header and then the base name of the
+// .thrift file. A namespace "a.b.c" is generated into the directory a/b/c
+// of the output directory as package c.
+//
+// Thrift types are mapped to Go types as follows:
+//
+//   - bool, byte, i8, i16, i32, i64, double and string map to the
+//     corresponding Go types, with byte mapping to int8.
+//   - binary maps to []byte, and uuid maps to [16]byte.
+//   - list<T> and set<T> map to [thrift.List] and [thrift.Set],
+//     and map<K, V> maps to a Go map.
+//   - Enums map to named int32 types with a constant for each value.
+//   - Structs, unions and exceptions map to Go structs, which are referred
+//     to by pointers. Exceptions implement the error interface, and unions
+//     implement [thrift.Validator] to check that exactly one field is set.
+//   - Typedefs of base types map to named types, and other typedefs map to
+//     type aliases to keep the encoding of the aliased type.
+//
+// For each service, thrift-gen-go generates an interface with a method for
+// each method of the service, which takes a [context.Context] and the
+// arguments, and returns the result, if any, and an error. Declared
+// exceptions are returned as errors, and a service extending another embeds
+// the interface of the other. A client implementing the interface on top of
+// a [thrift.Client] is created by New<Service>Client, and an implementation
+// of the interface is registered to a [thrift.Processor] by
+// Register<Service>, which maps the errors to the declared exceptions.
+//
+// Optional fields of base and enum types, fields of those types with
+// default values unless they are required, and all fields of unions, are
+// pointers such that unset fields can be distinguished from zero values.
+// New<Struct> returns a struct with the fields set to their default values.
+// Included files are resolved relative to the including file and then
+// the include directories, and refer to Go packages under the import prefix.
+//
+// The flags are:
+//
+//	-out
+//		output directory; default "."
+//	-import-prefix
+//		import path of the output directory, used to import included files
+//	-I
+//		directory to search for included files; may be repeated
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+var (
+	outDir       = flag.String("out", ".", "output directory")
+	importPrefix = flag.String("import-prefix", "", "import path of the output directory")
+	includeDirs  stringsFlag
+)
+
+func init() {
+	flag.Var(&includeDirs, "I", "directory to search for included files; may be repeated")
+}
+
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return fmt.Sprint(*f)
+}
+
+func (f *stringsFlag) Set(s string) error {
+	*f = append(*f, s)
+	return nil
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: thrift-gen-go [flags] file.thrift...\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("thrift-gen-go: ")
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	l := newLoader(includeDirs)
+	for _, path := range flag.Args() {
+		f, err := l.load(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		src, err := generate(f, *importPrefix)
+		if err != nil {
+			log.Fatal(err)
+		}
+		name := filepath.Join(*outDir, filepath.FromSlash(f.pkgDir), f.name+".go")
+		if err := os.MkdirAll(filepath.Dir(name), 0o777); err != nil {
+			log.Fatal(err)
+		}
+		if err := os.WriteFile(name, src, 0o666); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,359 @@
+package main
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/itstarsun/go-thrift/tools/thriftfile"
+)
+
+// A method is a method of a generated service.
+type method struct {
+	*thriftfile.Method
+	name    string // Go name
+	service string // Go name of the service
+	args    []structField
+	params  []string // Go names of the parameters
+	throws  []structField
+	success string // Go type of the Success field of the result, or "" if void
+	pointer bool   // whether the Success field is a pointer to the result
+}
+
+// The Success field of a result is a pointer to the result unless the result
+// is a struct, which is already a pointer, such that a reply is always written
+// with the result, and a missing result is detected by the client.
+
+// argsType returns the Go name of the struct of the arguments of m.
+func (m *method) argsType() string {
+	return m.service + m.name + "Args"
+}
+
+// resultType returns the Go name of the struct of the result of m.
+func (m *method) resultType() string {
+	return m.service + m.name + "Result"
+}
+
+// methods returns the methods of the service d.
+func (g *generator) methods(d *thriftfile.Service) []*method {
+	service := exportName(d.Name.Name)
+	methods := make([]*method, len(d.Methods.List))
+	for i, m := range d.Methods.List {
+		gm := &method{
+			Method:  m,
+			name:    exportName(m.Name.Name),
+			service: service,
+		}
+		gm.args = g.structFields(g.f, &thriftfile.Struct{Name: m.Name, Fields: m.Arguments})
+		reserved := make(map[string]bool)
+		for _, f := range gm.args {
+			gm.params = append(gm.params, paramName(f.Field.Name.Name, reserved, g.f))
+		}
+		if m.Throws != nil {
+			gm.throws = g.structFields(g.f, &thriftfile.Struct{Name: m.Name, Fields: m.Throws})
+			names := map[string]bool{"Success": true}
+			for i := range gm.throws {
+				f := &gm.throws[i]
+				for names[f.name] {
+					f.name += "_"
+				}
+				names[f.name] = true
+				if g.kindOf(g.f, f.Type) != structKind {
+					g.errorf("%s.%s throws %s, which is not an exception", d.Name.Name, m.Name.Name, f.Field.Name.Name)
+				}
+			}
+		}
+		if m.OneWay && (!m.Void || len(gm.throws) > 0) {
+			g.errorf("one-way method %s.%s must be void and not throw exceptions", d.Name.Name, m.Name.Name)
+		}
+		if !m.Void {
+			gm.success = g.goType(g.f, m.Return)
+			gm.pointer = g.kindOf(g.f, m.Return) != structKind
+		}
+		methods[i] = gm
+	}
+	return methods
+}
+
+// paramName returns the Go name of a parameter from a Thrift field name,
+// avoiding the names reserved by the generated code.
+func paramName(name string, reserved map[string]bool, f *file) string {
+	s := fieldName(name)
+	r, n := utf8.DecodeRuneInString(s)
+	return localName(string(unicode.ToLower(r))+s[n:], reserved, f)
+}
+
+// localName returns name, or name with underscores appended,
+// avoiding the names reserved by the generated code.
+func localName(name string, reserved map[string]bool, f *file) string {
+	for isReservedParam(name, f) || reserved[name] {
+		name += "_"
+	}
+	reserved[name] = true
+	return name
+}
+
+func isReservedParam(name string, f *file) bool {
+	switch name {
+	case "ctx", "x", "args", "result", "err",
+		"context", "errors", "fmt", "thrift":
+		return true
+	}
+	for _, inc := range f.includes {
+		if name == inc.pkgName {
+			return true
+		}
+	}
+	return token.IsKeyword(name) || types.Universe.Lookup(name) != nil
+}
+
+func (g *generator) genService(d *thriftfile.Service) {
+	name := exportName(d.Name.Name)
+	methods := g.methods(d)
+
+	var parent string // qualified Go name of the parent service
+	if d.Extends != nil {
+		df, pd := g.f.lookup(d.Extends.Name)
+		if _, ok := pd.(*thriftfile.Service); !ok {
+			g.errorf("%s extends %s, which is not a service", d.Name.Name, d.Extends.Name)
+			return
+		}
+		parent = g.qualified(df, exportName(pd.(*thriftfile.Service).Name.Name))
+	}
+
+	for _, m := range methods {
+		if len(m.args) == 0 {
+			g.printf("type %s struct{}\n\n", m.argsType())
+		} else {
+			g.printf("type %s struct {\n", m.argsType())
+			g.printStructFields(m.args)
+			g.printf("}\n\n")
+		}
+		switch {
+		case m.OneWay:
+		case m.success == "" && len(m.throws) == 0:
+			g.printf("type %s struct{}\n\n", m.resultType())
+		default:
+			g.printf("type %s struct {\n", m.resultType())
+			if m.success != "" {
+				typ := m.success
+				if m.pointer {
+					typ = "*" + typ
+				}
+				g.printf("Success %s `thrift:\"0\"`\n", typ)
+			}
+			g.printStructFields(m.throws)
+			g.printf("}\n\n")
+		}
+	}
+
+	g.printf("// %s is the interface of the %s service.\n", name, d.Name.Name)
+	g.printf("type %s interface {\n", name)
+	if parent != "" {
+		g.printf("%s\n", parent)
+	}
+	for _, m := range methods {
+		g.printf("%s(%s) %s\n", m.name, g.signature(m), g.results(m))
+	}
+	g.printf("}\n\n")
+
+	g.printf("// %sClient is a client of the %s service.\n", name, d.Name.Name)
+	g.printf("type %sClient struct {\n", name)
+	if parent != "" {
+		g.printf("*%sClient\n", parent)
+	}
+	g.printf("c %s.Client\n", g.thrift())
+	g.printf("}\n\n")
+	g.printf("var _ %s = (*%[1]sClient)(nil)\n\n", name)
+
+	g.printf("// New%sClient returns a new %[1]sClient making calls with c.\n", name)
+	g.printf("func New%sClient(c %s.Client) *%[1]sClient {\n", name, g.thrift())
+	g.printf("return &%sClient{\n", name)
+	if parent != "" {
+		i := strings.LastIndexByte(parent, '.')
+		g.printf("%sClient: %sClient(c),\n", parent[i+1:], newName(parent))
+	}
+	g.printf("c: c,\n")
+	g.printf("}\n")
+	g.printf("}\n\n")
+
+	for _, m := range methods {
+		g.genClientMethod(m)
+	}
+
+	reserved := make(map[string]bool)
+	p, h := localName("p", reserved, g.f), localName("h", reserved, g.f)
+	g.printf("// Register%s registers the methods of the %s service to %s,\n", name, d.Name.Name, p)
+	g.printf("// dispatching calls to %s.\n", h)
+	g.printf("func Register%s(%s *%s.Processor, %s %[1]s) {\n", name, p, g.thrift(), h)
+	if parent != "" {
+		g.printf("%s(%s, %s)\n", registerName(parent), p, h)
+	}
+	for _, m := range methods {
+		g.genHandler(m, p, h)
+	}
+	g.printf("}\n\n")
+
+	g.printf("// New%sProcessor returns a new processor dispatching calls to %s.\n", name, h)
+	g.printf("func New%sProcessor(%s %s) *%s.Processor {\n", name, h, name, g.thrift())
+	g.printf("%s := new(%s.Processor)\n", p, g.thrift())
+	g.printf("Register%s(%s, %s)\n", name, p, h)
+	g.printf("return %s\n", p)
+	g.printf("}\n\n")
+}
+
+// newName returns the qualified name of the constructor of the client
+// of the service with the qualified Go name.
+func newName(service string) string {
+	i := strings.LastIndexByte(service, '.')
+	return service[:i+1] + "New" + service[i+1:]
+}
+
+// registerName returns the qualified name of the registration function
+// of the service with the qualified Go name.
+func registerName(service string) string {
+	i := strings.LastIndexByte(service, '.')
+	return service[:i+1] + "Register" + service[i+1:]
+}
+
+// signature returns the Go parameters of m.
+func (g *generator) signature(m *method) string {
+	params := []string{"ctx " + g.importPackage("context", "context") + ".Context"}
+	for i, f := range m.args {
+		typ := g.goType(g.f, f.Type)
+		if f.pointer {
+			typ = "*" + typ
+		}
+		params = append(params, m.params[i]+" "+typ)
+	}
+	return strings.Join(params, ", ")
+}
+
+// results returns the Go results of m.
+func (g *generator) results(m *method) string {
+	if m.success == "" {
+		return "error"
+	}
+	return "(" + m.success + ", error)"
+}
+
+// zero returns the zero value of the result of m.
+func (g *generator) zero(m *method) string {
+	f, t := g.resolve(g.f, m.Return)
+	switch g.kindOf(f, t) {
+	case baseKind:
+		switch t.(*thriftfile.TypeRef).Name.Name {
+		case "bool":
+			return "false"
+		case "string":
+			return `""`
+		}
+		return "0"
+	case enumKind:
+		return "0"
+	case uuidKind:
+		return m.success + "{}"
+	}
+	return "nil"
+}
+
+func (g *generator) genClientMethod(m *method) {
+	g.printf("func (x *%sClient) %s(%s) %s {\n", m.service, m.name, g.signature(m), g.results(m))
+	g.printf("args := %s{\n", m.argsType())
+	for i, f := range m.args {
+		g.printf("%s: %s,\n", f.name, m.params[i])
+	}
+	g.printf("}\n")
+
+	if m.OneWay {
+		g.printf("return x.c.Call(ctx, %q, &args, nil)\n", m.Name.Name)
+		g.printf("}\n\n")
+		return
+	}
+
+	ret := func(v string) string {
+		if m.success == "" {
+			return v
+		}
+		return g.zero(m) + ", " + v
+	}
+	g.printf("var result %s\n", m.resultType())
+	g.printf("if err := x.c.Call(ctx, %q, &args, &result); err != nil {\n", m.Name.Name)
+	g.printf("return %s\n", ret("err"))
+	g.printf("}\n")
+	for _, f := range m.throws {
+		g.printf("if result.%s != nil {\n", f.name)
+		g.printf("return %s\n", ret("result."+f.name))
+		g.printf("}\n")
+	}
+	if m.success == "" {
+		g.printf("return nil\n")
+		g.printf("}\n\n")
+		return
+	}
+	g.printf("if result.Success == nil {\n")
+	g.printf("return %s\n", ret(fmt.Sprintf("&%s.ApplicationError{Message: %q, Type: %[1]s.MissingResult}", g.thrift(), m.Name.Name+" failed: missing result")))
+	g.printf("}\n")
+	if m.pointer {
+		g.printf("return *result.Success, nil\n")
+	} else {
+		g.printf("return result.Success, nil\n")
+	}
+	g.printf("}\n\n")
+}
+
+// genHandler generates the registration of the handler of m to the
+// processor p, calling the method of h. The locals of the handler avoid
+// the names of the packages of included files.
+func (g *generator) genHandler(m *method, p, h string) {
+	ctx := g.importPackage("context", "context")
+	result := "(*" + m.resultType() + ")(nil)"
+	if m.OneWay {
+		result = "nil"
+	}
+	reserved := map[string]bool{p: true, h: true}
+	a, r := localName("a", reserved, g.f), localName("r", reserved, g.f)
+	res, e := localName("res", reserved, g.f), localName("e", reserved, g.f)
+	g.printf("%s.Handle(%q, (*%s)(nil), %s, func(ctx %s.Context, args, result any) error {\n", p, m.Name.Name, m.argsType(), result, ctx)
+	argList := []string{"ctx"}
+	if len(m.args) > 0 {
+		g.printf("%s := args.(*%s)\n", a, m.argsType())
+		for _, f := range m.args {
+			argList = append(argList, a+"."+f.name)
+		}
+	}
+	call := h + "." + m.name + "(" + strings.Join(argList, ", ") + ")"
+	if m.OneWay || (m.success == "" && len(m.throws) == 0) {
+		g.printf("return %s\n", call)
+		g.printf("})\n")
+		return
+	}
+
+	g.printf("%s := result.(*%s)\n", r, m.resultType())
+	if m.success != "" {
+		g.printf("%s, err := %s\n", res, call)
+	} else {
+		g.printf("err := %s\n", call)
+	}
+	g.printf("if err != nil {\n")
+	for _, f := range m.throws {
+		g.printf("if %s := (%s)(nil); %s.As(err, &%[1]s) {\n", e, g.goType(g.f, f.Type), g.importPackage("errors", "errors"))
+		g.printf("%s.%s = %s\n", r, f.name, e)
+		g.printf("return nil\n")
+		g.printf("}\n")
+	}
+	g.printf("return err\n")
+	g.printf("}\n")
+	switch {
+	case m.success == "":
+	case m.pointer:
+		g.printf("%s.Success = &%s\n", r, res)
+	default:
+		g.printf("%s.Success = %s\n", r, res)
+	}
+	g.printf("return nil\n")
+	g.printf("})\n")
+}
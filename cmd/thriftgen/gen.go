@@ -0,0 +1,844 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/fs"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	thriftPath     = "github.com/itstarsun/go-thrift/thrift"
+	thriftwirePath = "github.com/itstarsun/go-thrift/encoding/thriftwire"
+)
+
+// generate returns the generated source of the package in dir,
+// excluding the existing output file.
+func generate(dir, output string, typeNames []string, command string, warn func(string)) ([]byte, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(fi fs.FileInfo) bool {
+		name := fi.Name()
+		return name != output && !strings.HasSuffix(name, "_test.go")
+	}, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("%s: expected exactly one package, found %d", dir, len(pkgs))
+	}
+	var files []*ast.File
+	var pkgName string
+	for name, p := range pkgs {
+		pkgName = name
+		for _, f := range p.Files {
+			files = append(files, f)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return fset.File(files[i].Pos()).Name() < fset.File(files[j].Pos()).Name()
+	})
+
+	conf := types.Config{
+		Importer: importer.ForCompiler(fset, "source", nil),
+		// Ignore errors, which are likely caused by references to
+		// previously generated methods that are excluded.
+		Error: func(error) {},
+	}
+	pkg, _ := conf.Check(pkgName, fset, files, nil)
+	if pkg == nil {
+		return nil, fmt.Errorf("%s: cannot type-check package", dir)
+	}
+
+	g := &generator{
+		pkg:     pkg,
+		imports: make(map[string]string),
+		names:   make(map[string]string),
+	}
+	if err := g.selectTypes(typeNames, warn); err != nil {
+		return nil, err
+	}
+	return g.generate(command)
+}
+
+type generator struct {
+	pkg   *types.Package
+	types []*structType
+	gen   map[*types.TypeName]bool // types with generated methods
+
+	imports map[string]string // import path to package name
+	names   map[string]string // package name to import path
+	buf     bytes.Buffer
+	tmp     int
+}
+
+type structType struct {
+	obj    *types.TypeName
+	fields []structField
+}
+
+type structField struct {
+	name      string
+	typ       types.Type
+	id        int16
+	required  bool
+	sensitive bool
+}
+
+// selectTypes selects the types to generate methods for.
+func (g *generator) selectTypes(typeNames []string, warn func(string)) error {
+	scope := g.pkg.Scope()
+	var objs []*types.TypeName
+	if len(typeNames) > 0 {
+		for _, name := range typeNames {
+			obj, ok := scope.Lookup(name).(*types.TypeName)
+			if !ok {
+				return fmt.Errorf("type %s not found", name)
+			}
+			objs = append(objs, obj)
+		}
+	} else {
+		for _, name := range scope.Names() {
+			obj, ok := scope.Lookup(name).(*types.TypeName)
+			if !ok || obj.IsAlias() {
+				continue
+			}
+			if s, ok := obj.Type().Underlying().(*types.Struct); ok && hasThriftTag(s) {
+				objs = append(objs, obj)
+			}
+		}
+	}
+	sort.Slice(objs, func(i, j int) bool { return objs[i].Pos() < objs[j].Pos() })
+
+	g.gen = make(map[*types.TypeName]bool)
+	for _, obj := range objs {
+		g.gen[obj] = true
+	}
+
+	// Exclude unsupported types until the remaining types are consistent,
+	// since excluding a type changes how other types refer to it.
+	for {
+		g.types = g.types[:0]
+		var excluded bool
+		for _, obj := range objs {
+			if !g.gen[obj] {
+				continue
+			}
+			st, err := g.makeStructType(obj)
+			if err != nil {
+				warn(fmt.Sprintf("skipping %s: %v", obj.Name(), err))
+				delete(g.gen, obj)
+				excluded = true
+				continue
+			}
+			g.types = append(g.types, st)
+		}
+		if !excluded {
+			return nil
+		}
+	}
+}
+
+func hasThriftTag(s *types.Struct) bool {
+	for i := 0; i < s.NumFields(); i++ {
+		if _, ok := reflect.StructTag(s.Tag(i)).Lookup("thrift"); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *generator) makeStructType(obj *types.TypeName) (*structType, error) {
+	named, ok := obj.Type().(*types.Named)
+	if !ok || named.TypeParams().Len() > 0 {
+		return nil, errors.New("generic types are not supported")
+	}
+	s, ok := named.Underlying().(*types.Struct)
+	if !ok {
+		return nil, errors.New("not a struct")
+	}
+	for _, m := range []string{"MarshalThrift", "UnmarshalThrift"} {
+		if sel := types.NewMethodSet(types.NewPointer(named)).Lookup(g.pkg, m); sel != nil {
+			return nil, fmt.Errorf("method %s already exists", m)
+		}
+	}
+	st := &structType{obj: obj}
+	seen := make(map[int16]string)
+	for i := 0; i < s.NumFields(); i++ {
+		v := s.Field(i)
+		f, ignored, err := parseField(v, s.Tag(i))
+		if err != nil {
+			return nil, err
+		} else if ignored {
+			continue
+		}
+		if other, ok := seen[f.id]; ok {
+			return nil, fmt.Errorf("fields %s and %s have the same ID %d", other, f.name, f.id)
+		}
+		seen[f.id] = f.name
+		if g.shapeOf(f.typ) == invalidShape {
+			return nil, fmt.Errorf("field %s has unsupported type %s", f.name, types.TypeString(f.typ, types.RelativeTo(g.pkg)))
+		}
+		if !f.required && !g.canCheckZero(f.typ) {
+			return nil, fmt.Errorf("field %s has type %s with inaccessible fields", f.name, types.TypeString(f.typ, types.RelativeTo(g.pkg)))
+		}
+		st.fields = append(st.fields, f)
+	}
+	sort.Slice(st.fields, func(i, j int) bool { return st.fields[i].id < st.fields[j].id })
+	return st, nil
+}
+
+// parseField parses the `thrift` tag of a struct field
+// like the thrift package does.
+func parseField(v *types.Var, tag string) (f structField, ignored bool, err error) {
+	f.name = v.Name()
+	f.typ = v.Type()
+	tag, hasTag := reflect.StructTag(tag).Lookup("thrift")
+	if tag == "-" {
+		return f, true, nil
+	}
+	if !v.Exported() {
+		if hasTag {
+			return f, true, fmt.Errorf("unexported field %s cannot have non-ignored `thrift:%q` tag", f.name, tag)
+		}
+		return f, true, nil
+	}
+	opts := strings.Split(tag, ",")
+	id, err := strconv.ParseInt(opts[0], 10, 16)
+	if err != nil {
+		return f, true, fmt.Errorf("field %s has malformed `thrift` tag: invalid field ID: %w", f.name, err)
+	}
+	f.id = int16(id)
+	seen := make(map[string]bool)
+	for _, opt := range opts[1:] {
+		if !isIdent(opt) {
+			return f, true, fmt.Errorf("field %s has malformed `thrift` tag", f.name)
+		}
+		switch opt {
+		case "required":
+			f.required = true
+		case "sensitive":
+			f.sensitive = true
+		default:
+			switch strings.ReplaceAll(strings.ToLower(opt), "_", "") {
+			case "required", "sensitive":
+				return f, true, fmt.Errorf("field %s has invalid appearance of `%s` tag option", f.name, opt)
+			}
+		}
+		if seen[opt] {
+			return f, true, fmt.Errorf("field %s has duplicate appearance of `%s` tag option", f.name, opt)
+		}
+		seen[opt] = true
+	}
+	return f, false, nil
+}
+
+func isIdent(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	if r != '_' && !unicode.IsLetter(r) {
+		return false
+	}
+	for _, r := range s {
+		if r != '_' && !unicode.IsLetter(r) && !unicode.IsNumber(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// A shape describes how a Go type is handled by the thrift package.
+type shape int
+
+const (
+	invalidShape shape = iota
+	boolShape
+	intShape
+	doubleShape
+	stringShape
+	bytesShape
+	uuidShape
+	mapShape
+	setShape
+	listShape
+	pointerShape
+	methodShape  // call the MarshalThrift and UnmarshalThrift methods
+	reflectShape // call thrift.Marshal and thrift.Unmarshal
+)
+
+func (g *generator) shapeOf(t types.Type) shape {
+	if named, ok := t.(*types.Named); ok {
+		obj := named.Obj()
+		if obj.Pkg() != nil && obj.Pkg().Path() == thriftPath {
+			switch obj.Name() {
+			case "Set":
+				if g.shapeOf(named.Underlying().(*types.Slice).Elem()) == invalidShape {
+					return invalidShape
+				}
+				return setShape
+			case "List":
+				if g.shapeOf(named.Underlying().(*types.Slice).Elem()) == invalidShape {
+					return invalidShape
+				}
+				return listShape
+			}
+		}
+		if g.gen[obj] {
+			return methodShape
+		}
+		if hasMethod(t, "MarshalThrift") || hasMethod(t, "UnmarshalThrift") {
+			if g.defaultShapeOf(t) == invalidShape {
+				return invalidShape
+			}
+			if hasMethod(t, "MarshalThrift") && hasMethod(t, "UnmarshalThrift") {
+				return methodShape
+			}
+			return reflectShape
+		}
+	}
+	return g.defaultShapeOf(t)
+}
+
+// defaultShapeOf returns the shape of t ignoring any methods.
+func (g *generator) defaultShapeOf(t types.Type) shape {
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		switch u.Kind() {
+		case types.Bool:
+			return boolShape
+		case types.Int8, types.Int16, types.Int32, types.Int64,
+			types.Uint8, types.Uint16, types.Uint32, types.Uint64:
+			return intShape
+		case types.Float32, types.Float64:
+			return doubleShape
+		case types.String:
+			return stringShape
+		}
+	case *types.Slice:
+		if types.Identical(u.Elem(), types.Typ[types.Uint8]) {
+			return bytesShape
+		}
+		if g.shapeOf(u.Elem()) != invalidShape {
+			return listShape
+		}
+	case *types.Array:
+		if _, ok := t.(*types.Named); !ok && types.Identical(t, types.NewArray(types.Typ[types.Uint8], 16)) {
+			return uuidShape
+		}
+	case *types.Map:
+		if g.shapeOf(u.Key()) != invalidShape && g.shapeOf(u.Elem()) != invalidShape {
+			return mapShape
+		}
+	case *types.Pointer:
+		if g.shapeOf(u.Elem()) != invalidShape {
+			return pointerShape
+		}
+	case *types.Struct:
+		return reflectShape
+	}
+	return invalidShape
+}
+
+func hasMethod(t types.Type, name string) bool {
+	obj, _, _ := types.LookupFieldOrMethod(types.NewPointer(t), false, nil, name)
+	_, ok := obj.(*types.Func)
+	return ok
+}
+
+// wireType returns the name of the thriftwire.Type constant of t.
+func (g *generator) wireType(t types.Type) string {
+	switch g.shapeOf(t) {
+	case boolShape:
+		return "thriftwire.Bool"
+	case intShape:
+		switch t.Underlying().(*types.Basic).Kind() {
+		case types.Int8, types.Uint8:
+			return "thriftwire.Byte"
+		case types.Int16, types.Uint16:
+			return "thriftwire.I16"
+		case types.Int32, types.Uint32:
+			return "thriftwire.I32"
+		default:
+			return "thriftwire.I64"
+		}
+	case doubleShape:
+		return "thriftwire.Double"
+	case stringShape, bytesShape:
+		return "thriftwire.String"
+	case uuidShape:
+		return "thriftwire.UUID"
+	case mapShape:
+		return "thriftwire.Map"
+	case setShape:
+		return "thriftwire.Set"
+	case listShape:
+		return "thriftwire.List"
+	case pointerShape:
+		return g.wireType(t.Underlying().(*types.Pointer).Elem())
+	case methodShape, reflectShape:
+		if _, ok := t.Underlying().(*types.Struct); ok {
+			return "thriftwire.Struct"
+		}
+		return g.defaultWireType(t)
+	}
+	panic("unreachable")
+}
+
+func (g *generator) defaultWireType(t types.Type) string {
+	switch g.defaultShapeOf(t) {
+	case methodShape, reflectShape:
+		return "thriftwire.Struct"
+	case pointerShape:
+		return g.wireType(t.Underlying().(*types.Pointer).Elem())
+	}
+	// Other shapes do not depend on methods.
+	return (&generator{pkg: g.pkg}).wireType(t.Underlying())
+}
+
+// intMethod returns the suffix of the thriftwire methods and
+// the Go type of an integer type.
+func intMethod(t types.Type) (string, string) {
+	switch t.Underlying().(*types.Basic).Kind() {
+	case types.Int8, types.Uint8:
+		return "Byte", "byte"
+	case types.Int16, types.Uint16:
+		return "I16", "int16"
+	case types.Int32, types.Uint32:
+		return "I32", "int32"
+	default:
+		return "I64", "int64"
+	}
+}
+
+func (g *generator) printf(format string, args ...any) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+func (g *generator) newVar(prefix string) string {
+	g.tmp++
+	return prefix + strconv.Itoa(g.tmp)
+}
+
+// typeString returns the Go syntax of t, importing packages as needed.
+func (g *generator) typeString(t types.Type) string {
+	return types.TypeString(t, func(p *types.Package) string {
+		if p == g.pkg {
+			return ""
+		}
+		return g.importPackage(p.Path(), p.Name())
+	})
+}
+
+func (g *generator) importPackage(path, name string) string {
+	if name, ok := g.imports[path]; ok {
+		return name
+	}
+	base := name
+	for i := 2; g.names[name] != ""; i++ {
+		name = base + strconv.Itoa(i)
+	}
+	g.imports[path] = name
+	g.names[name] = path
+	return name
+}
+
+// convert returns expr converted to the Go type t,
+// omitting the conversion if it is not needed.
+func (g *generator) convert(expr string, t types.Type, from types.Type) string {
+	if types.Identical(t, from) {
+		return expr
+	}
+	return g.typeString(t) + "(" + expr + ")"
+}
+
+func (g *generator) generate(command string) ([]byte, error) {
+	g.importPackage(thriftwirePath, "thriftwire")
+	for _, st := range g.types {
+		g.generateMarshal(st)
+		g.generateUnmarshal(st)
+	}
+	body := g.buf.Bytes()
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by %q; DO NOT EDIT.\n\n", command)
+	fmt.Fprintf(&buf, "package %s\n\n", g.pkg.Name())
+	if len(g.types) > 0 {
+		paths := make([]string, 0, len(g.imports))
+		for path := range g.imports {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		// Group standard packages before others like goimports.
+		sort.SliceStable(paths, func(i, j int) bool {
+			return isStd(paths[i]) && !isStd(paths[j])
+		})
+		buf.WriteString("import (\n")
+		for i, path := range paths {
+			if i > 0 && isStd(paths[i-1]) != isStd(path) {
+				buf.WriteString("\n")
+			}
+			if name := g.imports[path]; name != filepath.Base(path) {
+				fmt.Fprintf(&buf, "%s %q\n", name, path)
+			} else {
+				fmt.Fprintf(&buf, "%q\n", path)
+			}
+		}
+		buf.WriteString(")\n\n")
+	}
+	buf.Write(body)
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return buf.Bytes(), fmt.Errorf("invalid generated code: %w", err)
+	}
+	return src, nil
+}
+
+func isStd(path string) bool {
+	return !strings.Contains(strings.SplitN(path, "/", 2)[0], ".")
+}
+
+func (g *generator) isValidator(t types.Type) bool {
+	obj, _, _ := types.LookupFieldOrMethod(types.NewPointer(t), false, g.pkg, "Validate")
+	fn, ok := obj.(*types.Func)
+	if !ok {
+		return false
+	}
+	sig := fn.Type().(*types.Signature)
+	return sig.Params().Len() == 0 && sig.Results().Len() == 1 &&
+		types.Identical(sig.Results().At(0).Type(), types.Universe.Lookup("error").Type())
+}
+
+func (g *generator) generateMarshal(st *structType) {
+	t := st.obj.Type()
+	g.printf("// MarshalThrift implements [thrift.Marshaler].\n")
+	g.printf("func (x *%s) MarshalThrift(w thriftwire.Writer) error {\n", st.obj.Name())
+	if g.isValidator(t) {
+		g.printf("if err := x.Validate(); err != nil {\nreturn err\n}\n")
+	}
+	g.printf("if err := w.WriteStructBegin(thriftwire.StructHeader{Name: %q}); err != nil {\nreturn err\n}\n", st.obj.Name())
+	for _, f := range st.fields {
+		expr := "x." + f.name
+		if !f.required {
+			g.printf("if %s {\n", g.nonZero(expr, f.typ))
+		}
+		g.printf("if err := w.WriteFieldBegin(thriftwire.FieldHeader{Name: %q, Type: %s, ID: %d}); err != nil {\nreturn err\n}\n", f.name, g.wireType(f.typ), f.id)
+		g.marshalValue(expr, f.typ)
+		g.printf("if err := w.WriteFieldEnd(); err != nil {\nreturn err\n}\n")
+		if !f.required {
+			g.printf("}\n")
+		}
+	}
+	g.printf("return w.WriteStructEnd()\n")
+	g.printf("}\n\n")
+}
+
+// nonZero returns an expression that reports whether expr
+// is not omitted when marshaling.
+func (g *generator) nonZero(expr string, t types.Type) string {
+	if g.isZeroer(t) {
+		if _, ok := t.Underlying().(*types.Pointer); ok {
+			return fmt.Sprintf("%s != nil && !%[1]s.IsZero()", expr)
+		}
+		return fmt.Sprintf("!%s.IsZero()", expr)
+	}
+	return g.nonZeroValue(expr, t)
+}
+
+// nonZeroValue returns an expression that reports whether expr is not
+// the zero value of t, as by isZeroValue in package thrift: a negative
+// zero float is not zero, and blank struct fields are ignored.
+func (g *generator) nonZeroValue(expr string, t types.Type) string {
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		switch {
+		case u.Info()&types.IsBoolean != 0:
+			return expr
+		case u.Info()&types.IsString != 0:
+			return expr + ` != ""`
+		case u.Info()&types.IsFloat != 0:
+			mathName := g.importPackage("math", "math")
+			return fmt.Sprintf("%s.Float64bits(float64(%s)) != 0", mathName, expr)
+		default:
+			return expr + " != 0"
+		}
+	case *types.Slice, *types.Map, *types.Pointer, *types.Interface:
+		return expr + " != nil"
+	case *types.Array:
+		if g.shapeOf(t) == uuidShape {
+			return expr + " != [16]byte{}"
+		}
+		if types.Comparable(t) && !hasFloat(u) {
+			return fmt.Sprintf("%s != (%s{})", expr, g.typeString(t))
+		}
+		var conds []string
+		for i := int64(0); i < u.Len(); i++ {
+			conds = append(conds, g.nonZeroValue(fmt.Sprintf("%s[%d]", expr, i), u.Elem()))
+		}
+		return anyOf(conds)
+	case *types.Struct:
+		var conds []string
+		for i := 0; i < u.NumFields(); i++ {
+			if f := u.Field(i); f.Name() != "_" {
+				conds = append(conds, g.nonZeroValue(expr+"."+f.Name(), f.Type()))
+			}
+		}
+		return anyOf(conds)
+	}
+	panic("unreachable")
+}
+
+// anyOf returns an expression that reports whether any of conds is true.
+func anyOf(conds []string) string {
+	switch len(conds) {
+	case 0:
+		return "false"
+	case 1:
+		return conds[0]
+	}
+	return "(" + strings.Join(conds, " || ") + ")"
+}
+
+// hasFloat reports whether the array or struct type t has float elements,
+// which are compared by bits rather than by ==.
+func hasFloat(t types.Type) bool {
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		return u.Info()&types.IsFloat != 0
+	case *types.Array:
+		return hasFloat(u.Elem())
+	case *types.Struct:
+		for i := 0; i < u.NumFields(); i++ {
+			if hasFloat(u.Field(i).Type()) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// canCheckZero reports whether nonZero can check a value of type t,
+// which requires the fields of struct types to be accessible.
+func (g *generator) canCheckZero(t types.Type) bool {
+	return g.isZeroer(t) || g.canCheckZeroValue(t)
+}
+
+func (g *generator) canCheckZeroValue(t types.Type) bool {
+	switch u := t.Underlying().(type) {
+	case *types.Array:
+		return g.canCheckZeroValue(u.Elem())
+	case *types.Struct:
+		for i := 0; i < u.NumFields(); i++ {
+			f := u.Field(i)
+			if f.Name() == "_" {
+				continue
+			}
+			if !f.Exported() && f.Pkg() != g.pkg || !g.canCheckZeroValue(f.Type()) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// isZeroer reports whether t or *t has an IsZero() bool method.
+func (g *generator) isZeroer(t types.Type) bool {
+	obj, _, _ := types.LookupFieldOrMethod(types.NewPointer(t), false, g.pkg, "IsZero")
+	if _, ok := t.Underlying().(*types.Pointer); ok {
+		obj, _, _ = types.LookupFieldOrMethod(t, false, g.pkg, "IsZero")
+	}
+	fn, ok := obj.(*types.Func)
+	if !ok || !fn.Exported() {
+		return false
+	}
+	sig := fn.Type().(*types.Signature)
+	return sig.Params().Len() == 0 && sig.Results().Len() == 1 &&
+		types.Identical(sig.Results().At(0).Type(), types.Typ[types.Bool])
+}
+
+// derefOperand returns the operand of expr if it is a dereference,
+// since methods with pointer receivers can be called on pointers directly.
+func derefOperand(expr string) string {
+	if strings.HasPrefix(expr, "(*") && strings.HasSuffix(expr, ")") {
+		return expr[len("(*") : len(expr)-len(")")]
+	}
+	return expr
+}
+
+func (g *generator) checkErr(call string) {
+	g.printf("if err := %s; err != nil {\nreturn err\n}\n", call)
+}
+
+// marshalValue generates code to marshal the addressable expression expr of type t.
+func (g *generator) marshalValue(expr string, t types.Type) {
+	switch g.shapeOf(t) {
+	case boolShape:
+		g.checkErr(fmt.Sprintf("w.WriteBool(%s)", g.convert(expr, types.Typ[types.Bool], t)))
+	case intShape:
+		name, typ := intMethod(t)
+		g.checkErr(fmt.Sprintf("w.Write%s(%s(%s))", name, typ, expr))
+	case doubleShape:
+		g.checkErr(fmt.Sprintf("w.WriteDouble(%s)", g.convert(expr, types.Typ[types.Float64], t)))
+	case stringShape:
+		g.checkErr(fmt.Sprintf("w.WriteString(%s)", g.convert(expr, types.Typ[types.String], t)))
+	case bytesShape:
+		g.checkErr(fmt.Sprintf("w.WriteBytes(%s)", expr))
+	case uuidShape:
+		g.checkErr(fmt.Sprintf("w.WriteUUID(&%s)", expr))
+	case methodShape:
+		g.checkErr(fmt.Sprintf("%s.MarshalThrift(w)", derefOperand(expr)))
+	case reflectShape:
+		g.checkErr(fmt.Sprintf("%s.Marshal(w, &%s)", g.importPackage(thriftPath, "thrift"), expr))
+	case pointerShape:
+		elem := t.Underlying().(*types.Pointer).Elem()
+		p := g.newVar("p")
+		g.printf("{\n%s := %s\n", p, expr)
+		g.printf("if %s == nil {\n%[1]s = new(%s)\n}\n", p, g.typeString(elem))
+		g.marshalValue("(*"+p+")", elem)
+		g.printf("}\n")
+	case mapShape:
+		m := t.Underlying().(*types.Map)
+		k, v := g.newVar("k"), g.newVar("v")
+		g.checkErr(fmt.Sprintf("w.WriteMapBegin(thriftwire.MapHeader{Key: %s, Value: %s, Size: len(%s)})", g.wireType(m.Key()), g.wireType(m.Elem()), expr))
+		g.printf("for %s, %s := range %s {\n", k, v, expr)
+		g.marshalValue(k, m.Key())
+		g.marshalValue(v, m.Elem())
+		g.printf("}\n")
+		g.checkErr("w.WriteMapEnd()")
+	case setShape, listShape:
+		kind := "List"
+		if g.shapeOf(t) == setShape {
+			kind = "Set"
+		}
+		elem := t.Underlying().(*types.Slice).Elem()
+		i := g.newVar("i")
+		g.checkErr(fmt.Sprintf("w.Write%sBegin(thriftwire.%[1]sHeader{Element: %s, Size: len(%s)})", kind, g.wireType(elem), expr))
+		g.printf("for %s := range %s {\n", i, expr)
+		g.marshalValue(fmt.Sprintf("%s[%s]", expr, i), elem)
+		g.printf("}\n")
+		g.checkErr(fmt.Sprintf("w.Write%sEnd()", kind))
+	default:
+		panic("unreachable")
+	}
+}
+
+func (g *generator) generateUnmarshal(st *structType) {
+	t := st.obj.Type()
+	fmtName := g.importPackage("fmt", "fmt")
+	g.printf("// UnmarshalThrift implements [thrift.Unmarshaler].\n")
+	g.printf("func (x *%s) UnmarshalThrift(r thriftwire.Reader) error {\n", st.obj.Name())
+	g.printf("if _, err := r.ReadStructBegin(); err != nil {\nreturn err\n}\n")
+	g.printf("for {\n")
+	g.printf("h, err := r.ReadFieldBegin()\nif err != nil {\nreturn err\n}\n")
+	g.printf("if h.Type == thriftwire.Stop {\nbreak\n}\n")
+	g.printf("switch h.ID {\n")
+	for _, f := range st.fields {
+		g.printf("case %d:\n", f.id)
+		g.printf("if h.Type != %s {\nreturn %s.Errorf(\"unexpected %%v for field %%d\", h.Type, h.ID)\n}\n", g.wireType(f.typ), fmtName)
+		g.unmarshalValue("x."+f.name, f.typ)
+	}
+	g.printf("default:\n")
+	g.checkErr("thriftwire.Skip(r, h.Type)")
+	g.printf("}\n")
+	g.checkErr("r.ReadFieldEnd()")
+	g.printf("}\n")
+	g.checkErr("r.ReadStructEnd()")
+	if g.isValidator(t) {
+		g.printf("return x.Validate()\n")
+	} else {
+		g.printf("return nil\n")
+	}
+	g.printf("}\n\n")
+}
+
+// unmarshalValue generates code to unmarshal into the addressable expression expr of type t.
+// The Thrift type of the value must be checked beforehand.
+func (g *generator) unmarshalValue(expr string, t types.Type) {
+	read := func(method string, from types.Type) {
+		v := g.newVar("v")
+		g.printf("{\n%s, err := r.%s()\nif err != nil {\nreturn err\n}\n", v, method)
+		g.printf("%s = %s\n}\n", expr, g.convert(v, t, from))
+	}
+	switch g.shapeOf(t) {
+	case boolShape:
+		read("ReadBool", types.Typ[types.Bool])
+	case intShape:
+		name, _ := intMethod(t)
+		from := map[string]types.Type{
+			"Byte": types.Typ[types.Byte],
+			"I16":  types.Typ[types.Int16],
+			"I32":  types.Typ[types.Int32],
+			"I64":  types.Typ[types.Int64],
+		}[name]
+		read("Read"+name, from)
+	case doubleShape:
+		read("ReadDouble", types.Typ[types.Float64])
+	case stringShape:
+		read("ReadString", types.Typ[types.String])
+	case bytesShape:
+		v := g.newVar("v")
+		g.printf("{\n%s, err := r.ReadBytes(%s[:0])\nif err != nil {\nreturn err\n}\n", v, expr)
+		g.printf("%s = %s\n}\n", expr, v)
+	case uuidShape:
+		g.checkErr(fmt.Sprintf("r.ReadUUID(&%s)", expr))
+	case methodShape:
+		g.checkErr(fmt.Sprintf("%s.UnmarshalThrift(r)", derefOperand(expr)))
+	case reflectShape:
+		g.checkErr(fmt.Sprintf("%s.Unmarshal(r, &%s)", g.importPackage(thriftPath, "thrift"), expr))
+	case pointerShape:
+		elem := t.Underlying().(*types.Pointer).Elem()
+		g.printf("if %s == nil {\n%[1]s = new(%s)\n}\n", expr, g.typeString(elem))
+		g.unmarshalValue("(*"+expr+")", elem)
+	case mapShape:
+		m := t.Underlying().(*types.Map)
+		fmtName := g.importPackage("fmt", "fmt")
+		h, i, k, v := g.newVar("h"), g.newVar("i"), g.newVar("k"), g.newVar("v")
+		g.printf("{\n%s, err := r.ReadMapBegin()\nif err != nil {\nreturn err\n}\n", h)
+		g.printf("if %s.Size > 0 {\n", h)
+		g.printf("if %[1]s.Key != %[2]s || %[1]s.Value != %[3]s {\n", h, g.wireType(m.Key()), g.wireType(m.Elem()))
+		g.printf("return %s.Errorf(\"unexpected map<%%v, %%v>\", %s.Key, %[2]s.Value)\n}\n", fmtName, h)
+		g.printf("if %s == nil {\n%[1]s = make(%s)\n}\n", expr, g.typeString(t))
+		g.printf("for %s := 0; %[1]s < %s.Size; %[1]s++ {\n", i, h)
+		g.printf("var %s %s\n", k, g.typeString(m.Key()))
+		g.unmarshalValue(k, m.Key())
+		g.printf("%s := %s[%s]\n", v, expr, k)
+		g.unmarshalValue(v, m.Elem())
+		g.printf("%s[%s] = %s\n", expr, k, v)
+		g.printf("}\n}\n")
+		g.checkErr("r.ReadMapEnd()")
+		g.printf("}\n")
+	case setShape, listShape:
+		kind := "List"
+		if g.shapeOf(t) == setShape {
+			kind = "Set"
+		}
+		elem := t.Underlying().(*types.Slice).Elem()
+		fmtName := g.importPackage("fmt", "fmt")
+		h, i, e := g.newVar("h"), g.newVar("i"), g.newVar("e")
+		g.printf("{\n%s, err := r.Read%sBegin()\nif err != nil {\nreturn err\n}\n", h, kind)
+		g.printf("%s = %[1]s[:0]\n", expr)
+		g.printf("if %s.Size > 0 {\n", h)
+		g.printf("if %s.Element != %s {\n", h, g.wireType(elem))
+		g.printf("return %s.Errorf(\"unexpected %s<%%v>\", %s.Element)\n}\n", fmtName, strings.ToLower(kind), h)
+		g.printf("for %s := 0; %[1]s < %s.Size; %[1]s++ {\n", i, h)
+		g.printf("var %s %s\n", e, g.typeString(elem))
+		g.printf("%s = append(%[1]s, %s)\n", expr, e)
+		g.unmarshalValue(fmt.Sprintf("%s[%s]", expr, i), elem)
+		g.printf("}\n}\n")
+		g.checkErr(fmt.Sprintf("r.Read%sEnd()", kind))
+		g.printf("}\n")
+	default:
+		panic("unreachable")
+	}
+}
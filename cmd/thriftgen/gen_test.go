@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	dir := filepath.Join("internal", "sample")
+	var warnings []string
+	got, err := generate(dir, "zthrift.go", nil, "thriftgen", func(msg string) {
+		warnings = append(warnings, msg)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile(filepath.Join(dir, "zthrift.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("generated code for %s is out of date; run go generate", dir)
+	}
+	wantWarnings := []string{"skipping Unsupported: field N has unsupported type int"}
+	if !reflect.DeepEqual(warnings, wantWarnings) {
+		t.Errorf("warnings:\ngot  %q\nwant %q", warnings, wantWarnings)
+	}
+}
+
+func TestGenerateTypes(t *testing.T) {
+	dir := filepath.Join("internal", "sample")
+	src, err := generate(dir, "zthrift.go", []string{"Point"}, "thriftgen -type Point", func(string) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, method := range []string{"func (x *Point) MarshalThrift", "func (x *Point) UnmarshalThrift"} {
+		if !bytes.Contains(src, []byte(method)) {
+			t.Errorf("generated code does not contain %s", method)
+		}
+	}
+	if bytes.Contains(src, []byte("func (x *User)")) {
+		t.Errorf("generated code contains methods of unselected type User")
+	}
+
+	if _, err := generate(dir, "zthrift.go", []string{"Missing"}, "thriftgen", func(string) {}); err == nil {
+		t.Errorf("generate with a missing type succeeded")
+	}
+}
@@ -0,0 +1,90 @@
+// Package sample contains types for testing the code generated by thriftgen.
+package sample
+
+import (
+	"errors"
+
+	"github.com/itstarsun/go-thrift/thrift"
+)
+
+//go:generate go run github.com/itstarsun/go-thrift/cmd/thriftgen
+
+type Status int32
+
+const (
+	StatusUnknown Status = iota
+	StatusActive
+	StatusDisabled
+)
+
+type Name string
+
+type Point struct {
+	X float64 `thrift:"1"`
+	Y float32 `thrift:"2"`
+}
+
+// IsZero reports whether p is the origin.
+func (p Point) IsZero() bool {
+	return p.X == 0 && p.Y == 0
+}
+
+type User struct {
+	ID       int64              `thrift:"1,required"`
+	Name     Name               `thrift:"2"`
+	Status   Status             `thrift:"3"`
+	Active   bool               `thrift:"4"`
+	Avatar   []byte             `thrift:"5"`
+	UUID     [16]byte           `thrift:"6"`
+	Tags     thrift.Set[string] `thrift:"7"`
+	Aliases  thrift.List[Name]  `thrift:"8"`
+	Home     *Point             `thrift:"9"`
+	Work     Point              `thrift:"10"`
+	Friends  []*User            `thrift:"11"`
+	Groups   map[string][]int16 `thrift:"12"`
+	Scores   map[int8]uint16    `thrift:"13"`
+	Password string             `thrift:"14,sensitive"`
+	Extra    struct {
+		Note string `thrift:"1"`
+	} `thrift:"15"`
+	Ignored string `thrift:"-"`
+	private int
+}
+
+var errNoName = errors.New("name is required")
+
+type Group struct {
+	Name    string  `thrift:"1"`
+	Members []User  `thrift:"2"`
+	Owner   *User   `thrift:"3"`
+	Ratio   float64 `thrift:"4,required"`
+}
+
+// Validate implements [thrift.Validator].
+func (g *Group) Validate() error {
+	if g.Name == "" {
+		return errNoName
+	}
+	return nil
+}
+
+type Vector struct {
+	X float64 `thrift:"1"`
+	Y float64 `thrift:"2"`
+}
+
+// Box has struct fields without IsZero methods,
+// which are omitted if all of their fields are zero.
+type Box struct {
+	Min   Vector `thrift:"1"`
+	Max   Vector `thrift:"2"`
+	Label struct {
+		Text  string  `thrift:"1"`
+		Scale float32 `thrift:"2"`
+	} `thrift:"3"`
+}
+
+// Unsupported is skipped because it contains an int field.
+type Unsupported struct {
+	N int `thrift:"1"`
+}
@@ -0,0 +1,175 @@
+package sample
+
+import (
+	"bytes"
+	"errors"
+	"go/parser"
+	"go/token"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/itstarsun/go-thrift/encoding/thriftcompact"
+	"github.com/itstarsun/go-thrift/internal/thriftmemo"
+	"github.com/itstarsun/go-thrift/thrift"
+)
+
+// Types without the generated methods, which use the reflective implementation.
+type (
+	plainPoint Point
+	plainUser  User
+	plainGroup Group
+	plainBox   Box
+)
+
+var testUsers = []User{
+	{},
+	{ID: 1},
+	{
+		ID:       2,
+		Name:     "gopher",
+		Status:   StatusActive,
+		Active:   true,
+		Avatar:   []byte{},
+		UUID:     [16]byte{1, 2, 3},
+		Tags:     thrift.Set[string]{"a", "b"},
+		Aliases:  thrift.List[Name]{},
+		Home:     &Point{X: 1, Y: -2},
+		Work:     Point{Y: 3},
+		Friends:  []*User{nil, {ID: 3, Home: &Point{}}},
+		Groups:   map[string][]int16{"admin": {1, 2}},
+		Scores:   map[int8]uint16{-1: 65535},
+		Password: "secret",
+		Ignored:  "ignored",
+	},
+}
+
+func init() {
+	testUsers[2].Extra.Note = "note"
+}
+
+var negZero = math.Copysign(0, -1)
+
+func TestCompatibility(t *testing.T) {
+	tests := []struct {
+		generated any
+		plain     any
+	}{
+		{&Point{X: 1}, &plainPoint{X: 1}},
+		{&Point{}, &plainPoint{}},
+		{&Point{X: negZero, Y: float32(negZero)}, &plainPoint{X: negZero, Y: float32(negZero)}},
+		{&Box{}, &plainBox{}},
+		{&Box{Min: Vector{X: negZero}}, &plainBox{Min: Vector{X: negZero}}},
+		{&Box{Max: Vector{Y: 1}}, &plainBox{Max: Vector{Y: 1}}},
+	}
+	var box Box
+	box.Label.Scale = float32(negZero)
+	tests = append(tests, struct{ generated, plain any }{&box, (*plainBox)(&box)})
+	for i := range testUsers {
+		tests = append(tests, struct{ generated, plain any }{&testUsers[i], (*plainUser)(&testUsers[i])})
+	}
+	tests = append(tests, struct{ generated, plain any }{
+		&Group{Name: "g", Members: testUsers, Owner: &testUsers[1]},
+		&plainGroup{Name: "g", Members: testUsers, Owner: &testUsers[1]},
+	})
+
+	for _, tt := range tests {
+		var want, got thriftmemo.Memo
+		if err := thrift.Marshal(want.Writer(), tt.plain); err != nil {
+			t.Fatal(err)
+		}
+		if err := thrift.Marshal(got.Writer(), tt.generated); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got.Steps(), want.Steps()) {
+			t.Errorf("Marshal(%T):\ngot  %v\nwant %v", tt.generated, got.Steps(), want.Steps())
+		}
+
+		var wantBuf, gotBuf bytes.Buffer
+		if err := marshalCompact(&wantBuf, tt.plain); err != nil {
+			t.Fatal(err)
+		}
+		if err := marshalCompact(&gotBuf, tt.generated); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(gotBuf.Bytes(), wantBuf.Bytes()) {
+			t.Errorf("Marshal(%T):\ngot  %x\nwant %x", tt.generated, gotBuf.Bytes(), wantBuf.Bytes())
+		}
+
+		gotValue := reflect.New(reflect.TypeOf(tt.generated).Elem())
+		if err := thrift.Unmarshal(thriftcompact.Protocol.NewReader(&gotBuf), gotValue.Interface()); err != nil {
+			t.Fatal(err)
+		}
+		wantValue := reflect.New(reflect.TypeOf(tt.plain).Elem())
+		if err := thrift.Unmarshal(thriftcompact.Protocol.NewReader(&wantBuf), wantValue.Interface()); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(gotValue.Elem().Convert(wantValue.Elem().Type()).Interface(), wantValue.Elem().Interface()) {
+			t.Errorf("Unmarshal(%T):\ngot  %+v\nwant %+v", tt.generated, gotValue.Elem(), wantValue.Elem())
+		}
+	}
+}
+
+func TestNoReflect(t *testing.T) {
+	f, err := parser.ParseFile(token.NewFileSet(), "zthrift.go", nil, parser.ImportsOnly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, imp := range f.Imports {
+		if imp.Path.Value == `"reflect"` {
+			t.Error("generated code imports reflect")
+		}
+	}
+}
+
+func marshalCompact(buf *bytes.Buffer, v any) error {
+	w := thriftcompact.Protocol.NewWriter(buf)
+	if err := thrift.Marshal(w, v); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+func TestValidate(t *testing.T) {
+	var m thriftmemo.Memo
+	err := thrift.Marshal(m.Writer(), &Group{})
+	if !errors.Is(err, errNoName) {
+		t.Errorf("Marshal = %v, want %v", err, errNoName)
+	}
+
+	if err := thrift.Marshal(m.Writer(), &plainGroup{}); err != nil {
+		t.Fatal(err)
+	}
+	err = thrift.Unmarshal(m.Reader(), &Group{})
+	if !errors.Is(err, errNoName) {
+		t.Errorf("Unmarshal = %v, want %v", err, errNoName)
+	}
+}
+
+func TestUnmarshalMismatch(t *testing.T) {
+	var m thriftmemo.Memo
+	if err := thrift.Marshal(m.Writer(), &struct {
+		ID      int64       `thrift:"1"`
+		Unknown *plainPoint `thrift:"100"`
+	}{1, &plainPoint{X: 1}}); err != nil {
+		t.Fatal(err)
+	}
+	var u User
+	if err := thrift.Unmarshal(m.Reader(), &u); err != nil {
+		t.Fatal(err) // unknown fields are skipped
+	}
+	if u.ID != 1 {
+		t.Errorf("Unmarshal: ID = %d, want 1", u.ID)
+	}
+
+	m.Reset()
+	if err := thrift.Marshal(m.Writer(), &struct {
+		ID string `thrift:"1"`
+	}{"1"}); err != nil {
+		t.Fatal(err)
+	}
+	var se *thrift.SemanticError
+	if err := thrift.Unmarshal(m.Reader(), &u); !errors.As(err, &se) {
+		t.Errorf("Unmarshal = %v, want a SemanticError", err)
+	}
+}
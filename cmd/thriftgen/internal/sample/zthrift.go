@@ -0,0 +1,969 @@
+// Code generated by "thriftgen"; DO NOT EDIT.
+
+package sample
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/itstarsun/go-thrift/encoding/thriftwire"
+	"github.com/itstarsun/go-thrift/thrift"
+)
+
+// MarshalThrift implements [thrift.Marshaler].
+func (x *Point) MarshalThrift(w thriftwire.Writer) error {
+	if err := w.WriteStructBegin(thriftwire.StructHeader{Name: "Point"}); err != nil {
+		return err
+	}
+	if math.Float64bits(float64(x.X)) != 0 {
+		if err := w.WriteFieldBegin(thriftwire.FieldHeader{Name: "X", Type: thriftwire.Double, ID: 1}); err != nil {
+			return err
+		}
+		if err := w.WriteDouble(x.X); err != nil {
+			return err
+		}
+		if err := w.WriteFieldEnd(); err != nil {
+			return err
+		}
+	}
+	if math.Float64bits(float64(x.Y)) != 0 {
+		if err := w.WriteFieldBegin(thriftwire.FieldHeader{Name: "Y", Type: thriftwire.Double, ID: 2}); err != nil {
+			return err
+		}
+		if err := w.WriteDouble(float64(x.Y)); err != nil {
+			return err
+		}
+		if err := w.WriteFieldEnd(); err != nil {
+			return err
+		}
+	}
+	return w.WriteStructEnd()
+}
+
+// UnmarshalThrift implements [thrift.Unmarshaler].
+func (x *Point) UnmarshalThrift(r thriftwire.Reader) error {
+	if _, err := r.ReadStructBegin(); err != nil {
+		return err
+	}
+	for {
+		h, err := r.ReadFieldBegin()
+		if err != nil {
+			return err
+		}
+		if h.Type == thriftwire.Stop {
+			break
+		}
+		switch h.ID {
+		case 1:
+			if h.Type != thriftwire.Double {
+				return fmt.Errorf("unexpected %v for field %d", h.Type, h.ID)
+			}
+			{
+				v1, err := r.ReadDouble()
+				if err != nil {
+					return err
+				}
+				x.X = v1
+			}
+		case 2:
+			if h.Type != thriftwire.Double {
+				return fmt.Errorf("unexpected %v for field %d", h.Type, h.ID)
+			}
+			{
+				v2, err := r.ReadDouble()
+				if err != nil {
+					return err
+				}
+				x.Y = float32(v2)
+			}
+		default:
+			if err := thriftwire.Skip(r, h.Type); err != nil {
+				return err
+			}
+		}
+		if err := r.ReadFieldEnd(); err != nil {
+			return err
+		}
+	}
+	if err := r.ReadStructEnd(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// MarshalThrift implements [thrift.Marshaler].
+func (x *User) MarshalThrift(w thriftwire.Writer) error {
+	if err := w.WriteStructBegin(thriftwire.StructHeader{Name: "User"}); err != nil {
+		return err
+	}
+	if err := w.WriteFieldBegin(thriftwire.FieldHeader{Name: "ID", Type: thriftwire.I64, ID: 1}); err != nil {
+		return err
+	}
+	if err := w.WriteI64(int64(x.ID)); err != nil {
+		return err
+	}
+	if err := w.WriteFieldEnd(); err != nil {
+		return err
+	}
+	if x.Name != "" {
+		if err := w.WriteFieldBegin(thriftwire.FieldHeader{Name: "Name", Type: thriftwire.String, ID: 2}); err != nil {
+			return err
+		}
+		if err := w.WriteString(string(x.Name)); err != nil {
+			return err
+		}
+		if err := w.WriteFieldEnd(); err != nil {
+			return err
+		}
+	}
+	if x.Status != 0 {
+		if err := w.WriteFieldBegin(thriftwire.FieldHeader{Name: "Status", Type: thriftwire.I32, ID: 3}); err != nil {
+			return err
+		}
+		if err := w.WriteI32(int32(x.Status)); err != nil {
+			return err
+		}
+		if err := w.WriteFieldEnd(); err != nil {
+			return err
+		}
+	}
+	if x.Active {
+		if err := w.WriteFieldBegin(thriftwire.FieldHeader{Name: "Active", Type: thriftwire.Bool, ID: 4}); err != nil {
+			return err
+		}
+		if err := w.WriteBool(x.Active); err != nil {
+			return err
+		}
+		if err := w.WriteFieldEnd(); err != nil {
+			return err
+		}
+	}
+	if x.Avatar != nil {
+		if err := w.WriteFieldBegin(thriftwire.FieldHeader{Name: "Avatar", Type: thriftwire.String, ID: 5}); err != nil {
+			return err
+		}
+		if err := w.WriteBytes(x.Avatar); err != nil {
+			return err
+		}
+		if err := w.WriteFieldEnd(); err != nil {
+			return err
+		}
+	}
+	if x.UUID != [16]byte{} {
+		if err := w.WriteFieldBegin(thriftwire.FieldHeader{Name: "UUID", Type: thriftwire.UUID, ID: 6}); err != nil {
+			return err
+		}
+		if err := w.WriteUUID(&x.UUID); err != nil {
+			return err
+		}
+		if err := w.WriteFieldEnd(); err != nil {
+			return err
+		}
+	}
+	if x.Tags != nil {
+		if err := w.WriteFieldBegin(thriftwire.FieldHeader{Name: "Tags", Type: thriftwire.Set, ID: 7}); err != nil {
+			return err
+		}
+		if err := w.WriteSetBegin(thriftwire.SetHeader{Element: thriftwire.String, Size: len(x.Tags)}); err != nil {
+			return err
+		}
+		for i3 := range x.Tags {
+			if err := w.WriteString(x.Tags[i3]); err != nil {
+				return err
+			}
+		}
+		if err := w.WriteSetEnd(); err != nil {
+			return err
+		}
+		if err := w.WriteFieldEnd(); err != nil {
+			return err
+		}
+	}
+	if x.Aliases != nil {
+		if err := w.WriteFieldBegin(thriftwire.FieldHeader{Name: "Aliases", Type: thriftwire.List, ID: 8}); err != nil {
+			return err
+		}
+		if err := w.WriteListBegin(thriftwire.ListHeader{Element: thriftwire.String, Size: len(x.Aliases)}); err != nil {
+			return err
+		}
+		for i4 := range x.Aliases {
+			if err := w.WriteString(string(x.Aliases[i4])); err != nil {
+				return err
+			}
+		}
+		if err := w.WriteListEnd(); err != nil {
+			return err
+		}
+		if err := w.WriteFieldEnd(); err != nil {
+			return err
+		}
+	}
+	if x.Home != nil && !x.Home.IsZero() {
+		if err := w.WriteFieldBegin(thriftwire.FieldHeader{Name: "Home", Type: thriftwire.Struct, ID: 9}); err != nil {
+			return err
+		}
+		{
+			p5 := x.Home
+			if p5 == nil {
+				p5 = new(Point)
+			}
+			if err := p5.MarshalThrift(w); err != nil {
+				return err
+			}
+		}
+		if err := w.WriteFieldEnd(); err != nil {
+			return err
+		}
+	}
+	if !x.Work.IsZero() {
+		if err := w.WriteFieldBegin(thriftwire.FieldHeader{Name: "Work", Type: thriftwire.Struct, ID: 10}); err != nil {
+			return err
+		}
+		if err := x.Work.MarshalThrift(w); err != nil {
+			return err
+		}
+		if err := w.WriteFieldEnd(); err != nil {
+			return err
+		}
+	}
+	if x.Friends != nil {
+		if err := w.WriteFieldBegin(thriftwire.FieldHeader{Name: "Friends", Type: thriftwire.List, ID: 11}); err != nil {
+			return err
+		}
+		if err := w.WriteListBegin(thriftwire.ListHeader{Element: thriftwire.Struct, Size: len(x.Friends)}); err != nil {
+			return err
+		}
+		for i6 := range x.Friends {
+			{
+				p7 := x.Friends[i6]
+				if p7 == nil {
+					p7 = new(User)
+				}
+				if err := p7.MarshalThrift(w); err != nil {
+					return err
+				}
+			}
+		}
+		if err := w.WriteListEnd(); err != nil {
+			return err
+		}
+		if err := w.WriteFieldEnd(); err != nil {
+			return err
+		}
+	}
+	if x.Groups != nil {
+		if err := w.WriteFieldBegin(thriftwire.FieldHeader{Name: "Groups", Type: thriftwire.Map, ID: 12}); err != nil {
+			return err
+		}
+		if err := w.WriteMapBegin(thriftwire.MapHeader{Key: thriftwire.String, Value: thriftwire.List, Size: len(x.Groups)}); err != nil {
+			return err
+		}
+		for k8, v9 := range x.Groups {
+			if err := w.WriteString(k8); err != nil {
+				return err
+			}
+			if err := w.WriteListBegin(thriftwire.ListHeader{Element: thriftwire.I16, Size: len(v9)}); err != nil {
+				return err
+			}
+			for i10 := range v9 {
+				if err := w.WriteI16(int16(v9[i10])); err != nil {
+					return err
+				}
+			}
+			if err := w.WriteListEnd(); err != nil {
+				return err
+			}
+		}
+		if err := w.WriteMapEnd(); err != nil {
+			return err
+		}
+		if err := w.WriteFieldEnd(); err != nil {
+			return err
+		}
+	}
+	if x.Scores != nil {
+		if err := w.WriteFieldBegin(thriftwire.FieldHeader{Name: "Scores", Type: thriftwire.Map, ID: 13}); err != nil {
+			return err
+		}
+		if err := w.WriteMapBegin(thriftwire.MapHeader{Key: thriftwire.Byte, Value: thriftwire.I16, Size: len(x.Scores)}); err != nil {
+			return err
+		}
+		for k11, v12 := range x.Scores {
+			if err := w.WriteByte(byte(k11)); err != nil {
+				return err
+			}
+			if err := w.WriteI16(int16(v12)); err != nil {
+				return err
+			}
+		}
+		if err := w.WriteMapEnd(); err != nil {
+			return err
+		}
+		if err := w.WriteFieldEnd(); err != nil {
+			return err
+		}
+	}
+	if x.Password != "" {
+		if err := w.WriteFieldBegin(thriftwire.FieldHeader{Name: "Password", Type: thriftwire.String, ID: 14}); err != nil {
+			return err
+		}
+		if err := w.WriteString(x.Password); err != nil {
+			return err
+		}
+		if err := w.WriteFieldEnd(); err != nil {
+			return err
+		}
+	}
+	if x.Extra.Note != "" {
+		if err := w.WriteFieldBegin(thriftwire.FieldHeader{Name: "Extra", Type: thriftwire.Struct, ID: 15}); err != nil {
+			return err
+		}
+		if err := thrift.Marshal(w, &x.Extra); err != nil {
+			return err
+		}
+		if err := w.WriteFieldEnd(); err != nil {
+			return err
+		}
+	}
+	return w.WriteStructEnd()
+}
+
+// UnmarshalThrift implements [thrift.Unmarshaler].
+func (x *User) UnmarshalThrift(r thriftwire.Reader) error {
+	if _, err := r.ReadStructBegin(); err != nil {
+		return err
+	}
+	for {
+		h, err := r.ReadFieldBegin()
+		if err != nil {
+			return err
+		}
+		if h.Type == thriftwire.Stop {
+			break
+		}
+		switch h.ID {
+		case 1:
+			if h.Type != thriftwire.I64 {
+				return fmt.Errorf("unexpected %v for field %d", h.Type, h.ID)
+			}
+			{
+				v13, err := r.ReadI64()
+				if err != nil {
+					return err
+				}
+				x.ID = v13
+			}
+		case 2:
+			if h.Type != thriftwire.String {
+				return fmt.Errorf("unexpected %v for field %d", h.Type, h.ID)
+			}
+			{
+				v14, err := r.ReadString()
+				if err != nil {
+					return err
+				}
+				x.Name = Name(v14)
+			}
+		case 3:
+			if h.Type != thriftwire.I32 {
+				return fmt.Errorf("unexpected %v for field %d", h.Type, h.ID)
+			}
+			{
+				v15, err := r.ReadI32()
+				if err != nil {
+					return err
+				}
+				x.Status = Status(v15)
+			}
+		case 4:
+			if h.Type != thriftwire.Bool {
+				return fmt.Errorf("unexpected %v for field %d", h.Type, h.ID)
+			}
+			{
+				v16, err := r.ReadBool()
+				if err != nil {
+					return err
+				}
+				x.Active = v16
+			}
+		case 5:
+			if h.Type != thriftwire.String {
+				return fmt.Errorf("unexpected %v for field %d", h.Type, h.ID)
+			}
+			{
+				v17, err := r.ReadBytes(x.Avatar[:0])
+				if err != nil {
+					return err
+				}
+				x.Avatar = v17
+			}
+		case 6:
+			if h.Type != thriftwire.UUID {
+				return fmt.Errorf("unexpected %v for field %d", h.Type, h.ID)
+			}
+			if err := r.ReadUUID(&x.UUID); err != nil {
+				return err
+			}
+		case 7:
+			if h.Type != thriftwire.Set {
+				return fmt.Errorf("unexpected %v for field %d", h.Type, h.ID)
+			}
+			{
+				h18, err := r.ReadSetBegin()
+				if err != nil {
+					return err
+				}
+				x.Tags = x.Tags[:0]
+				if h18.Size > 0 {
+					if h18.Element != thriftwire.String {
+						return fmt.Errorf("unexpected set<%v>", h18.Element)
+					}
+					for i19 := 0; i19 < h18.Size; i19++ {
+						var e20 string
+						x.Tags = append(x.Tags, e20)
+						{
+							v21, err := r.ReadString()
+							if err != nil {
+								return err
+							}
+							x.Tags[i19] = v21
+						}
+					}
+				}
+				if err := r.ReadSetEnd(); err != nil {
+					return err
+				}
+			}
+		case 8:
+			if h.Type != thriftwire.List {
+				return fmt.Errorf("unexpected %v for field %d", h.Type, h.ID)
+			}
+			{
+				h22, err := r.ReadListBegin()
+				if err != nil {
+					return err
+				}
+				x.Aliases = x.Aliases[:0]
+				if h22.Size > 0 {
+					if h22.Element != thriftwire.String {
+						return fmt.Errorf("unexpected list<%v>", h22.Element)
+					}
+					for i23 := 0; i23 < h22.Size; i23++ {
+						var e24 Name
+						x.Aliases = append(x.Aliases, e24)
+						{
+							v25, err := r.ReadString()
+							if err != nil {
+								return err
+							}
+							x.Aliases[i23] = Name(v25)
+						}
+					}
+				}
+				if err := r.ReadListEnd(); err != nil {
+					return err
+				}
+			}
+		case 9:
+			if h.Type != thriftwire.Struct {
+				return fmt.Errorf("unexpected %v for field %d", h.Type, h.ID)
+			}
+			if x.Home == nil {
+				x.Home = new(Point)
+			}
+			if err := x.Home.UnmarshalThrift(r); err != nil {
+				return err
+			}
+		case 10:
+			if h.Type != thriftwire.Struct {
+				return fmt.Errorf("unexpected %v for field %d", h.Type, h.ID)
+			}
+			if err := x.Work.UnmarshalThrift(r); err != nil {
+				return err
+			}
+		case 11:
+			if h.Type != thriftwire.List {
+				return fmt.Errorf("unexpected %v for field %d", h.Type, h.ID)
+			}
+			{
+				h26, err := r.ReadListBegin()
+				if err != nil {
+					return err
+				}
+				x.Friends = x.Friends[:0]
+				if h26.Size > 0 {
+					if h26.Element != thriftwire.Struct {
+						return fmt.Errorf("unexpected list<%v>", h26.Element)
+					}
+					for i27 := 0; i27 < h26.Size; i27++ {
+						var e28 *User
+						x.Friends = append(x.Friends, e28)
+						if x.Friends[i27] == nil {
+							x.Friends[i27] = new(User)
+						}
+						if err := x.Friends[i27].UnmarshalThrift(r); err != nil {
+							return err
+						}
+					}
+				}
+				if err := r.ReadListEnd(); err != nil {
+					return err
+				}
+			}
+		case 12:
+			if h.Type != thriftwire.Map {
+				return fmt.Errorf("unexpected %v for field %d", h.Type, h.ID)
+			}
+			{
+				h29, err := r.ReadMapBegin()
+				if err != nil {
+					return err
+				}
+				if h29.Size > 0 {
+					if h29.Key != thriftwire.String || h29.Value != thriftwire.List {
+						return fmt.Errorf("unexpected map<%v, %v>", h29.Key, h29.Value)
+					}
+					if x.Groups == nil {
+						x.Groups = make(map[string][]int16)
+					}
+					for i30 := 0; i30 < h29.Size; i30++ {
+						var k31 string
+						{
+							v33, err := r.ReadString()
+							if err != nil {
+								return err
+							}
+							k31 = v33
+						}
+						v32 := x.Groups[k31]
+						{
+							h34, err := r.ReadListBegin()
+							if err != nil {
+								return err
+							}
+							v32 = v32[:0]
+							if h34.Size > 0 {
+								if h34.Element != thriftwire.I16 {
+									return fmt.Errorf("unexpected list<%v>", h34.Element)
+								}
+								for i35 := 0; i35 < h34.Size; i35++ {
+									var e36 int16
+									v32 = append(v32, e36)
+									{
+										v37, err := r.ReadI16()
+										if err != nil {
+											return err
+										}
+										v32[i35] = v37
+									}
+								}
+							}
+							if err := r.ReadListEnd(); err != nil {
+								return err
+							}
+						}
+						x.Groups[k31] = v32
+					}
+				}
+				if err := r.ReadMapEnd(); err != nil {
+					return err
+				}
+			}
+		case 13:
+			if h.Type != thriftwire.Map {
+				return fmt.Errorf("unexpected %v for field %d", h.Type, h.ID)
+			}
+			{
+				h38, err := r.ReadMapBegin()
+				if err != nil {
+					return err
+				}
+				if h38.Size > 0 {
+					if h38.Key != thriftwire.Byte || h38.Value != thriftwire.I16 {
+						return fmt.Errorf("unexpected map<%v, %v>", h38.Key, h38.Value)
+					}
+					if x.Scores == nil {
+						x.Scores = make(map[int8]uint16)
+					}
+					for i39 := 0; i39 < h38.Size; i39++ {
+						var k40 int8
+						{
+							v42, err := r.ReadByte()
+							if err != nil {
+								return err
+							}
+							k40 = int8(v42)
+						}
+						v41 := x.Scores[k40]
+						{
+							v43, err := r.ReadI16()
+							if err != nil {
+								return err
+							}
+							v41 = uint16(v43)
+						}
+						x.Scores[k40] = v41
+					}
+				}
+				if err := r.ReadMapEnd(); err != nil {
+					return err
+				}
+			}
+		case 14:
+			if h.Type != thriftwire.String {
+				return fmt.Errorf("unexpected %v for field %d", h.Type, h.ID)
+			}
+			{
+				v44, err := r.ReadString()
+				if err != nil {
+					return err
+				}
+				x.Password = v44
+			}
+		case 15:
+			if h.Type != thriftwire.Struct {
+				return fmt.Errorf("unexpected %v for field %d", h.Type, h.ID)
+			}
+			if err := thrift.Unmarshal(r, &x.Extra); err != nil {
+				return err
+			}
+		default:
+			if err := thriftwire.Skip(r, h.Type); err != nil {
+				return err
+			}
+		}
+		if err := r.ReadFieldEnd(); err != nil {
+			return err
+		}
+	}
+	if err := r.ReadStructEnd(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// MarshalThrift implements [thrift.Marshaler].
+func (x *Group) MarshalThrift(w thriftwire.Writer) error {
+	if err := x.Validate(); err != nil {
+		return err
+	}
+	if err := w.WriteStructBegin(thriftwire.StructHeader{Name: "Group"}); err != nil {
+		return err
+	}
+	if x.Name != "" {
+		if err := w.WriteFieldBegin(thriftwire.FieldHeader{Name: "Name", Type: thriftwire.String, ID: 1}); err != nil {
+			return err
+		}
+		if err := w.WriteString(x.Name); err != nil {
+			return err
+		}
+		if err := w.WriteFieldEnd(); err != nil {
+			return err
+		}
+	}
+	if x.Members != nil {
+		if err := w.WriteFieldBegin(thriftwire.FieldHeader{Name: "Members", Type: thriftwire.List, ID: 2}); err != nil {
+			return err
+		}
+		if err := w.WriteListBegin(thriftwire.ListHeader{Element: thriftwire.Struct, Size: len(x.Members)}); err != nil {
+			return err
+		}
+		for i45 := range x.Members {
+			if err := x.Members[i45].MarshalThrift(w); err != nil {
+				return err
+			}
+		}
+		if err := w.WriteListEnd(); err != nil {
+			return err
+		}
+		if err := w.WriteFieldEnd(); err != nil {
+			return err
+		}
+	}
+	if x.Owner != nil {
+		if err := w.WriteFieldBegin(thriftwire.FieldHeader{Name: "Owner", Type: thriftwire.Struct, ID: 3}); err != nil {
+			return err
+		}
+		{
+			p46 := x.Owner
+			if p46 == nil {
+				p46 = new(User)
+			}
+			if err := p46.MarshalThrift(w); err != nil {
+				return err
+			}
+		}
+		if err := w.WriteFieldEnd(); err != nil {
+			return err
+		}
+	}
+	if err := w.WriteFieldBegin(thriftwire.FieldHeader{Name: "Ratio", Type: thriftwire.Double, ID: 4}); err != nil {
+		return err
+	}
+	if err := w.WriteDouble(x.Ratio); err != nil {
+		return err
+	}
+	if err := w.WriteFieldEnd(); err != nil {
+		return err
+	}
+	return w.WriteStructEnd()
+}
+
+// UnmarshalThrift implements [thrift.Unmarshaler].
+func (x *Group) UnmarshalThrift(r thriftwire.Reader) error {
+	if _, err := r.ReadStructBegin(); err != nil {
+		return err
+	}
+	for {
+		h, err := r.ReadFieldBegin()
+		if err != nil {
+			return err
+		}
+		if h.Type == thriftwire.Stop {
+			break
+		}
+		switch h.ID {
+		case 1:
+			if h.Type != thriftwire.String {
+				return fmt.Errorf("unexpected %v for field %d", h.Type, h.ID)
+			}
+			{
+				v47, err := r.ReadString()
+				if err != nil {
+					return err
+				}
+				x.Name = v47
+			}
+		case 2:
+			if h.Type != thriftwire.List {
+				return fmt.Errorf("unexpected %v for field %d", h.Type, h.ID)
+			}
+			{
+				h48, err := r.ReadListBegin()
+				if err != nil {
+					return err
+				}
+				x.Members = x.Members[:0]
+				if h48.Size > 0 {
+					if h48.Element != thriftwire.Struct {
+						return fmt.Errorf("unexpected list<%v>", h48.Element)
+					}
+					for i49 := 0; i49 < h48.Size; i49++ {
+						var e50 User
+						x.Members = append(x.Members, e50)
+						if err := x.Members[i49].UnmarshalThrift(r); err != nil {
+							return err
+						}
+					}
+				}
+				if err := r.ReadListEnd(); err != nil {
+					return err
+				}
+			}
+		case 3:
+			if h.Type != thriftwire.Struct {
+				return fmt.Errorf("unexpected %v for field %d", h.Type, h.ID)
+			}
+			if x.Owner == nil {
+				x.Owner = new(User)
+			}
+			if err := x.Owner.UnmarshalThrift(r); err != nil {
+				return err
+			}
+		case 4:
+			if h.Type != thriftwire.Double {
+				return fmt.Errorf("unexpected %v for field %d", h.Type, h.ID)
+			}
+			{
+				v51, err := r.ReadDouble()
+				if err != nil {
+					return err
+				}
+				x.Ratio = v51
+			}
+		default:
+			if err := thriftwire.Skip(r, h.Type); err != nil {
+				return err
+			}
+		}
+		if err := r.ReadFieldEnd(); err != nil {
+			return err
+		}
+	}
+	if err := r.ReadStructEnd(); err != nil {
+		return err
+	}
+	return x.Validate()
+}
+
+// MarshalThrift implements [thrift.Marshaler].
+func (x *Vector) MarshalThrift(w thriftwire.Writer) error {
+	if err := w.WriteStructBegin(thriftwire.StructHeader{Name: "Vector"}); err != nil {
+		return err
+	}
+	if math.Float64bits(float64(x.X)) != 0 {
+		if err := w.WriteFieldBegin(thriftwire.FieldHeader{Name: "X", Type: thriftwire.Double, ID: 1}); err != nil {
+			return err
+		}
+		if err := w.WriteDouble(x.X); err != nil {
+			return err
+		}
+		if err := w.WriteFieldEnd(); err != nil {
+			return err
+		}
+	}
+	if math.Float64bits(float64(x.Y)) != 0 {
+		if err := w.WriteFieldBegin(thriftwire.FieldHeader{Name: "Y", Type: thriftwire.Double, ID: 2}); err != nil {
+			return err
+		}
+		if err := w.WriteDouble(x.Y); err != nil {
+			return err
+		}
+		if err := w.WriteFieldEnd(); err != nil {
+			return err
+		}
+	}
+	return w.WriteStructEnd()
+}
+
+// UnmarshalThrift implements [thrift.Unmarshaler].
+func (x *Vector) UnmarshalThrift(r thriftwire.Reader) error {
+	if _, err := r.ReadStructBegin(); err != nil {
+		return err
+	}
+	for {
+		h, err := r.ReadFieldBegin()
+		if err != nil {
+			return err
+		}
+		if h.Type == thriftwire.Stop {
+			break
+		}
+		switch h.ID {
+		case 1:
+			if h.Type != thriftwire.Double {
+				return fmt.Errorf("unexpected %v for field %d", h.Type, h.ID)
+			}
+			{
+				v52, err := r.ReadDouble()
+				if err != nil {
+					return err
+				}
+				x.X = v52
+			}
+		case 2:
+			if h.Type != thriftwire.Double {
+				return fmt.Errorf("unexpected %v for field %d", h.Type, h.ID)
+			}
+			{
+				v53, err := r.ReadDouble()
+				if err != nil {
+					return err
+				}
+				x.Y = v53
+			}
+		default:
+			if err := thriftwire.Skip(r, h.Type); err != nil {
+				return err
+			}
+		}
+		if err := r.ReadFieldEnd(); err != nil {
+			return err
+		}
+	}
+	if err := r.ReadStructEnd(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// MarshalThrift implements [thrift.Marshaler].
+func (x *Box) MarshalThrift(w thriftwire.Writer) error {
+	if err := w.WriteStructBegin(thriftwire.StructHeader{Name: "Box"}); err != nil {
+		return err
+	}
+	if math.Float64bits(float64(x.Min.X)) != 0 || math.Float64bits(float64(x.Min.Y)) != 0 {
+		if err := w.WriteFieldBegin(thriftwire.FieldHeader{Name: "Min", Type: thriftwire.Struct, ID: 1}); err != nil {
+			return err
+		}
+		if err := x.Min.MarshalThrift(w); err != nil {
+			return err
+		}
+		if err := w.WriteFieldEnd(); err != nil {
+			return err
+		}
+	}
+	if math.Float64bits(float64(x.Max.X)) != 0 || math.Float64bits(float64(x.Max.Y)) != 0 {
+		if err := w.WriteFieldBegin(thriftwire.FieldHeader{Name: "Max", Type: thriftwire.Struct, ID: 2}); err != nil {
+			return err
+		}
+		if err := x.Max.MarshalThrift(w); err != nil {
+			return err
+		}
+		if err := w.WriteFieldEnd(); err != nil {
+			return err
+		}
+	}
+	if x.Label.Text != "" || math.Float64bits(float64(x.Label.Scale)) != 0 {
+		if err := w.WriteFieldBegin(thriftwire.FieldHeader{Name: "Label", Type: thriftwire.Struct, ID: 3}); err != nil {
+			return err
+		}
+		if err := thrift.Marshal(w, &x.Label); err != nil {
+			return err
+		}
+		if err := w.WriteFieldEnd(); err != nil {
+			return err
+		}
+	}
+	return w.WriteStructEnd()
+}
+
+// UnmarshalThrift implements [thrift.Unmarshaler].
+func (x *Box) UnmarshalThrift(r thriftwire.Reader) error {
+	if _, err := r.ReadStructBegin(); err != nil {
+		return err
+	}
+	for {
+		h, err := r.ReadFieldBegin()
+		if err != nil {
+			return err
+		}
+		if h.Type == thriftwire.Stop {
+			break
+		}
+		switch h.ID {
+		case 1:
+			if h.Type != thriftwire.Struct {
+				return fmt.Errorf("unexpected %v for field %d", h.Type, h.ID)
+			}
+			if err := x.Min.UnmarshalThrift(r); err != nil {
+				return err
+			}
+		case 2:
+			if h.Type != thriftwire.Struct {
+				return fmt.Errorf("unexpected %v for field %d", h.Type, h.ID)
+			}
+			if err := x.Max.UnmarshalThrift(r); err != nil {
+				return err
+			}
+		case 3:
+			if h.Type != thriftwire.Struct {
+				return fmt.Errorf("unexpected %v for field %d", h.Type, h.ID)
+			}
+			if err := thrift.Unmarshal(r, &x.Label); err != nil {
+				return err
+			}
+		default:
+			if err := thriftwire.Skip(r, h.Type); err != nil {
+				return err
+			}
+		}
+		if err := r.ReadFieldEnd(); err != nil {
+			return err
+		}
+	}
+	if err := r.ReadStructEnd(); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+// Thriftgen generates reflection-free Thrift marshaling methods
+// for Go structs with `thrift` tags.
+//
+// Usage:
+//
+//	thriftgen [flags] [directory]
+//
+// For each Go struct type with `thrift` tags in the package in the given
+// directory (default "."), thriftgen generates MarshalThrift and
+// UnmarshalThrift methods that implement [thrift.Marshaler] and
+// [thrift.Unmarshaler]. The generated methods produce exactly the same
+// sequence of [thriftwire.Writer] calls as the reflective implementation,
+// and [thrift.Marshal] and [thrift.Unmarshal] use them automatically.
+//
+// Types whose fields cannot be handled by generated code are skipped with
+// a warning, and continue to use the reflective implementation.
+//
+// It is typically invoked by a go:generate directive:
+//
+//	//go:generate go run github.com/itstarsun/go-thrift/cmd/thriftgen
+//
+// The flags are:
+//
+//	-type
+//		comma-separated list of type names; default all types with `thrift` tags
+//	-output
+//		output file name; default "zthrift.go"
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	typeNames = flag.String("type", "", "comma-separated list of type names; default all types with `thrift` tags")
+	output    = flag.String("output", "zthrift.go", "output file name")
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: thriftgen [flags] [directory]\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("thriftgen: ")
+	flag.Usage = usage
+	flag.Parse()
+
+	dir := "."
+	switch flag.NArg() {
+	case 0:
+	case 1:
+		dir = flag.Arg(0)
+	default:
+		usage()
+		os.Exit(2)
+	}
+
+	var types []string
+	if *typeNames != "" {
+		types = strings.Split(*typeNames, ",")
+	}
+
+	args := []string{"thriftgen"}
+	args = append(args, os.Args[1:]...)
+	src, err := generate(dir, *output, types, strings.Join(args, " "), func(msg string) {
+		log.Print(msg)
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, *output), src, 0o666); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -39,6 +39,10 @@ func (protocol) String() string {
 	return "thriftbinary.Protocol"
 }
 
+func init() {
+	thriftwire.RegisterProtocol("\x80\x01", Protocol)
+}
+
 type protocolNonStrict struct{}
 
 func (protocolNonStrict) NewReader(r io.Reader) thriftwire.Reader {
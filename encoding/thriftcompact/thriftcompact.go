@@ -35,6 +35,10 @@ func (protocol) String() string {
 	return "thriftcompact.Protocol"
 }
 
+func init() {
+	thriftwire.RegisterProtocol(string([]byte{protocolID}), Protocol)
+}
+
 type reader struct {
 	*bufio.Reader
 	lastFieldIDs []int16
@@ -0,0 +1,138 @@
+// Package thriftframed implements the Thrift framed transport, which
+// prefixes each message with its size as a 4-byte big-endian integer.
+// See https://github.com/apache/thrift/blob/master/doc/specs/thrift-rpc.md#framed-vs-unframed-transport
+// for details.
+package thriftframed
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/itstarsun/go-thrift/encoding/thriftbinary"
+	"github.com/itstarsun/go-thrift/encoding/thriftcompact"
+	"github.com/itstarsun/go-thrift/encoding/thriftwire"
+)
+
+// DefaultMaxFrameSize is the default maximum size of a frame,
+// which is the same as the Apache Thrift implementations.
+const DefaultMaxFrameSize = 16384000
+
+// Protocol is a [thriftwire.Protocol] that frames the messages
+// encoded by another protocol.
+//
+// The [thriftwire.Writer] buffers a message until it is flushed, and then
+// writes the message as a frame. The [thriftwire.Reader] reads a frame
+// when reading the beginning of a message, discarding the unread part
+// of the previous frame, if any.
+type Protocol struct {
+	// Protocol encodes the messages in the frames.
+	Protocol thriftwire.Protocol
+
+	// MaxFrameSize is the maximum size of a frame to read.
+	// If zero, DefaultMaxFrameSize is used.
+	MaxFrameSize int
+}
+
+var _ thriftwire.Protocol = (*Protocol)(nil)
+
+func init() {
+	thriftwire.RegisterProtocol("????\x80\x01", &Protocol{Protocol: thriftbinary.Protocol})
+	thriftwire.RegisterProtocol("????\x82", &Protocol{Protocol: thriftcompact.Protocol})
+}
+
+func (p *Protocol) NewReader(r io.Reader) thriftwire.Reader {
+	x := &reader{
+		r:            r,
+		maxFrameSize: p.MaxFrameSize,
+	}
+	if x.maxFrameSize == 0 {
+		x.maxFrameSize = DefaultMaxFrameSize
+	}
+	x.frame.R = r
+	x.Reader = p.Protocol.NewReader(&x.frame)
+	return x
+}
+
+func (p *Protocol) NewWriter(w io.Writer) thriftwire.Writer {
+	x := &writer{w: w}
+	x.Writer = p.Protocol.NewWriter(&x.buf)
+	return x
+}
+
+func (p *Protocol) String() string {
+	return fmt.Sprintf("thriftframed.Protocol(%v)", p.Protocol)
+}
+
+type reader struct {
+	thriftwire.Reader
+	r            io.Reader
+	frame        io.LimitedReader // the unread part of the current frame
+	maxFrameSize int
+	buf          [4]byte
+}
+
+func (x *reader) ReadMessageBegin() (thriftwire.MessageHeader, error) {
+	if err := x.readFrameHeader(); err != nil {
+		return thriftwire.MessageHeader{}, err
+	}
+	return x.Reader.ReadMessageBegin()
+}
+
+func (x *reader) readFrameHeader() error {
+	if x.frame.N > 0 {
+		if _, err := io.Copy(io.Discard, &x.frame); err != nil {
+			return err
+		}
+	}
+	if _, err := io.ReadFull(x.r, x.buf[:]); err != nil {
+		return err
+	}
+	size := int32(binary.BigEndian.Uint32(x.buf[:]))
+	if size < 0 || int64(size) > int64(x.maxFrameSize) {
+		return fmt.Errorf("thriftframed: invalid frame size %d", size)
+	}
+	x.frame.N = int64(size)
+	// Discard the data of the previous frame buffered by the Reader.
+	x.Reader.Reset(&x.frame)
+	return nil
+}
+
+func (x *reader) Reset(r io.Reader) {
+	x.r = r
+	x.frame = io.LimitedReader{R: r}
+	x.Reader.Reset(&x.frame)
+}
+
+type writer struct {
+	thriftwire.Writer
+	w   io.Writer
+	buf bytes.Buffer // the message being written
+}
+
+func (x *writer) Flush() error {
+	if err := x.Writer.Flush(); err != nil {
+		return err
+	}
+	if x.buf.Len() > 0 {
+		if x.buf.Len() > 1<<31-1 {
+			return fmt.Errorf("thriftframed: frame size %d is too large", x.buf.Len())
+		}
+		var size [4]byte
+		binary.BigEndian.PutUint32(size[:], uint32(x.buf.Len()))
+		if _, err := x.w.Write(size[:]); err != nil {
+			return err
+		}
+		if _, err := x.buf.WriteTo(x.w); err != nil {
+			return err
+		}
+	}
+	return thriftwire.Flush(x.w)
+}
+
+func (x *writer) Reset(w io.Writer) {
+	x.w = w
+	x.buf.Reset()
+	x.Writer.Reset(&x.buf)
+}
@@ -0,0 +1,84 @@
+package thriftframed
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/itstarsun/go-thrift/encoding/thriftbinary"
+	"github.com/itstarsun/go-thrift/encoding/thriftcompact"
+	"github.com/itstarsun/go-thrift/encoding/thriftwire"
+	"github.com/itstarsun/go-thrift/testing/thrifttest"
+	"github.com/itstarsun/go-thrift/thrift"
+)
+
+var protocolOptions = thrifttest.ProtocolOptions{
+	UUID: true,
+}
+
+func TestProtocol(t *testing.T) {
+	for _, p := range []thriftwire.Protocol{
+		thriftbinary.Protocol,
+		thriftbinary.ProtocolNonStrict,
+		thriftcompact.Protocol,
+	} {
+		p := &Protocol{Protocol: p}
+		t.Run(p.String(), func(t *testing.T) {
+			thrifttest.TestProtocol(t, p, protocolOptions)
+		})
+	}
+}
+
+type message struct {
+	Text string `thrift:"1"`
+}
+
+func writeMessage(t *testing.T, w thriftwire.Writer, text string) {
+	t.Helper()
+	if err := w.WriteMessageBegin(thriftwire.MessageHeader{Name: "m", Type: thriftwire.Call, ID: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := thrift.Marshal(w, &message{Text: text}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteMessageEnd(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFrame(t *testing.T) {
+	p := &Protocol{Protocol: thriftbinary.Protocol, MaxFrameSize: 64}
+
+	var b, unframed bytes.Buffer
+	writeMessage(t, thriftbinary.Protocol.NewWriter(&unframed), "hello")
+	writeMessage(t, p.NewWriter(&b), "hello")
+	if got, want := b.Bytes(), append([]byte{0, 0, 0, byte(unframed.Len())}, unframed.Bytes()...); !bytes.Equal(got, want) {
+		t.Fatalf("\ngot  %q\nwant %q", got, want)
+	}
+
+	// The reader discards the unread part of a frame.
+	w := p.NewWriter(&b)
+	writeMessage(t, w, "world")
+	r := p.NewReader(&b)
+	if _, err := r.ReadMessageBegin(); err != nil {
+		t.Fatal(err)
+	}
+	var m message
+	if _, err := r.ReadMessageBegin(); err != nil {
+		t.Fatal(err)
+	}
+	if err := thrift.Unmarshal(r, &m); err != nil {
+		t.Fatal(err)
+	}
+	if m.Text != "world" {
+		t.Fatalf("got %q, want %q", m.Text, "world")
+	}
+
+	writeMessage(t, w, strings.Repeat("x", 64))
+	if _, err := r.ReadMessageBegin(); err == nil || !strings.Contains(err.Error(), "invalid frame size") {
+		t.Fatalf("got %v, want an invalid frame size error", err)
+	}
+}
@@ -0,0 +1,562 @@
+// Package thriftheader implements the THeader transport of Apache Thrift
+// and fbthrift, which frames each message with a header carrying the protocol
+// of the payload, a sequence ID, transforms of the payload, and key/value
+// info headers.
+// See https://github.com/apache/thrift/blob/master/doc/specs/HeaderFormatSpec.md
+// for details.
+//
+// Like the Apache and fbthrift servers, the [thriftwire.Reader] detects the
+// format of each message, and also accepts the unframed and framed binary
+// and compact protocols. The [thriftwire.Writer] created with a Reader by
+// [Protocol.NewReadWriter] replies in the format of the last message read.
+//
+// The Reader and the Writer implement the Header and SetHeader methods
+// to read and write the info headers, which are exposed through the context
+// of calls by the thrift package.
+package thriftheader
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/itstarsun/go-thrift/encoding/thriftbinary"
+	"github.com/itstarsun/go-thrift/encoding/thriftcompact"
+	"github.com/itstarsun/go-thrift/encoding/thriftframed"
+	"github.com/itstarsun/go-thrift/encoding/thriftjson"
+	"github.com/itstarsun/go-thrift/encoding/thriftwire"
+)
+
+const headerMagic = 0x0fff
+
+// A ProtocolID identifies the protocol of the payload of a message.
+type ProtocolID int32
+
+// Protocols of payloads.
+const (
+	BinaryProtocol  ProtocolID = 0
+	JSONProtocol    ProtocolID = 1
+	CompactProtocol ProtocolID = 2
+)
+
+func (id ProtocolID) protocol() (thriftwire.Protocol, error) {
+	switch id {
+	case BinaryProtocol:
+		return thriftbinary.Protocol, nil
+	case JSONProtocol:
+		return thriftjson.Protocol, nil
+	case CompactProtocol:
+		return thriftcompact.Protocol, nil
+	}
+	return nil, fmt.Errorf("thriftheader: unsupported protocol ID %d", id)
+}
+
+// A TransformID identifies a transform of the payload of a message.
+type TransformID int32
+
+// Transforms of payloads. Only ZlibTransform is supported.
+const (
+	ZlibTransform TransformID = 1
+)
+
+// Types of info headers.
+const (
+	infoKeyValue           = 1
+	infoPersistentKeyValue = 2
+)
+
+// A format is the framing of a message.
+type format int
+
+const (
+	formatHeader format = iota
+	formatFramed
+	formatUnframed
+)
+
+// Protocol is a [thriftwire.Protocol] that implements the THeader transport.
+//
+// The [thriftwire.Writer] buffers a message until it is flushed, and then
+// writes the message as a frame. The [thriftwire.Reader] reads a frame
+// when reading the beginning of a message, discarding the unread part
+// of the previous frame, if any.
+type Protocol struct {
+	// ProtocolID is the protocol of the payloads of the messages written.
+	// The zero value is BinaryProtocol.
+	ProtocolID ProtocolID
+
+	// Transforms are applied to the payloads of the messages written,
+	// in order.
+	Transforms []TransformID
+
+	// MaxFrameSize is the maximum size of a frame to read, which also limits
+	// the size of a payload after the transforms are undone.
+	// If zero, thriftframed.DefaultMaxFrameSize is used.
+	MaxFrameSize int
+}
+
+var _ thriftwire.DuplexProtocol = (*Protocol)(nil)
+
+func init() {
+	thriftwire.RegisterProtocol("????\x0f\xff", &Protocol{})
+}
+
+func (p *Protocol) NewReader(r io.Reader) thriftwire.Reader {
+	x := &reader{
+		b:            bufio.NewReader(r),
+		maxFrameSize: p.MaxFrameSize,
+	}
+	if x.maxFrameSize == 0 {
+		x.maxFrameSize = thriftframed.DefaultMaxFrameSize
+	}
+	x.reset()
+	return x
+}
+
+func (p *Protocol) NewWriter(w io.Writer) thriftwire.Writer {
+	x := &writer{p: p, w: w}
+	x.Reset(w)
+	return x
+}
+
+// NewReadWriter returns a new Reader and Writer for rw, where the Writer
+// writes the messages in the format, the protocol and with the transforms
+// of the last message read by the Reader, if any.
+func (p *Protocol) NewReadWriter(rw io.ReadWriter) (thriftwire.Reader, thriftwire.Writer) {
+	r := p.NewReader(rw).(*reader)
+	w := p.NewWriter(rw).(*writer)
+	w.r = r
+	return r, w
+}
+
+func (p *Protocol) String() string {
+	return "thriftheader.Protocol"
+}
+
+// settings are how a message is framed and encoded.
+type settings struct {
+	format     format
+	protocolID ProtocolID
+	transforms []TransformID
+}
+
+type reader struct {
+	thriftwire.Reader // reader of the payload of the current message
+
+	b            *bufio.Reader
+	maxFrameSize int
+	frame        bytes.Reader // the unread part of the payload of the current message
+	buf          []byte
+	readers      [3]thriftwire.Reader // readers of the payloads by protocol ID
+
+	read   bool // whether a message has been read
+	last   settings
+	header map[string]string
+}
+
+func (x *reader) reset() {
+	x.frame.Reset(nil)
+	for _, r := range x.readers {
+		if r != nil {
+			r.Reset(&x.frame)
+		}
+	}
+	x.Reader = x.payloadReader(BinaryProtocol)
+	x.read = false
+	x.last = settings{}
+	x.header = nil
+}
+
+// payloadReader returns the reader of the payloads of the protocol id,
+// which must be supported.
+func (x *reader) payloadReader(id ProtocolID) thriftwire.Reader {
+	if x.readers[id] == nil {
+		p, _ := id.protocol()
+		x.readers[id] = p.NewReader(&x.frame)
+	}
+	return x.readers[id]
+}
+
+// Header returns the info headers of the message last read,
+// or nil if the message has no header.
+func (x *reader) Header() map[string]string {
+	return x.header
+}
+
+func (x *reader) ReadMessageBegin() (thriftwire.MessageHeader, error) {
+	if err := x.readFrame(); err != nil {
+		return thriftwire.MessageHeader{}, err
+	}
+	return x.Reader.ReadMessageBegin()
+}
+
+// readFrame reads the frame of the next message, or detects an unframed
+// message, and sets up the reader of its payload.
+func (x *reader) readFrame() error {
+	if x.read && x.last.format == formatUnframed {
+		// The payload reader may have buffered the following messages.
+		return nil
+	}
+	peek, err := x.b.Peek(4)
+	if err != nil {
+		if len(peek) > 0 && err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	x.header = nil
+	if id, ok := detect(peek); ok {
+		p, _ := id.protocol()
+		x.Reader = p.NewReader(x.b)
+		x.read = true
+		x.last = settings{format: formatUnframed, protocolID: id}
+		return nil
+	}
+
+	size := int32(binary.BigEndian.Uint32(peek))
+	if size < 0 || int64(size) > int64(x.maxFrameSize) {
+		return fmt.Errorf("thriftheader: invalid frame size %d", size)
+	}
+	if _, err := x.b.Discard(4); err != nil {
+		return err
+	}
+	if cap(x.buf) < int(size) {
+		x.buf = make([]byte, size)
+	}
+	frame := x.buf[:size]
+	if _, err := io.ReadFull(x.b, frame); err != nil {
+		return unexpectedEOF(err)
+	}
+
+	s := settings{format: formatFramed}
+	payload := frame
+	if len(frame) >= 2 && binary.BigEndian.Uint16(frame) == headerMagic {
+		s.format = formatHeader
+		if payload, err = x.readHeader(&s, frame); err != nil {
+			return err
+		}
+	} else if id, ok := detect(frame); ok {
+		s.protocolID = id
+	} else {
+		return errors.New("thriftheader: unknown frame format")
+	}
+	if _, err := s.protocolID.protocol(); err != nil {
+		return err
+	}
+	r := x.payloadReader(s.protocolID)
+	x.frame.Reset(payload)
+	// Discard the data of the previous frame buffered by the Reader.
+	r.Reset(&x.frame)
+	x.Reader = r
+	x.read = true
+	x.last = s
+	return nil
+}
+
+// detect detects the protocol of an unframed message beginning with b.
+func detect(b []byte) (ProtocolID, bool) {
+	switch {
+	case len(b) >= 2 && b[0] == 0x80 && b[1] == 0x01:
+		return BinaryProtocol, true
+	case len(b) >= 1 && b[0] == 0x82:
+		return CompactProtocol, true
+	}
+	return 0, false
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+var errInvalidHeader = errors.New("thriftheader: invalid header")
+
+// readHeader reads the header in the frame into s and x.header,
+// and returns the payload with the transforms undone.
+func (x *reader) readHeader(s *settings, frame []byte) ([]byte, error) {
+	// magic(2) flags(2) sequence ID(4) header size(2)
+	if len(frame) < 10 {
+		return nil, errInvalidHeader
+	}
+	n := 4 * int(binary.BigEndian.Uint16(frame[8:]))
+	if len(frame)-10 < n {
+		return nil, errInvalidHeader
+	}
+	h, payload := frame[10:10+n], frame[10+n:]
+
+	id, err := uvarint(&h)
+	if err != nil {
+		return nil, err
+	}
+	s.protocolID = ProtocolID(id)
+	count, err := uvarint(&h)
+	if err != nil {
+		return nil, err
+	}
+	for i := uint64(0); i < count; i++ {
+		id, err := uvarint(&h)
+		if err != nil {
+			return nil, err
+		}
+		s.transforms = append(s.transforms, TransformID(id))
+	}
+	for len(h) > 0 {
+		typ, err := uvarint(&h)
+		if err != nil {
+			return nil, err
+		}
+		if typ != infoKeyValue && typ != infoPersistentKeyValue {
+			break // padding or unknown info headers
+		}
+		count, err := uvarint(&h)
+		if err != nil {
+			return nil, err
+		}
+		for i := uint64(0); i < count; i++ {
+			k, err := bytesPrefixed(&h)
+			if err != nil {
+				return nil, err
+			}
+			v, err := bytesPrefixed(&h)
+			if err != nil {
+				return nil, err
+			}
+			if x.header == nil {
+				x.header = make(map[string]string)
+			}
+			x.header[string(k)] = string(v)
+		}
+	}
+
+	for i := len(s.transforms) - 1; i >= 0; i-- {
+		switch s.transforms[i] {
+		case ZlibTransform:
+			zr, err := zlib.NewReader(bytes.NewReader(payload))
+			if err != nil {
+				return nil, fmt.Errorf("thriftheader: %w", err)
+			}
+			payload, err = io.ReadAll(io.LimitReader(zr, int64(x.maxFrameSize)+1))
+			if err != nil {
+				return nil, fmt.Errorf("thriftheader: %w", err)
+			}
+			if len(payload) > x.maxFrameSize {
+				return nil, fmt.Errorf("thriftheader: payload is larger than %d", x.maxFrameSize)
+			}
+		default:
+			return nil, fmt.Errorf("thriftheader: unsupported transform ID %d", s.transforms[i])
+		}
+	}
+	return payload, nil
+}
+
+func uvarint(b *[]byte) (uint64, error) {
+	v, n := binary.Uvarint(*b)
+	if n <= 0 {
+		return 0, errInvalidHeader
+	}
+	*b = (*b)[n:]
+	return v, nil
+}
+
+func bytesPrefixed(b *[]byte) ([]byte, error) {
+	n, err := uvarint(b)
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(*b)) < n {
+		return nil, errInvalidHeader
+	}
+	v := (*b)[:n]
+	*b = (*b)[n:]
+	return v, nil
+}
+
+func (x *reader) Reset(r io.Reader) {
+	x.b.Reset(r)
+	x.reset()
+}
+
+type writer struct {
+	thriftwire.Writer // writer of the payload of the current message
+
+	p       *Protocol
+	w       io.Writer
+	r       *reader // the reader of the same connection, if any
+	buf     bytes.Buffer
+	writers [3]thriftwire.Writer // writers of the payloads by protocol ID
+
+	current settings
+	pending bool // whether a message is being written
+	seqID   int32
+	header  map[string]string
+}
+
+// SetHeader sets the info headers of the next message written.
+func (x *writer) SetHeader(h map[string]string) {
+	x.header = h
+}
+
+func (x *writer) settings() settings {
+	if x.r != nil && x.r.read {
+		return x.r.last
+	}
+	return settings{
+		format:     formatHeader,
+		protocolID: x.p.ProtocolID,
+		transforms: x.p.Transforms,
+	}
+}
+
+func (x *writer) WriteMessageBegin(h thriftwire.MessageHeader) error {
+	if !x.pending {
+		// Messages written before a flush share the frame of the first one.
+		if err := x.use(x.settings()); err != nil {
+			return err
+		}
+		x.pending = true
+	}
+	x.seqID = h.ID
+	return x.Writer.WriteMessageBegin(h)
+}
+
+// use makes the writer write the next message with the settings s.
+func (x *writer) use(s settings) error {
+	p, err := s.protocolID.protocol()
+	if err != nil {
+		return err
+	}
+	if x.writers[s.protocolID] == nil {
+		x.writers[s.protocolID] = p.NewWriter(&x.buf)
+	}
+	x.Writer = x.writers[s.protocolID]
+	x.current = s
+	return nil
+}
+
+func (x *writer) Flush() error {
+	if err := x.Writer.Flush(); err != nil {
+		return err
+	}
+	if x.buf.Len() > 0 {
+		if err := x.writeFrame(); err != nil {
+			return err
+		}
+	}
+	x.pending = false
+	x.header = nil
+	return thriftwire.Flush(x.w)
+}
+
+func (x *writer) writeFrame() error {
+	defer x.buf.Reset()
+	s := x.current
+	if s.format == formatUnframed {
+		_, err := x.buf.WriteTo(x.w)
+		return err
+	}
+
+	var head []byte
+	payload := x.buf.Bytes()
+	if s.format == formatHeader {
+		var err error
+		if payload, err = transform(payload, s.transforms); err != nil {
+			return err
+		}
+		if head, err = x.appendHeader(make([]byte, 4, 64), s); err != nil {
+			return err
+		}
+	} else {
+		head = make([]byte, 4)
+	}
+	size := len(head) - 4 + len(payload)
+	if size > 1<<31-1 {
+		return fmt.Errorf("thriftheader: frame size %d is too large", size)
+	}
+	binary.BigEndian.PutUint32(head, uint32(size))
+	if _, err := x.w.Write(head); err != nil {
+		return err
+	}
+	_, err := x.w.Write(payload)
+	return err
+}
+
+// appendHeader appends the header of a message with the settings s to b.
+func (x *writer) appendHeader(b []byte, s settings) ([]byte, error) {
+	b = binary.BigEndian.AppendUint16(b, headerMagic)
+	b = binary.BigEndian.AppendUint16(b, 0) // flags
+	b = binary.BigEndian.AppendUint32(b, uint32(x.seqID))
+	b = append(b, 0, 0) // header size
+	start := len(b)
+	b = binary.AppendUvarint(b, uint64(s.protocolID))
+	b = binary.AppendUvarint(b, uint64(len(s.transforms)))
+	for _, id := range s.transforms {
+		b = binary.AppendUvarint(b, uint64(id))
+	}
+	if len(x.header) > 0 {
+		keys := make([]string, 0, len(x.header))
+		for k := range x.header {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		b = binary.AppendUvarint(b, infoKeyValue)
+		b = binary.AppendUvarint(b, uint64(len(keys)))
+		for _, k := range keys {
+			b = appendPrefixed(b, k)
+			b = appendPrefixed(b, x.header[k])
+		}
+	}
+	for (len(b)-start)%4 != 0 {
+		b = append(b, 0)
+	}
+	n := (len(b) - start) / 4
+	if n > 0xffff {
+		return nil, fmt.Errorf("thriftheader: header size %d is too large", len(b)-start)
+	}
+	binary.BigEndian.PutUint16(b[start-2:], uint16(n))
+	return b, nil
+}
+
+func appendPrefixed(b []byte, s string) []byte {
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+func transform(payload []byte, transforms []TransformID) ([]byte, error) {
+	for _, id := range transforms {
+		switch id {
+		case ZlibTransform:
+			var b bytes.Buffer
+			zw := zlib.NewWriter(&b)
+			if _, err := zw.Write(payload); err != nil {
+				return nil, err
+			}
+			if err := zw.Close(); err != nil {
+				return nil, err
+			}
+			payload = b.Bytes()
+		default:
+			return nil, fmt.Errorf("thriftheader: unsupported transform ID %d", id)
+		}
+	}
+	return payload, nil
+}
+
+func (x *writer) Reset(w io.Writer) {
+	x.w = w
+	x.buf.Reset()
+	for _, w := range x.writers {
+		if w != nil {
+			w.Reset(&x.buf)
+		}
+	}
+	x.pending = false
+	x.header = nil
+	x.use(x.settings())
+}
@@ -0,0 +1,161 @@
+package thriftheader
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/itstarsun/go-thrift/encoding/thriftbinary"
+	"github.com/itstarsun/go-thrift/encoding/thriftcompact"
+	"github.com/itstarsun/go-thrift/encoding/thriftframed"
+	"github.com/itstarsun/go-thrift/encoding/thriftwire"
+	"github.com/itstarsun/go-thrift/testing/thrifttest"
+	"github.com/itstarsun/go-thrift/thrift"
+)
+
+var protocolOptions = thrifttest.ProtocolOptions{
+	UUID: true,
+}
+
+func TestProtocol(t *testing.T) {
+	for _, p := range []*Protocol{
+		{},
+		{ProtocolID: CompactProtocol},
+		{ProtocolID: JSONProtocol},
+		{ProtocolID: CompactProtocol, Transforms: []TransformID{ZlibTransform}},
+	} {
+		t.Run("", func(t *testing.T) {
+			thrifttest.TestProtocol(t, p, protocolOptions)
+		})
+	}
+}
+
+type message struct {
+	Text string `thrift:"1"`
+}
+
+func writeMessage(t *testing.T, w thriftwire.Writer, mt thriftwire.MessageType, text string) {
+	t.Helper()
+	if err := w.WriteMessageBegin(thriftwire.MessageHeader{Name: "m", Type: mt, ID: 7}); err != nil {
+		t.Fatal(err)
+	}
+	if err := thrift.Marshal(w, &message{Text: text}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteMessageEnd(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readMessage(t *testing.T, r thriftwire.Reader) string {
+	t.Helper()
+	if _, err := r.ReadMessageBegin(); err != nil {
+		t.Fatal(err)
+	}
+	var m message
+	if err := thrift.Unmarshal(r, &m); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.ReadMessageEnd(); err != nil {
+		t.Fatal(err)
+	}
+	return m.Text
+}
+
+func TestReply(t *testing.T) {
+	for _, p := range []thriftwire.Protocol{
+		thriftbinary.Protocol,
+		thriftcompact.Protocol,
+		&thriftframed.Protocol{Protocol: thriftbinary.Protocol},
+		&thriftframed.Protocol{Protocol: thriftcompact.Protocol},
+		&Protocol{},
+		&Protocol{ProtocolID: JSONProtocol, Transforms: []TransformID{ZlibTransform}},
+	} {
+		t.Run(p.(interface{ String() string }).String(), func(t *testing.T) {
+			var in, out, want bytes.Buffer
+			for _, text := range []string{"hello", "world"} {
+				writeMessage(t, p.NewWriter(&in), thriftwire.Call, text)
+			}
+			writeMessage(t, p.NewWriter(&want), thriftwire.Reply, "hello")
+			writeMessage(t, p.NewWriter(&want), thriftwire.Reply, "world")
+
+			// A server reading with a default Protocol replies in the same format.
+			r, w := (&Protocol{}).NewReadWriter(struct {
+				io.Reader
+				io.Writer
+			}{&in, &out})
+			for _, text := range []string{"hello", "world"} {
+				if got := readMessage(t, r); got != text {
+					t.Fatalf("got %q, want %q", got, text)
+				}
+				writeMessage(t, w, thriftwire.Reply, text)
+			}
+			if _, err := r.ReadMessageBegin(); err != io.EOF {
+				t.Fatalf("got %v, want EOF", err)
+			}
+			if !bytes.Equal(out.Bytes(), want.Bytes()) {
+				t.Fatalf("\ngot  %q\nwant %q", out.Bytes(), want.Bytes())
+			}
+		})
+	}
+}
+
+func TestHeader(t *testing.T) {
+	var b, payload bytes.Buffer
+	writeMessage(t, thriftbinary.Protocol.NewWriter(&payload), thriftwire.Call, "hello")
+
+	w := (&Protocol{}).NewWriter(&b)
+	w.(thrift.HeaderWriter).SetHeader(map[string]string{"k": "v"})
+	writeMessage(t, w, thriftwire.Call, "hello")
+
+	head := []byte{
+		0x0f, 0xff, // magic
+		0, 0, // flags
+		0, 0, 0, 7, // sequence ID
+		0, 2, // header size / 4
+		0,    // protocol ID
+		0,    // number of transforms
+		1, 1, // one key/value info header
+		1, 'k', 1, 'v',
+	}
+	want := append([]byte{0, 0, 0, byte(len(head) + payload.Len())}, head...)
+	want = append(want, payload.Bytes()...)
+	if !bytes.Equal(b.Bytes(), want) {
+		t.Fatalf("\ngot  %q\nwant %q", b.Bytes(), want)
+	}
+
+	r := (&Protocol{}).NewReader(&b)
+	if got := readMessage(t, r); got != "hello" {
+		t.Fatalf("got %q, want %q", got, "hello")
+	}
+	if got, want := r.(thrift.HeaderReader).Header(), map[string]string{"k": "v"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got header %v, want %v", got, want)
+	}
+}
+
+func TestInvalidFrame(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"TooLarge", "\x00\x00\x01\x00", "invalid frame size"},
+		{"UnknownFormat", "\x00\x00\x00\x02\x12\x34", "unknown frame format"},
+		{"ShortHeader", "\x00\x00\x00\x04\x0f\xff\x00\x00", "invalid header"},
+		{"UnknownProtocol", "\x00\x00\x00\x0e\x0f\xff\x00\x00\x00\x00\x00\x00\x00\x01\x09\x00\x00\x00", "unsupported protocol ID 9"},
+		{"UnknownTransform", "\x00\x00\x00\x0e\x0f\xff\x00\x00\x00\x00\x00\x00\x00\x01\x00\x01\x03\x00", "unsupported transform ID 3"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			r := (&Protocol{MaxFrameSize: 64}).NewReader(strings.NewReader(tt.in))
+			_, err := r.ReadMessageBegin()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("got %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
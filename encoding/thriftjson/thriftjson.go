@@ -0,0 +1,870 @@
+// Package thriftjson implements the Thrift JSON protocol encoding,
+// also known as TJSONProtocol.
+// See https://github.com/apache/thrift/blob/master/doc/specs/thrift-json-protocol.md
+// for details.
+//
+// Messages are encoded as JSON arrays like [1,"name",1,0,{...}], and structs
+// are encoded as JSON objects keyed by field IDs, whose values are tagged by
+// their types like {"1":{"i32":5}}. Binary values are encoded as base64
+// strings, and the special double values are encoded as the JSON strings
+// "NaN", "Infinity" and "-Infinity".
+package thriftjson
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/itstarsun/go-thrift/encoding/thriftwire"
+)
+
+const version1 = 1
+
+// Protocol is the [thriftwire.Protocol] that implements the Thrift JSON protocol encoding.
+var Protocol protocol
+
+type protocol struct{}
+
+func (protocol) NewReader(r io.Reader) thriftwire.Reader {
+	return &reader{b: bufio.NewReader(r)}
+}
+
+func (protocol) NewWriter(w io.Writer) thriftwire.Writer {
+	return &writer{b: bufio.NewWriter(w), w: w}
+}
+
+func (protocol) String() string {
+	return "thriftjson.Protocol"
+}
+
+func init() {
+	thriftwire.RegisterProtocol("[", Protocol)
+}
+
+var _ thriftwire.Protocol = (*protocol)(nil)
+
+var typeNames = [...]string{
+	thriftwire.Bool:   "tf",
+	thriftwire.Byte:   "i8",
+	thriftwire.Double: "dbl",
+	thriftwire.I16:    "i16",
+	thriftwire.I32:    "i32",
+	thriftwire.I64:    "i64",
+	thriftwire.String: "str",
+	thriftwire.Struct: "rec",
+	thriftwire.Map:    "map",
+	thriftwire.Set:    "set",
+	thriftwire.List:   "lst",
+	thriftwire.UUID:   "uid",
+}
+
+func typeName(t thriftwire.Type) (string, error) {
+	if int(t) < len(typeNames) && typeNames[t] != "" {
+		return typeNames[t], nil
+	}
+	return "", thriftwire.InvalidTypeError(t)
+}
+
+func typeOf(name string) (thriftwire.Type, error) {
+	for t, s := range typeNames {
+		if s != "" && s == name {
+			return thriftwire.Type(t), nil
+		}
+	}
+	return thriftwire.Stop, fmt.Errorf("thriftjson: unknown type %q", name)
+}
+
+// A context is the state of a JSON array or object being read or written,
+// which determines the separator before the next value.
+type context struct {
+	object bool // whether the values alternate between keys and values
+	n      int  // number of values so far
+}
+
+// contexts is a stack of contexts. The zero value is the top level,
+// where no separators are used.
+type contexts struct {
+	stack []context
+}
+
+func (c *contexts) push(object bool) {
+	c.stack = append(c.stack, context{object: object})
+}
+
+func (c *contexts) pop() {
+	c.stack = c.stack[:len(c.stack)-1]
+}
+
+// next returns the separator before the next value, or 0 if none,
+// and whether the value is an object key.
+func (c *contexts) next() (sep byte, key bool) {
+	if len(c.stack) == 0 {
+		return 0, false
+	}
+	top := &c.stack[len(c.stack)-1]
+	defer func() { top.n++ }()
+	switch {
+	case top.object && top.n%2 == 1:
+		return ':', false
+	case top.n > 0:
+		return ',', top.object
+	default:
+		return 0, top.object
+	}
+}
+
+func (c *contexts) reset() {
+	c.stack = c.stack[:0]
+}
+
+type reader struct {
+	b   *bufio.Reader
+	ctx contexts
+	buf []byte
+}
+
+// skipSpace skips whitespace, and returns the next byte without consuming it.
+func (x *reader) skipSpace() (byte, error) {
+	for {
+		c, err := x.b.ReadByte()
+		if err != nil {
+			return 0, unexpectedEOF(err)
+		}
+		switch c {
+		case ' ', '\t', '\n', '\r':
+			continue
+		}
+		return c, x.b.UnreadByte()
+	}
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// expect consumes the byte c after whitespace.
+func (x *reader) expect(c byte) error {
+	got, err := x.skipSpace()
+	if err != nil {
+		return err
+	}
+	if got != c {
+		return fmt.Errorf("thriftjson: expected %q, got %q", c, got)
+	}
+	_, err = x.b.ReadByte()
+	return err
+}
+
+// value consumes the separator before the next value,
+// and reports whether the value is an object key.
+func (x *reader) value() (key bool, err error) {
+	sep, key := x.ctx.next()
+	if sep != 0 {
+		if err := x.expect(sep); err != nil {
+			return key, err
+		}
+	}
+	return key, nil
+}
+
+func (x *reader) begin(c byte, object bool) error {
+	if _, err := x.value(); err != nil {
+		return err
+	}
+	if err := x.expect(c); err != nil {
+		return err
+	}
+	x.ctx.push(object)
+	return nil
+}
+
+func (x *reader) end(c byte) error {
+	if len(x.ctx.stack) == 0 {
+		return fmt.Errorf("thriftjson: unexpected %q", c)
+	}
+	if err := x.expect(c); err != nil {
+		return err
+	}
+	x.ctx.pop()
+	return nil
+}
+
+// readRawString reads a JSON string into x.buf.
+func (x *reader) readRawString() ([]byte, error) {
+	if err := x.expect('"'); err != nil {
+		return nil, err
+	}
+	buf := x.buf[:0]
+	for {
+		c, err := x.b.ReadByte()
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		switch c {
+		case '"':
+			x.buf = buf
+			return buf, nil
+		case '\\':
+		default:
+			buf = append(buf, c)
+			continue
+		}
+		c, err = x.b.ReadByte()
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		switch c {
+		case '"', '\\', '/':
+			buf = append(buf, c)
+		case 'b':
+			buf = append(buf, '\b')
+		case 'f':
+			buf = append(buf, '\f')
+		case 'n':
+			buf = append(buf, '\n')
+		case 'r':
+			buf = append(buf, '\r')
+		case 't':
+			buf = append(buf, '\t')
+		case 'u':
+			r, err := x.readHex4()
+			if err != nil {
+				return nil, err
+			}
+			if utf16.IsSurrogate(r) {
+				// Expect the low surrogate as another escape sequence.
+				if err := x.expectBytes(`\u`); err != nil {
+					return nil, err
+				}
+				r2, err := x.readHex4()
+				if err != nil {
+					return nil, err
+				}
+				r = utf16.DecodeRune(r, r2)
+			}
+			buf = utf8.AppendRune(buf, r)
+		default:
+			return nil, fmt.Errorf("thriftjson: invalid escape sequence \\%c", c)
+		}
+	}
+}
+
+func (x *reader) expectBytes(s string) error {
+	for i := 0; i < len(s); i++ {
+		c, err := x.b.ReadByte()
+		if err != nil {
+			return unexpectedEOF(err)
+		}
+		if c != s[i] {
+			return fmt.Errorf("thriftjson: expected %q, got %q", s[i], c)
+		}
+	}
+	return nil
+}
+
+func (x *reader) readHex4() (rune, error) {
+	buf, err := thriftwire.Next(x.b, 4)
+	if err != nil {
+		return 0, unexpectedEOF(err)
+	}
+	v, err := strconv.ParseUint(string(buf), 16, 16)
+	if err != nil {
+		return 0, fmt.Errorf("thriftjson: invalid escape sequence \\u%s", buf)
+	}
+	return rune(v), nil
+}
+
+// readNumber reads a JSON number, or a JSON string if quoted,
+// which is the case for object keys.
+func (x *reader) readNumber() (string, error) {
+	key, err := x.value()
+	if err != nil {
+		return "", err
+	}
+	c, err := x.skipSpace()
+	if err != nil {
+		return "", err
+	}
+	if c == '"' {
+		buf, err := x.readRawString()
+		return string(buf), err
+	}
+	if key {
+		return "", fmt.Errorf("thriftjson: expected a quoted number, got %q", c)
+	}
+	buf := x.buf[:0]
+	for {
+		c, err := x.b.ReadByte()
+		if err == io.EOF && len(buf) > 0 {
+			break
+		}
+		if err != nil {
+			return "", unexpectedEOF(err)
+		}
+		if !('0' <= c && c <= '9' || c == '-' || c == '+' || c == '.' || c == 'e' || c == 'E') {
+			if err := x.b.UnreadByte(); err != nil {
+				return "", err
+			}
+			break
+		}
+		buf = append(buf, c)
+	}
+	x.buf = buf
+	if len(buf) == 0 {
+		return "", fmt.Errorf("thriftjson: expected a number, got %q", c)
+	}
+	return string(buf), nil
+}
+
+func (x *reader) readInt(bitSize int) (int64, error) {
+	s, err := x.readNumber()
+	if err != nil {
+		return 0, err
+	}
+	v, err := strconv.ParseInt(s, 10, bitSize)
+	if err != nil {
+		return 0, fmt.Errorf("thriftjson: invalid integer %q", s)
+	}
+	return v, nil
+}
+
+func (x *reader) readString() ([]byte, error) {
+	if _, err := x.value(); err != nil {
+		return nil, err
+	}
+	return x.readRawString()
+}
+
+func (x *reader) ReadMessageBegin() (h thriftwire.MessageHeader, err error) {
+	if err := x.begin('[', false); err != nil {
+		return h, err
+	}
+	version, err := x.readInt(32)
+	if err != nil {
+		return h, err
+	}
+	if version != version1 {
+		return h, fmt.Errorf("thriftjson: bad version %d", version)
+	}
+	name, err := x.readString()
+	if err != nil {
+		return h, err
+	}
+	h.Name = string(name)
+	t, err := x.readInt(8)
+	if err != nil {
+		return h, err
+	}
+	h.Type = thriftwire.MessageType(t)
+	id, err := x.readInt(32)
+	if err != nil {
+		return h, err
+	}
+	h.ID = int32(id)
+	return h, nil
+}
+
+func (x *reader) ReadMessageEnd() error {
+	return x.end(']')
+}
+
+func (x *reader) ReadStructBegin() (h thriftwire.StructHeader, err error) {
+	return h, x.begin('{', true)
+}
+
+func (x *reader) ReadStructEnd() error {
+	return x.end('}')
+}
+
+func (x *reader) ReadFieldBegin() (h thriftwire.FieldHeader, err error) {
+	c, err := x.skipSpace()
+	if err != nil {
+		return h, err
+	}
+	if c == '}' {
+		return h, nil // the end of the struct is consumed by ReadStructEnd
+	}
+	id, err := x.readInt(16)
+	if err != nil {
+		return h, err
+	}
+	h.ID = int16(id)
+	if err := x.begin('{', true); err != nil {
+		return h, err
+	}
+	name, err := x.readString()
+	if err != nil {
+		return h, err
+	}
+	h.Type, err = typeOf(string(name))
+	return h, err
+}
+
+func (x *reader) ReadFieldEnd() error {
+	return x.end('}')
+}
+
+func (x *reader) readType() (thriftwire.Type, error) {
+	name, err := x.readString()
+	if err != nil {
+		return 0, err
+	}
+	return typeOf(string(name))
+}
+
+func (x *reader) readSize() (int, error) {
+	n, err := x.readInt(32)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("thriftjson: negative size %d", n)
+	}
+	return int(n), nil
+}
+
+func (x *reader) ReadMapBegin() (h thriftwire.MapHeader, err error) {
+	if err := x.begin('[', false); err != nil {
+		return h, err
+	}
+	if h.Key, err = x.readType(); err != nil {
+		return h, err
+	}
+	if h.Value, err = x.readType(); err != nil {
+		return h, err
+	}
+	if h.Size, err = x.readSize(); err != nil {
+		return h, err
+	}
+	return h, x.begin('{', true)
+}
+
+func (x *reader) ReadMapEnd() error {
+	if err := x.end('}'); err != nil {
+		return err
+	}
+	return x.end(']')
+}
+
+func (x *reader) readListBegin() (h thriftwire.ListHeader, err error) {
+	if err := x.begin('[', false); err != nil {
+		return h, err
+	}
+	if h.Element, err = x.readType(); err != nil {
+		return h, err
+	}
+	h.Size, err = x.readSize()
+	return h, err
+}
+
+func (x *reader) ReadSetBegin() (thriftwire.SetHeader, error) {
+	h, err := x.readListBegin()
+	return thriftwire.SetHeader(h), err
+}
+
+func (x *reader) ReadSetEnd() error {
+	return x.end(']')
+}
+
+func (x *reader) ReadListBegin() (thriftwire.ListHeader, error) {
+	return x.readListBegin()
+}
+
+func (x *reader) ReadListEnd() error {
+	return x.end(']')
+}
+
+func (x *reader) ReadBool() (bool, error) {
+	v, err := x.readInt(8)
+	return v != 0, err
+}
+
+func (x *reader) ReadByte() (byte, error) {
+	v, err := x.readInt(8)
+	return byte(v), err
+}
+
+func (x *reader) ReadDouble() (float64, error) {
+	s, err := x.readNumber()
+	if err != nil {
+		return 0, err
+	}
+	switch s {
+	case "NaN":
+		return math.NaN(), nil
+	case "Infinity":
+		return math.Inf(1), nil
+	case "-Infinity":
+		return math.Inf(-1), nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("thriftjson: invalid double %q", s)
+	}
+	return v, nil
+}
+
+func (x *reader) ReadI16() (int16, error) {
+	v, err := x.readInt(16)
+	return int16(v), err
+}
+
+func (x *reader) ReadI32() (int32, error) {
+	v, err := x.readInt(32)
+	return int32(v), err
+}
+
+func (x *reader) ReadI64() (int64, error) {
+	return x.readInt(64)
+}
+
+func (x *reader) ReadString() (string, error) {
+	v, err := x.readString()
+	return string(v), err
+}
+
+func (x *reader) ReadBytes(buf []byte) ([]byte, error) {
+	v, err := x.readString()
+	if err != nil {
+		return buf, err
+	}
+	// Accept base64 with or without padding like Apache Thrift.
+	for len(v) > 0 && v[len(v)-1] == '=' {
+		v = v[:len(v)-1]
+	}
+	n := len(buf)
+	buf = append(buf, make([]byte, base64.RawStdEncoding.DecodedLen(len(v)))...)
+	m, err := base64.RawStdEncoding.Decode(buf[n:], v)
+	if err != nil {
+		return buf[:n], fmt.Errorf("thriftjson: invalid base64: %w", err)
+	}
+	return buf[:n+m], nil
+}
+
+func (x *reader) ReadUUID(v *[16]byte) error {
+	s, err := x.readString()
+	if err != nil {
+		return err
+	}
+	if len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return fmt.Errorf("thriftjson: invalid UUID %q", s)
+	}
+	var digits [32]byte
+	n := 0
+	for i, c := range s {
+		if i != 8 && i != 13 && i != 18 && i != 23 {
+			digits[n] = byte(c)
+			n++
+		}
+	}
+	if _, err := hex.Decode(v[:], digits[:]); err != nil {
+		return fmt.Errorf("thriftjson: invalid UUID %q", s)
+	}
+	return nil
+}
+
+func (x *reader) SkipString() error {
+	_, err := x.readString()
+	return err
+}
+
+func (x *reader) SkipUUID() error {
+	var v [16]byte
+	return x.ReadUUID(&v)
+}
+
+func (x *reader) Reset(r io.Reader) {
+	x.b.Reset(r)
+	x.ctx.reset()
+}
+
+type writer struct {
+	b   *bufio.Writer
+	w   io.Writer
+	ctx contexts
+	buf []byte
+}
+
+// value writes the separator before the next value,
+// and reports whether the value is an object key.
+func (x *writer) value() (key bool, err error) {
+	sep, key := x.ctx.next()
+	if sep != 0 {
+		return key, x.b.WriteByte(sep)
+	}
+	return key, nil
+}
+
+func (x *writer) begin(c byte, object bool) error {
+	if _, err := x.value(); err != nil {
+		return err
+	}
+	x.ctx.push(object)
+	return x.b.WriteByte(c)
+}
+
+func (x *writer) end(c byte) error {
+	if len(x.ctx.stack) == 0 {
+		return fmt.Errorf("thriftjson: unexpected %q", c)
+	}
+	x.ctx.pop()
+	return x.b.WriteByte(c)
+}
+
+// writeNumber writes the number formatted in x.buf,
+// which is quoted if it is an object key.
+func (x *writer) writeNumber(num []byte) error {
+	key, err := x.value()
+	if err != nil {
+		return err
+	}
+	if key {
+		x.b.WriteByte('"')
+	}
+	x.b.Write(num)
+	if key {
+		return x.b.WriteByte('"')
+	}
+	return nil
+}
+
+func (x *writer) writeInt(v int64) error {
+	x.buf = strconv.AppendInt(x.buf[:0], v, 10)
+	return x.writeNumber(x.buf)
+}
+
+func (x *writer) writeString(s string) error {
+	if _, err := x.value(); err != nil {
+		return err
+	}
+	x.buf = appendQuoted(x.buf[:0], s)
+	_, err := x.b.Write(x.buf)
+	return err
+}
+
+// appendQuoted appends s as a JSON string to buf. Like Apache Thrift,
+// only the quote, the backslash and control characters are escaped.
+func appendQuoted(buf []byte, s string) []byte {
+	const hex = "0123456789abcdef"
+	buf = append(buf, '"')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"', '\\':
+			buf = append(buf, '\\', c)
+		case '\b':
+			buf = append(buf, '\\', 'b')
+		case '\f':
+			buf = append(buf, '\\', 'f')
+		case '\n':
+			buf = append(buf, '\\', 'n')
+		case '\r':
+			buf = append(buf, '\\', 'r')
+		case '\t':
+			buf = append(buf, '\\', 't')
+		default:
+			if c < 0x20 {
+				buf = append(buf, '\\', 'u', '0', '0', hex[c>>4], hex[c&15])
+			} else {
+				buf = append(buf, c)
+			}
+		}
+	}
+	return append(buf, '"')
+}
+
+func (x *writer) writeType(t thriftwire.Type) error {
+	name, err := typeName(t)
+	if err != nil {
+		return err
+	}
+	return x.writeString(name)
+}
+
+func (x *writer) WriteMessageBegin(h thriftwire.MessageHeader) error {
+	if err := x.begin('[', false); err != nil {
+		return err
+	}
+	if err := x.writeInt(version1); err != nil {
+		return err
+	}
+	if err := x.writeString(h.Name); err != nil {
+		return err
+	}
+	if err := x.writeInt(int64(h.Type)); err != nil {
+		return err
+	}
+	return x.writeInt(int64(h.ID))
+}
+
+func (x *writer) WriteMessageEnd() error {
+	return x.end(']')
+}
+
+func (x *writer) WriteStructBegin(h thriftwire.StructHeader) error {
+	return x.begin('{', true)
+}
+
+func (x *writer) WriteStructEnd() error {
+	return x.end('}')
+}
+
+func (x *writer) WriteFieldBegin(h thriftwire.FieldHeader) error {
+	if err := x.writeInt(int64(h.ID)); err != nil {
+		return err
+	}
+	if err := x.begin('{', true); err != nil {
+		return err
+	}
+	return x.writeType(h.Type)
+}
+
+func (x *writer) WriteFieldEnd() error {
+	return x.end('}')
+}
+
+// writeEmptyType writes t, or a placeholder if t is Stop for empty
+// containers, since the types are required even if there are no elements.
+func (x *writer) writeEmptyType(t thriftwire.Type, size int) error {
+	if t == thriftwire.Stop && size <= 0 {
+		t = thriftwire.Byte
+	}
+	return x.writeType(t)
+}
+
+func (x *writer) WriteMapBegin(h thriftwire.MapHeader) error {
+	if err := x.begin('[', false); err != nil {
+		return err
+	}
+	if err := x.writeEmptyType(h.Key, h.Size); err != nil {
+		return err
+	}
+	if err := x.writeEmptyType(h.Value, h.Size); err != nil {
+		return err
+	}
+	if err := x.writeInt(int64(max(h.Size, 0))); err != nil {
+		return err
+	}
+	return x.begin('{', true)
+}
+
+func (x *writer) WriteMapEnd() error {
+	if err := x.end('}'); err != nil {
+		return err
+	}
+	return x.end(']')
+}
+
+func (x *writer) writeListBegin(h thriftwire.ListHeader) error {
+	if err := x.begin('[', false); err != nil {
+		return err
+	}
+	if err := x.writeEmptyType(h.Element, h.Size); err != nil {
+		return err
+	}
+	return x.writeInt(int64(max(h.Size, 0)))
+}
+
+func (x *writer) WriteSetBegin(h thriftwire.SetHeader) error {
+	return x.writeListBegin(thriftwire.ListHeader(h))
+}
+
+func (x *writer) WriteSetEnd() error {
+	return x.end(']')
+}
+
+func (x *writer) WriteListBegin(h thriftwire.ListHeader) error {
+	return x.writeListBegin(h)
+}
+
+func (x *writer) WriteListEnd() error {
+	return x.end(']')
+}
+
+func (x *writer) WriteBool(v bool) error {
+	if v {
+		return x.writeInt(1)
+	}
+	return x.writeInt(0)
+}
+
+func (x *writer) WriteByte(v byte) error {
+	return x.writeInt(int64(int8(v)))
+}
+
+func (x *writer) WriteDouble(v float64) error {
+	switch {
+	case math.IsNaN(v):
+		return x.writeString("NaN")
+	case math.IsInf(v, 1):
+		return x.writeString("Infinity")
+	case math.IsInf(v, -1):
+		return x.writeString("-Infinity")
+	}
+	x.buf = strconv.AppendFloat(x.buf[:0], v, 'g', -1, 64)
+	return x.writeNumber(x.buf)
+}
+
+func (x *writer) WriteI16(v int16) error {
+	return x.writeInt(int64(v))
+}
+
+func (x *writer) WriteI32(v int32) error {
+	return x.writeInt(int64(v))
+}
+
+func (x *writer) WriteI64(v int64) error {
+	return x.writeInt(v)
+}
+
+func (x *writer) WriteString(v string) error {
+	return x.writeString(v)
+}
+
+func (x *writer) WriteBytes(v []byte) error {
+	if _, err := x.value(); err != nil {
+		return err
+	}
+	n := base64.StdEncoding.EncodedLen(len(v))
+	x.buf = append(x.buf[:0], make([]byte, n+2)...)
+	x.buf[0] = '"'
+	base64.StdEncoding.Encode(x.buf[1:], v)
+	x.buf[n+1] = '"'
+	_, err := x.b.Write(x.buf)
+	return err
+}
+
+func (x *writer) WriteUUID(v *[16]byte) error {
+	var buf [36]byte
+	hex.Encode(buf[0:8], v[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], v[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], v[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], v[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], v[10:])
+	return x.writeString(string(buf[:]))
+}
+
+func (x *writer) Flush() error {
+	if err := x.b.Flush(); err != nil {
+		return err
+	}
+	return thriftwire.Flush(x.w)
+}
+
+func (x *writer) Reset(w io.Writer) {
+	x.b.Reset(w)
+	x.w = w
+	x.ctx.reset()
+}
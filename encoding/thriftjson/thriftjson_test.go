@@ -0,0 +1,130 @@
+package thriftjson
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/itstarsun/go-thrift/encoding/thriftwire"
+	"github.com/itstarsun/go-thrift/testing/thrifttest"
+	"github.com/itstarsun/go-thrift/thrift"
+)
+
+var protocolOptions = thrifttest.ProtocolOptions{
+	UUID: true,
+}
+
+func TestProtocol(t *testing.T) {
+	thrifttest.TestProtocol(t, Protocol, protocolOptions)
+}
+
+type aStruct struct {
+	Bool   bool               `thrift:"1"`
+	Byte   int8               `thrift:"2"`
+	I32    int32              `thrift:"3"`
+	Double float64            `thrift:"4"`
+	String string             `thrift:"5"`
+	Bytes  []byte             `thrift:"6"`
+	UUID   [16]byte           `thrift:"7"`
+	Map    map[int32]float64  `thrift:"8"`
+	List   thrift.List[int16] `thrift:"9"`
+	Set    thrift.Set[string] `thrift:"10"`
+	Struct *aStruct           `thrift:"11"`
+}
+
+func TestEncoding(t *testing.T) {
+	in := &aStruct{
+		Bool:   true,
+		Byte:   -1,
+		I32:    5,
+		Double: 1.5,
+		String: "\"é\"\n\x01",
+		Bytes:  []byte("hello"),
+		UUID:   [16]byte{0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88, 0x99, 0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0xff},
+		Map:    map[int32]float64{1: math.Inf(-1)},
+		List:   thrift.List[int16]{1, 2},
+		Set:    thrift.Set[string]{"a"},
+		Struct: &aStruct{I32: 1},
+	}
+	const want = `[1,"call",1,7,{` +
+		`"1":{"tf":1},` +
+		`"2":{"i8":-1},` +
+		`"3":{"i32":5},` +
+		`"4":{"dbl":1.5},` +
+		`"5":{"str":"\"é\"\n\u0001"},` +
+		`"6":{"str":"aGVsbG8="},` +
+		`"7":{"uid":"00112233-4455-6677-8899-aabbccddeeff"},` +
+		`"8":{"map":["i32","dbl",1,{"1":"-Infinity"}]},` +
+		`"9":{"lst":["i16",2,1,2]},` +
+		`"10":{"set":["str",1,"a"]},` +
+		`"11":{"rec":{"3":{"i32":1}}}` +
+		`}]`
+
+	var b bytes.Buffer
+	w := Protocol.NewWriter(&b)
+	mh := thriftwire.MessageHeader{Name: "call", Type: thriftwire.Call, ID: 7}
+	if err := w.WriteMessageBegin(mh); err != nil {
+		t.Fatal(err)
+	}
+	if err := thrift.Marshal(w, in); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteMessageEnd(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.String(); got != want {
+		t.Fatalf("\ngot  %s\nwant %s", got, want)
+	}
+
+	// Whitespace, unpadded base64 and escaped surrogate pairs are accepted.
+	input := `[ 1, "call", 1, 7, { "6": {"str": "aGVsbG8"}, "5": {"str": "\ud83d\ude00\/"} } ]`
+	r := Protocol.NewReader(bytes.NewBufferString(input))
+	if got, err := r.ReadMessageBegin(); err != nil || got != mh {
+		t.Fatalf("got (%+v, %v), want %+v", got, err, mh)
+	}
+	var out aStruct
+	if err := thrift.Unmarshal(r, &out); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.ReadMessageEnd(); err != nil {
+		t.Fatal(err)
+	}
+	if string(out.Bytes) != "hello" || out.String != "😀/" {
+		t.Fatalf("got %s", thrift.Format(&out))
+	}
+
+	r.Reset(&b)
+	if _, err := r.ReadMessageBegin(); err != nil {
+		t.Fatal(err)
+	}
+	out = aStruct{}
+	if err := thrift.Unmarshal(r, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !thrift.Equal(&out, in) {
+		t.Fatalf("round trip mismatch: %v", thrift.Diff(in, &out))
+	}
+}
+
+func TestSpecialDoubles(t *testing.T) {
+	for _, v := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		var b bytes.Buffer
+		w := Protocol.NewWriter(&b)
+		if err := w.WriteDouble(v); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		got, err := Protocol.NewReader(&b).ReadDouble()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !(got == v || math.IsNaN(got) && math.IsNaN(v)) {
+			t.Errorf("got %v, want %v", got, v)
+		}
+	}
+}
@@ -0,0 +1,756 @@
+// Package thriftsimplejson implements a simple JSON encoding of Thrift values
+// in the spirit of TSimpleJSONProtocol, which is meant to be read by humans
+// and by JSON tools rather than by Thrift peers.
+//
+// Structs are encoded as JSON objects keyed by field names, or by field IDs
+// if the names are unknown, like {"Name":"x","Count":5}. Maps are encoded as
+// JSON objects whose keys are the map keys as JSON strings, and sets and
+// lists are encoded as JSON arrays. Messages are encoded as JSON arrays like
+// ["name",1,0,{...}]. Binary values are encoded as base64 strings, UUIDs are
+// encoded as canonical strings, and the special double values are encoded as
+// the JSON strings "NaN", "Infinity" and "-Infinity".
+//
+// Since neither the field IDs nor the types of values are encoded,
+// the reader is a [thriftwire.NamedFieldReader]: it can only be read with
+// the schema of the values, such as by [thrift.Unmarshal] into Go structs,
+// which matches fields by their names. Fields with null values are ignored.
+package thriftsimplejson
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/itstarsun/go-thrift/encoding/thriftwire"
+)
+
+// Protocol is the [thriftwire.Protocol] that implements the simple JSON encoding.
+var Protocol protocol
+
+type protocol struct{}
+
+func (protocol) NewReader(r io.Reader) thriftwire.Reader {
+	return &reader{d: newDecoder(r)}
+}
+
+func (protocol) NewWriter(w io.Writer) thriftwire.Writer {
+	return &writer{b: bufio.NewWriter(w), w: w}
+}
+
+func (protocol) String() string {
+	return "thriftsimplejson.Protocol"
+}
+
+func init() {
+	// Unlike the messages of thriftjson, which begin with the version 1.
+	thriftwire.RegisterProtocol(`["`, Protocol)
+}
+
+var _ thriftwire.Protocol = (*protocol)(nil)
+
+// A member is a member of a JSON object.
+type member struct {
+	key   string
+	value any
+}
+
+// JSON values are decoded as nil, bool, json.Number, string, object or array.
+type (
+	object []member
+	array  []any
+)
+
+// A frame is a JSON object or array being read.
+type frame struct {
+	values []any  // values to be read next
+	fields object // fields of a struct to be read next
+}
+
+type reader struct {
+	d      *json.Decoder
+	frames []frame
+}
+
+var _ thriftwire.NamedFieldReader = (*reader)(nil)
+
+func newDecoder(r io.Reader) *json.Decoder {
+	d := json.NewDecoder(r)
+	d.UseNumber()
+	return d
+}
+
+func (x *reader) NamedFields() {}
+
+// decode decodes the next JSON value from the input,
+// preserving the order of the members of objects.
+func (x *reader) decode() (any, error) {
+	tok, err := x.d.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case json.Delim('['):
+		a := array{}
+		for x.d.More() {
+			v, err := x.decode()
+			if err != nil {
+				return nil, unexpectedEOF(err)
+			}
+			a = append(a, v)
+		}
+		if _, err := x.d.Token(); err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		return a, nil
+	case json.Delim('{'):
+		o := object{}
+		for x.d.More() {
+			tok, err := x.d.Token()
+			if err != nil {
+				return nil, unexpectedEOF(err)
+			}
+			v, err := x.decode()
+			if err != nil {
+				return nil, unexpectedEOF(err)
+			}
+			o = append(o, member{key: tok.(string), value: v})
+		}
+		if _, err := x.d.Token(); err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		return o, nil
+	}
+	return tok, nil
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// next returns the next value to be read. At the top level,
+// it decodes the next JSON value from the input.
+func (x *reader) next() (any, error) {
+	if len(x.frames) == 0 {
+		return x.decode()
+	}
+	f := &x.frames[len(x.frames)-1]
+	if len(f.values) == 0 {
+		return nil, fmt.Errorf("thriftsimplejson: no more values to read")
+	}
+	v := f.values[0]
+	f.values = f.values[1:]
+	return v, nil
+}
+
+func (x *reader) push(f frame) {
+	x.frames = append(x.frames, f)
+}
+
+func (x *reader) pop() error {
+	if len(x.frames) == 0 {
+		return fmt.Errorf("thriftsimplejson: unexpected end of value")
+	}
+	x.frames = x.frames[:len(x.frames)-1]
+	return nil
+}
+
+// describe returns the kind of the JSON value v for error messages.
+func describe(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	case string:
+		return "string"
+	case object:
+		return "object"
+	case array:
+		return "array"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func unexpected(want string, v any) error {
+	return fmt.Errorf("thriftsimplejson: expected %s, got %s", want, describe(v))
+}
+
+// typeOf returns the Thrift type that describes the shape of the JSON value v,
+// or Stop for null. Numbers are doubles, which can be read from any number.
+// The elements of arrays must have the same shape to be skipped.
+func typeOf(v any) thriftwire.Type {
+	switch v.(type) {
+	case bool:
+		return thriftwire.Bool
+	case json.Number:
+		return thriftwire.Double
+	case string:
+		return thriftwire.String
+	case object:
+		return thriftwire.Struct
+	case array:
+		return thriftwire.List
+	}
+	return thriftwire.Stop
+}
+
+func (x *reader) ReadMessageBegin() (h thriftwire.MessageHeader, err error) {
+	v, err := x.next()
+	if err != nil {
+		return h, err
+	}
+	a, ok := v.(array)
+	if !ok || len(a) != 4 {
+		return h, unexpected("message array", v)
+	}
+	x.push(frame{values: a})
+	if h.Name, err = x.ReadString(); err != nil {
+		return h, err
+	}
+	t, err := x.readInt(8)
+	if err != nil {
+		return h, err
+	}
+	h.Type = thriftwire.MessageType(t)
+	id, err := x.readInt(32)
+	if err != nil {
+		return h, err
+	}
+	h.ID = int32(id)
+	return h, nil
+}
+
+func (x *reader) ReadMessageEnd() error {
+	return x.pop()
+}
+
+func (x *reader) ReadStructBegin() (h thriftwire.StructHeader, err error) {
+	v, err := x.next()
+	if err != nil {
+		return h, err
+	}
+	o, ok := v.(object)
+	if !ok {
+		return h, unexpected("object", v)
+	}
+	x.push(frame{fields: o})
+	return h, nil
+}
+
+func (x *reader) ReadStructEnd() error {
+	return x.pop()
+}
+
+func (x *reader) ReadFieldBegin() (h thriftwire.FieldHeader, err error) {
+	if len(x.frames) == 0 {
+		return h, fmt.Errorf("thriftsimplejson: unexpected field")
+	}
+	f := &x.frames[len(x.frames)-1]
+	for len(f.fields) > 0 {
+		m := f.fields[0]
+		f.fields = f.fields[1:]
+		if m.value == nil {
+			continue
+		}
+		if id, err := strconv.ParseInt(m.key, 10, 16); err == nil {
+			h.ID = int16(id)
+		} else {
+			h.Name = m.key
+		}
+		h.Type = typeOf(m.value)
+		f.values = append(f.values[:0], m.value)
+		return h, nil
+	}
+	return h, nil // Stop
+}
+
+func (x *reader) ReadFieldEnd() error {
+	return nil
+}
+
+func (x *reader) ReadMapBegin() (h thriftwire.MapHeader, err error) {
+	v, err := x.next()
+	if err != nil {
+		return h, err
+	}
+	o, ok := v.(object)
+	if !ok {
+		return h, unexpected("object", v)
+	}
+	values := make([]any, 0, 2*len(o))
+	for _, m := range o {
+		values = append(values, m.key, m.value)
+	}
+	x.push(frame{values: values})
+	h.Key = thriftwire.String
+	if len(o) > 0 {
+		h.Value = typeOf(o[0].value)
+	}
+	h.Size = len(o)
+	return h, nil
+}
+
+func (x *reader) ReadMapEnd() error {
+	return x.pop()
+}
+
+func (x *reader) readListBegin() (h thriftwire.ListHeader, err error) {
+	v, err := x.next()
+	if err != nil {
+		return h, err
+	}
+	a, ok := v.(array)
+	if !ok {
+		return h, unexpected("array", v)
+	}
+	x.push(frame{values: a})
+	if len(a) > 0 {
+		h.Element = typeOf(a[0])
+	}
+	h.Size = len(a)
+	return h, nil
+}
+
+func (x *reader) ReadSetBegin() (thriftwire.SetHeader, error) {
+	h, err := x.readListBegin()
+	return thriftwire.SetHeader(h), err
+}
+
+func (x *reader) ReadSetEnd() error {
+	return x.pop()
+}
+
+func (x *reader) ReadListBegin() (thriftwire.ListHeader, error) {
+	return x.readListBegin()
+}
+
+func (x *reader) ReadListEnd() error {
+	return x.pop()
+}
+
+// readNumber reads a JSON number, or a JSON string,
+// which is the case for map keys and the special double values.
+func (x *reader) readNumber() (string, error) {
+	v, err := x.next()
+	if err != nil {
+		return "", err
+	}
+	switch v := v.(type) {
+	case json.Number:
+		return string(v), nil
+	case string:
+		return v, nil
+	}
+	return "", unexpected("number", v)
+}
+
+func (x *reader) readInt(bitSize int) (int64, error) {
+	s, err := x.readNumber()
+	if err != nil {
+		return 0, err
+	}
+	v, err := strconv.ParseInt(s, 10, bitSize)
+	if err != nil {
+		return 0, fmt.Errorf("thriftsimplejson: invalid integer %q", s)
+	}
+	return v, nil
+}
+
+func (x *reader) ReadBool() (bool, error) {
+	v, err := x.next()
+	if err != nil {
+		return false, err
+	}
+	switch v {
+	case true, "true":
+		return true, nil
+	case false, "false":
+		return false, nil
+	}
+	return false, unexpected("boolean", v)
+}
+
+func (x *reader) ReadByte() (byte, error) {
+	v, err := x.readInt(8)
+	return byte(v), err
+}
+
+func (x *reader) ReadDouble() (float64, error) {
+	s, err := x.readNumber()
+	if err != nil {
+		return 0, err
+	}
+	switch s {
+	case "NaN":
+		return math.NaN(), nil
+	case "Infinity":
+		return math.Inf(1), nil
+	case "-Infinity":
+		return math.Inf(-1), nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("thriftsimplejson: invalid double %q", s)
+	}
+	return v, nil
+}
+
+func (x *reader) ReadI16() (int16, error) {
+	v, err := x.readInt(16)
+	return int16(v), err
+}
+
+func (x *reader) ReadI32() (int32, error) {
+	v, err := x.readInt(32)
+	return int32(v), err
+}
+
+func (x *reader) ReadI64() (int64, error) {
+	return x.readInt(64)
+}
+
+func (x *reader) ReadString() (string, error) {
+	v, err := x.next()
+	if err != nil {
+		return "", err
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", unexpected("string", v)
+	}
+	return s, nil
+}
+
+func (x *reader) ReadBytes(buf []byte) ([]byte, error) {
+	s, err := x.ReadString()
+	if err != nil {
+		return buf, err
+	}
+	// Accept base64 with or without padding.
+	for len(s) > 0 && s[len(s)-1] == '=' {
+		s = s[:len(s)-1]
+	}
+	n := len(buf)
+	buf = append(buf, make([]byte, base64.RawStdEncoding.DecodedLen(len(s)))...)
+	m, err := base64.RawStdEncoding.Decode(buf[n:], []byte(s))
+	if err != nil {
+		return buf[:n], fmt.Errorf("thriftsimplejson: invalid base64: %w", err)
+	}
+	return buf[:n+m], nil
+}
+
+func (x *reader) ReadUUID(v *[16]byte) error {
+	s, err := x.ReadString()
+	if err != nil {
+		return err
+	}
+	if len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return fmt.Errorf("thriftsimplejson: invalid UUID %q", s)
+	}
+	var digits [32]byte
+	n := 0
+	for i := 0; i < len(s); i++ {
+		if i != 8 && i != 13 && i != 18 && i != 23 {
+			digits[n] = s[i]
+			n++
+		}
+	}
+	if _, err := hex.Decode(v[:], digits[:]); err != nil {
+		return fmt.Errorf("thriftsimplejson: invalid UUID %q", s)
+	}
+	return nil
+}
+
+func (x *reader) SkipString() error {
+	_, err := x.ReadString()
+	return err
+}
+
+func (x *reader) SkipUUID() error {
+	var v [16]byte
+	return x.ReadUUID(&v)
+}
+
+func (x *reader) Reset(r io.Reader) {
+	x.d = newDecoder(r)
+	x.frames = x.frames[:0]
+}
+
+// Kinds of contexts being written.
+const (
+	inStruct = iota + 1
+	inMap
+	inList
+)
+
+// A context is the state of a JSON object or array being written,
+// which determines the separator before the next value.
+type context struct {
+	kind int
+	n    int // number of values, or fields for structs, so far
+}
+
+type writer struct {
+	b   *bufio.Writer
+	w   io.Writer
+	ctx []context
+	buf []byte
+}
+
+// value writes the separator before the next value,
+// and reports whether the value is a map key.
+func (x *writer) value() (key bool, err error) {
+	if len(x.ctx) == 0 {
+		return false, nil
+	}
+	top := &x.ctx[len(x.ctx)-1]
+	switch top.kind {
+	case inMap:
+		top.n++
+		switch {
+		case top.n%2 == 0:
+			return false, x.b.WriteByte(':')
+		case top.n > 1:
+			return true, x.b.WriteByte(',')
+		}
+		return true, nil
+	case inList:
+		top.n++
+		if top.n > 1 {
+			return false, x.b.WriteByte(',')
+		}
+	}
+	return false, nil // preceded by the field name in structs
+}
+
+func (x *writer) begin(c byte, kind int) error {
+	key, err := x.value()
+	if err != nil {
+		return err
+	}
+	if key {
+		return fmt.Errorf("thriftsimplejson: unsupported map key %q", c)
+	}
+	x.ctx = append(x.ctx, context{kind: kind})
+	return x.b.WriteByte(c)
+}
+
+func (x *writer) end(c byte) error {
+	if len(x.ctx) == 0 {
+		return fmt.Errorf("thriftsimplejson: unexpected %q", c)
+	}
+	x.ctx = x.ctx[:len(x.ctx)-1]
+	return x.b.WriteByte(c)
+}
+
+// writeRaw writes the JSON value in buf, which is quoted if it is a map key.
+func (x *writer) writeRaw(buf []byte) error {
+	key, err := x.value()
+	if err != nil {
+		return err
+	}
+	if key {
+		x.b.WriteByte('"')
+	}
+	x.b.Write(buf)
+	if key {
+		return x.b.WriteByte('"')
+	}
+	return nil
+}
+
+func (x *writer) writeInt(v int64) error {
+	x.buf = strconv.AppendInt(x.buf[:0], v, 10)
+	return x.writeRaw(x.buf)
+}
+
+func (x *writer) writeString(s string) error {
+	if _, err := x.value(); err != nil {
+		return err
+	}
+	x.buf = appendQuoted(x.buf[:0], s)
+	_, err := x.b.Write(x.buf)
+	return err
+}
+
+// appendQuoted appends s as a JSON string to buf.
+// Only the quote, the backslash and control characters are escaped.
+func appendQuoted(buf []byte, s string) []byte {
+	const hex = "0123456789abcdef"
+	buf = append(buf, '"')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"', '\\':
+			buf = append(buf, '\\', c)
+		case '\b':
+			buf = append(buf, '\\', 'b')
+		case '\f':
+			buf = append(buf, '\\', 'f')
+		case '\n':
+			buf = append(buf, '\\', 'n')
+		case '\r':
+			buf = append(buf, '\\', 'r')
+		case '\t':
+			buf = append(buf, '\\', 't')
+		default:
+			if c < 0x20 {
+				buf = append(buf, '\\', 'u', '0', '0', hex[c>>4], hex[c&15])
+			} else {
+				buf = append(buf, c)
+			}
+		}
+	}
+	return append(buf, '"')
+}
+
+func (x *writer) WriteMessageBegin(h thriftwire.MessageHeader) error {
+	if err := x.begin('[', inList); err != nil {
+		return err
+	}
+	if err := x.writeString(h.Name); err != nil {
+		return err
+	}
+	if err := x.writeInt(int64(h.Type)); err != nil {
+		return err
+	}
+	return x.writeInt(int64(h.ID))
+}
+
+func (x *writer) WriteMessageEnd() error {
+	return x.end(']')
+}
+
+func (x *writer) WriteStructBegin(h thriftwire.StructHeader) error {
+	return x.begin('{', inStruct)
+}
+
+func (x *writer) WriteStructEnd() error {
+	return x.end('}')
+}
+
+func (x *writer) WriteFieldBegin(h thriftwire.FieldHeader) error {
+	if len(x.ctx) == 0 || x.ctx[len(x.ctx)-1].kind != inStruct {
+		return fmt.Errorf("thriftsimplejson: unexpected field %d outside of struct", h.ID)
+	}
+	top := &x.ctx[len(x.ctx)-1]
+	top.n++
+	if top.n > 1 {
+		x.b.WriteByte(',')
+	}
+	if h.Name != "" {
+		x.buf = appendQuoted(x.buf[:0], h.Name)
+	} else {
+		x.buf = strconv.AppendInt(append(x.buf[:0], '"'), int64(h.ID), 10)
+		x.buf = append(x.buf, '"')
+	}
+	x.buf = append(x.buf, ':')
+	_, err := x.b.Write(x.buf)
+	return err
+}
+
+func (x *writer) WriteFieldEnd() error {
+	return nil
+}
+
+func (x *writer) WriteMapBegin(h thriftwire.MapHeader) error {
+	return x.begin('{', inMap)
+}
+
+func (x *writer) WriteMapEnd() error {
+	return x.end('}')
+}
+
+func (x *writer) WriteSetBegin(h thriftwire.SetHeader) error {
+	return x.begin('[', inList)
+}
+
+func (x *writer) WriteSetEnd() error {
+	return x.end(']')
+}
+
+func (x *writer) WriteListBegin(h thriftwire.ListHeader) error {
+	return x.begin('[', inList)
+}
+
+func (x *writer) WriteListEnd() error {
+	return x.end(']')
+}
+
+func (x *writer) WriteBool(v bool) error {
+	x.buf = strconv.AppendBool(x.buf[:0], v)
+	return x.writeRaw(x.buf)
+}
+
+func (x *writer) WriteByte(v byte) error {
+	return x.writeInt(int64(int8(v)))
+}
+
+func (x *writer) WriteDouble(v float64) error {
+	switch {
+	case math.IsNaN(v):
+		return x.writeString("NaN")
+	case math.IsInf(v, 1):
+		return x.writeString("Infinity")
+	case math.IsInf(v, -1):
+		return x.writeString("-Infinity")
+	}
+	x.buf = strconv.AppendFloat(x.buf[:0], v, 'g', -1, 64)
+	return x.writeRaw(x.buf)
+}
+
+func (x *writer) WriteI16(v int16) error {
+	return x.writeInt(int64(v))
+}
+
+func (x *writer) WriteI32(v int32) error {
+	return x.writeInt(int64(v))
+}
+
+func (x *writer) WriteI64(v int64) error {
+	return x.writeInt(v)
+}
+
+func (x *writer) WriteString(v string) error {
+	return x.writeString(v)
+}
+
+func (x *writer) WriteBytes(v []byte) error {
+	return x.writeString(base64.StdEncoding.EncodeToString(v))
+}
+
+func (x *writer) WriteUUID(v *[16]byte) error {
+	var buf [36]byte
+	hex.Encode(buf[0:8], v[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], v[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], v[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], v[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], v[10:])
+	return x.writeString(string(buf[:]))
+}
+
+func (x *writer) Flush() error {
+	if err := x.b.Flush(); err != nil {
+		return err
+	}
+	return thriftwire.Flush(x.w)
+}
+
+func (x *writer) Reset(w io.Writer) {
+	x.b.Reset(w)
+	x.w = w
+	x.ctx = x.ctx[:0]
+}
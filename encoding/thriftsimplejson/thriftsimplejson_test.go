@@ -0,0 +1,154 @@
+package thriftsimplejson
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/itstarsun/go-thrift/encoding/thriftwire"
+	"github.com/itstarsun/go-thrift/thrift"
+)
+
+type aStruct struct {
+	Bool   bool               `thrift:"1"`
+	Byte   int8               `thrift:"2"`
+	I32    int32              `thrift:"3"`
+	Double float64            `thrift:"4"`
+	String string             `thrift:"5"`
+	Bytes  []byte             `thrift:"6"`
+	UUID   [16]byte           `thrift:"7"`
+	Map    map[int32]float64  `thrift:"8"`
+	List   thrift.List[int16] `thrift:"9"`
+	Set    thrift.Set[string] `thrift:"10"`
+	Struct *aStruct           `thrift:"11"`
+	Named  map[bool]string    `thrift:"12"`
+}
+
+var (
+	aValue = &aStruct{
+		Bool:   true,
+		Byte:   -1,
+		I32:    5,
+		Double: 1.5,
+		String: "\"é\"\n\x01",
+		Bytes:  []byte("hello"),
+		UUID:   [16]byte{0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88, 0x99, 0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0xff},
+		Map:    map[int32]float64{1: math.Inf(-1)},
+		List:   thrift.List[int16]{1, 2},
+		Set:    thrift.Set[string]{"a"},
+		Struct: &aStruct{I32: 1},
+		Named:  map[bool]string{true: "yes"},
+	}
+	aJSON = `["call",1,7,{` +
+		`"Bool":true,` +
+		`"Byte":-1,` +
+		`"I32":5,` +
+		`"Double":1.5,` +
+		`"String":"\"é\"\n\u0001",` +
+		`"Bytes":"aGVsbG8=",` +
+		`"UUID":"00112233-4455-6677-8899-aabbccddeeff",` +
+		`"Map":{"1":"-Infinity"},` +
+		`"List":[1,2],` +
+		`"Set":["a"],` +
+		`"Struct":{"I32":1},` +
+		`"Named":{"true":"yes"}` +
+		`}]`
+)
+
+func TestEncoding(t *testing.T) {
+	var b bytes.Buffer
+	w := Protocol.NewWriter(&b)
+	mh := thriftwire.MessageHeader{Name: "call", Type: thriftwire.Call, ID: 7}
+	if err := w.WriteMessageBegin(mh); err != nil {
+		t.Fatal(err)
+	}
+	if err := thrift.Marshal(w, aValue); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteMessageEnd(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.String(); got != aJSON {
+		t.Fatalf("\ngot  %s\nwant %s", got, aJSON)
+	}
+}
+
+func TestDecoding(t *testing.T) {
+	r := Protocol.NewReader(strings.NewReader(aJSON))
+	mh, err := r.ReadMessageBegin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (thriftwire.MessageHeader{Name: "call", Type: thriftwire.Call, ID: 7}); mh != want {
+		t.Fatalf("got %+v, want %+v", mh, want)
+	}
+	var got aStruct
+	if err := thrift.Unmarshal(r, &got); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.ReadMessageEnd(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&got, aValue) {
+		t.Fatalf("got %+v, want %+v", &got, aValue)
+	}
+}
+
+func TestDecodingFixture(t *testing.T) {
+	const in = `{
+		"I32": 5,
+		"3": 6,
+		"Unknown": {"a": [1, 2.5, 1e100], "b": [{"c": null}], "d": true},
+		"String": null,
+		"Double": "NaN",
+		"Map": {"-2": 3},
+		"Struct": {"Bytes": "aGk"}
+	}`
+	var got aStruct
+	if err := thrift.Unmarshal(Protocol.NewReader(strings.NewReader(in)), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.I32 != 6 {
+		t.Errorf("I32 = %d, want 6", got.I32)
+	}
+	if !math.IsNaN(got.Double) {
+		t.Errorf("Double = %v, want NaN", got.Double)
+	}
+	if want := map[int32]float64{-2: 3}; !reflect.DeepEqual(got.Map, want) {
+		t.Errorf("Map = %v, want %v", got.Map, want)
+	}
+	if got.Struct == nil || string(got.Struct.Bytes) != "hi" {
+		t.Errorf("Struct = %+v, want Bytes %q", got.Struct, "hi")
+	}
+}
+
+func TestDecodingErrors(t *testing.T) {
+	for _, in := range []string{
+		`{"I32":"x"}`,
+		`{"I32":1.5}`,
+		`{"String":1}`,
+		`{"List":{}}`,
+		`{"Struct":[]}`,
+		`{"I32":`,
+		`[]`,
+	} {
+		var v aStruct
+		if err := thrift.Unmarshal(Protocol.NewReader(strings.NewReader(in)), &v); err == nil {
+			t.Errorf("Unmarshal(%s): expected an error", in)
+		}
+	}
+}
+
+func TestStructMapKey(t *testing.T) {
+	var b bytes.Buffer
+	w := Protocol.NewWriter(&b)
+	v := map[*aStruct]bool{{}: true}
+	if err := thrift.Marshal(w, v); err == nil {
+		t.Fatal("expected an error")
+	}
+}
@@ -0,0 +1,164 @@
+package thriftwire
+
+// Copy copies the next value of type t from r to w.
+//
+// The value is copied token by token like [Skip], without knowing its Go
+// type, so that a value can be transcoded between any two protocols.
+// Strings are copied as strings, so binary values encoded as text by a
+// protocol, such as base64 in JSON, are copied in their encoded form.
+func Copy(w Writer, r Reader, t Type) (err error) {
+	switch t {
+	default:
+		return InvalidTypeError(t)
+	case Bool:
+		var v bool
+		if v, err = r.ReadBool(); err == nil {
+			err = w.WriteBool(v)
+		}
+	case Byte:
+		var v byte
+		if v, err = r.ReadByte(); err == nil {
+			err = w.WriteByte(v)
+		}
+	case Double:
+		var v float64
+		if v, err = r.ReadDouble(); err == nil {
+			err = w.WriteDouble(v)
+		}
+	case I16:
+		var v int16
+		if v, err = r.ReadI16(); err == nil {
+			err = w.WriteI16(v)
+		}
+	case I32:
+		var v int32
+		if v, err = r.ReadI32(); err == nil {
+			err = w.WriteI32(v)
+		}
+	case I64:
+		var v int64
+		if v, err = r.ReadI64(); err == nil {
+			err = w.WriteI64(v)
+		}
+	case String:
+		var v string
+		if v, err = r.ReadString(); err == nil {
+			err = w.WriteString(v)
+		}
+	case Struct:
+		h, err := r.ReadStructBegin()
+		if err != nil {
+			return err
+		}
+		if err := w.WriteStructBegin(h); err != nil {
+			return err
+		}
+		for {
+			h, err := r.ReadFieldBegin()
+			if err != nil {
+				return err
+			}
+			if h.Type == Stop {
+				break
+			}
+			if err := w.WriteFieldBegin(h); err != nil {
+				return err
+			}
+			if err := Copy(w, r, h.Type); err != nil {
+				return err
+			}
+			if err := r.ReadFieldEnd(); err != nil {
+				return err
+			}
+			if err := w.WriteFieldEnd(); err != nil {
+				return err
+			}
+		}
+		if err := r.ReadStructEnd(); err != nil {
+			return err
+		}
+		return w.WriteStructEnd()
+	case Map:
+		h, err := r.ReadMapBegin()
+		if err != nil {
+			return err
+		}
+		if err := w.WriteMapBegin(h); err != nil {
+			return err
+		}
+		for i := 0; i < h.Size; i++ {
+			if err := Copy(w, r, h.Key); err != nil {
+				return err
+			}
+			if err := Copy(w, r, h.Value); err != nil {
+				return err
+			}
+		}
+		if err := r.ReadMapEnd(); err != nil {
+			return err
+		}
+		return w.WriteMapEnd()
+	case Set:
+		h, err := r.ReadSetBegin()
+		if err != nil {
+			return err
+		}
+		if err := w.WriteSetBegin(h); err != nil {
+			return err
+		}
+		for i := 0; i < h.Size; i++ {
+			if err := Copy(w, r, h.Element); err != nil {
+				return err
+			}
+		}
+		if err := r.ReadSetEnd(); err != nil {
+			return err
+		}
+		return w.WriteSetEnd()
+	case List:
+		h, err := r.ReadListBegin()
+		if err != nil {
+			return err
+		}
+		if err := w.WriteListBegin(h); err != nil {
+			return err
+		}
+		for i := 0; i < h.Size; i++ {
+			if err := Copy(w, r, h.Element); err != nil {
+				return err
+			}
+		}
+		if err := r.ReadListEnd(); err != nil {
+			return err
+		}
+		return w.WriteListEnd()
+	case UUID:
+		var v [16]byte
+		if err = r.ReadUUID(&v); err == nil {
+			err = w.WriteUUID(&v)
+		}
+	}
+	return err
+}
+
+// CopyMessage copies the next message from r to w with [Copy],
+// and then flushes w.
+func CopyMessage(w Writer, r Reader) error {
+	h, err := r.ReadMessageBegin()
+	if err != nil {
+		return err
+	}
+	if err := w.WriteMessageBegin(h); err != nil {
+		return err
+	}
+	if err := Copy(w, r, Struct); err != nil {
+		return err
+	}
+	if err := r.ReadMessageEnd(); err != nil {
+		return err
+	}
+	if err := w.WriteMessageEnd(); err != nil {
+		return err
+	}
+	return w.Flush()
+}
@@ -0,0 +1,81 @@
+package thriftwire_test
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/itstarsun/go-thrift/encoding/thriftbinary"
+	"github.com/itstarsun/go-thrift/encoding/thriftcompact"
+	"github.com/itstarsun/go-thrift/encoding/thriftjson"
+	"github.com/itstarsun/go-thrift/encoding/thriftwire"
+	"github.com/itstarsun/go-thrift/thrift"
+)
+
+type copyInner struct {
+	Name string `thrift:"1"`
+}
+
+type copyStruct struct {
+	Bool   bool               `thrift:"1"`
+	Byte   int8               `thrift:"2"`
+	I16    int16              `thrift:"3"`
+	I32    int32              `thrift:"4"`
+	I64    int64              `thrift:"5"`
+	Double float64            `thrift:"6"`
+	String string             `thrift:"7"`
+	Map    map[string]int32   `thrift:"8"`
+	List   []copyInner        `thrift:"9"`
+	Nested map[int16][]string `thrift:"10"`
+	Inner  *copyInner         `thrift:"11"`
+}
+
+func TestCopy(t *testing.T) {
+	in := &copyStruct{
+		Bool:   true,
+		Byte:   -1,
+		I16:    1 << 10,
+		I32:    -1 << 20,
+		I64:    1 << 40,
+		Double: 3.5,
+		String: "hello",
+		Map:    map[string]int32{"a": 1},
+		List:   []copyInner{{"x"}, {"y"}},
+		Nested: map[int16][]string{7: {"p", "q"}},
+		Inner:  &copyInner{"z"},
+	}
+	for _, src := range []thriftwire.Protocol{thriftbinary.Protocol, thriftcompact.Protocol, thriftjson.Protocol} {
+		for _, dst := range []thriftwire.Protocol{thriftbinary.Protocol, thriftcompact.Protocol, thriftjson.Protocol} {
+			t.Run(fmt.Sprintf("%v/%v", src, dst), func(t *testing.T) {
+				var b bytes.Buffer
+				w := src.NewWriter(&b)
+				must(t, w.WriteMessageBegin(thriftwire.MessageHeader{Name: "m", Type: thriftwire.Call, ID: 7}))
+				must(t, thrift.Marshal(w, in))
+				must(t, w.WriteMessageEnd())
+				must(t, w.Flush())
+
+				var want bytes.Buffer
+				w = dst.NewWriter(&want)
+				must(t, w.WriteMessageBegin(thriftwire.MessageHeader{Name: "m", Type: thriftwire.Call, ID: 7}))
+				must(t, thrift.Marshal(w, in))
+				must(t, w.WriteMessageEnd())
+				must(t, w.Flush())
+
+				var got bytes.Buffer
+				must(t, thriftwire.CopyMessage(dst.NewWriter(&got), src.NewReader(&b)))
+
+				if !bytes.Equal(got.Bytes(), want.Bytes()) {
+					t.Errorf("got %q, want %q", got.Bytes(), want.Bytes())
+				}
+			})
+		}
+	}
+}
+
+func TestCopyInvalidType(t *testing.T) {
+	var b bytes.Buffer
+	err := thriftwire.Copy(thriftbinary.Protocol.NewWriter(&b), thriftbinary.Protocol.NewReader(&b), thriftwire.Type(99))
+	if _, ok := err.(thriftwire.InvalidTypeError); !ok {
+		t.Errorf("got %v, want InvalidTypeError", err)
+	}
+}
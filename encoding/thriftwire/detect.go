@@ -0,0 +1,89 @@
+package thriftwire
+
+import (
+	"bufio"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrUnknownProtocol is returned by [DetectProtocol] if the data does not
+// begin with the magic of any registered [Protocol].
+var ErrUnknownProtocol = errors.New("thriftwire: unknown protocol")
+
+// A registeredProtocol is a Protocol registered by RegisterProtocol.
+type registeredProtocol struct {
+	magic string
+	p     Protocol
+}
+
+var (
+	protocolsMu     sync.Mutex
+	atomicProtocols atomic.Value // []registeredProtocol
+)
+
+// RegisterProtocol registers a [Protocol] for use by [DetectProtocol].
+// Magic is the prefix of the messages encoded by the Protocol,
+// where each "?" matches any byte, such as a frame size.
+// RegisterProtocol is typically called by the init function of the package
+// implementing the Protocol.
+func RegisterProtocol(magic string, p Protocol) {
+	protocolsMu.Lock()
+	defer protocolsMu.Unlock()
+	protocols, _ := atomicProtocols.Load().([]registeredProtocol)
+	atomicProtocols.Store(append(protocols[:len(protocols):len(protocols)], registeredProtocol{magic, p}))
+}
+
+// DetectProtocol peeks at the first bytes of the next message from r without
+// consuming them, and returns the registered [Protocol] whose magic matches
+// them, preferring the longest magic. The returned Protocol can then read
+// the message from r. DetectProtocol peeks no more bytes than needed to rule
+// out longer magics, so it does not wait for the bytes of a short message
+// whose magic is short.
+//
+// The protocols of this module register themselves when their packages are
+// imported: the strict binary, compact and JSON protocols, as well as the
+// framed binary and compact protocols, and THeader.
+// If no magic matches, DetectProtocol returns [ErrUnknownProtocol], or the
+// error of r if there are not enough bytes to detect the Protocol.
+func DetectProtocol(r *bufio.Reader) (Protocol, error) {
+	protocols, _ := atomicProtocols.Load().([]registeredProtocol)
+	var found *registeredProtocol
+	for n := 1; ; {
+		b, err := r.Peek(n)
+		next := 0 // length of the shortest magic that may still match
+		for i, rp := range protocols {
+			switch {
+			case len(rp.magic) > len(b):
+				if match(rp.magic[:len(b)], b) && (next == 0 || len(rp.magic) < next) {
+					next = len(rp.magic)
+				}
+			case match(rp.magic, b) && (found == nil || len(rp.magic) > len(found.magic)):
+				found = &protocols[i]
+			}
+		}
+		switch {
+		case next != 0 && err == nil:
+			n = next
+			continue
+		case found != nil:
+			return found.p, nil
+		case err != nil:
+			return nil, err
+		}
+		return nil, ErrUnknownProtocol
+	}
+}
+
+// match reports whether b begins with magic.
+func match(magic string, b []byte) bool {
+	if len(magic) > len(b) {
+		return false
+	}
+	for i := 0; i < len(magic); i++ {
+		if magic[i] != '?' && magic[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,94 @@
+package thriftwire_test
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/itstarsun/go-thrift/encoding/thriftbinary"
+	"github.com/itstarsun/go-thrift/encoding/thriftcompact"
+	"github.com/itstarsun/go-thrift/encoding/thriftframed"
+	"github.com/itstarsun/go-thrift/encoding/thriftheader"
+	"github.com/itstarsun/go-thrift/encoding/thriftjson"
+	"github.com/itstarsun/go-thrift/encoding/thriftsimplejson"
+	"github.com/itstarsun/go-thrift/encoding/thriftwire"
+)
+
+func TestDetectProtocol(t *testing.T) {
+	for _, p := range []thriftwire.Protocol{
+		thriftbinary.Protocol,
+		thriftcompact.Protocol,
+		thriftjson.Protocol,
+		thriftsimplejson.Protocol,
+		&thriftframed.Protocol{Protocol: thriftbinary.Protocol},
+		&thriftframed.Protocol{Protocol: thriftcompact.Protocol},
+		&thriftheader.Protocol{ProtocolID: thriftheader.CompactProtocol},
+	} {
+		t.Run(fmt.Sprint(p), func(t *testing.T) {
+			mh := thriftwire.MessageHeader{Name: "ping", Type: thriftwire.Call, ID: 1}
+			var b bytes.Buffer
+			w := p.NewWriter(&b)
+			must(t, w.WriteMessageBegin(mh))
+			must(t, w.WriteStructBegin(thriftwire.StructHeader{}))
+			must(t, w.WriteStructEnd())
+			must(t, w.WriteMessageEnd())
+			must(t, w.Flush())
+
+			br := bufio.NewReader(&b)
+			got, err := thriftwire.DetectProtocol(br)
+			must(t, err)
+			r := got.NewReader(br)
+			h, err := r.ReadMessageBegin()
+			must(t, err)
+			if h != mh {
+				t.Fatalf("detected %v, got %+v, want %+v", got, h, mh)
+			}
+			must(t, thriftwire.Skip(r, thriftwire.Struct))
+			must(t, r.ReadMessageEnd())
+		})
+	}
+}
+
+func TestDetectProtocolShortMessage(t *testing.T) {
+	// A one-way call with an empty name and no args, which is shorter
+	// than the longest registered magic.
+	var b bytes.Buffer
+	w := thriftcompact.Protocol.NewWriter(&b)
+	must(t, w.WriteMessageBegin(thriftwire.MessageHeader{Type: thriftwire.OneWay}))
+	must(t, w.WriteStructBegin(thriftwire.StructHeader{}))
+	must(t, w.WriteStructEnd())
+	must(t, w.WriteMessageEnd())
+	must(t, w.Flush())
+
+	// The connection stays open after the message.
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	go pw.Write(b.Bytes())
+
+	done := make(chan thriftwire.Protocol, 1)
+	go func() {
+		p, _ := thriftwire.DetectProtocol(bufio.NewReader(pr))
+		done <- p
+	}()
+	select {
+	case p := <-done:
+		if p != thriftcompact.Protocol {
+			t.Errorf("detected %v, want %v", p, thriftcompact.Protocol)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("DetectProtocol blocked on a message of %d bytes", b.Len())
+	}
+}
+
+func TestDetectProtocolError(t *testing.T) {
+	if _, err := thriftwire.DetectProtocol(bufio.NewReader(strings.NewReader("\x00\x00\x00\x10\x12\x34"))); err != thriftwire.ErrUnknownProtocol {
+		t.Errorf("got %v, want %v", err, thriftwire.ErrUnknownProtocol)
+	}
+	if _, err := thriftwire.DetectProtocol(bufio.NewReader(strings.NewReader(""))); err != io.EOF {
+		t.Errorf("got %v, want %v", err, io.EOF)
+	}
+}
@@ -29,6 +29,26 @@ func (p joinedProtocol) String() string {
 	return fmt.Sprintf("thriftwire.JoinProtocol(%v, %v)", p.r, p.w)
 }
 
+// DuplexProtocol is the interface implemented by a [Protocol] whose [Reader]
+// and [Writer] for the same connection share state, such as a Writer that
+// replies in the format detected by the Reader.
+//
+// NewReadWriter returns a new Reader that reads from the given [io.ReadWriter],
+// and a new Writer that writes to it.
+type DuplexProtocol interface {
+	Protocol
+	NewReadWriter(io.ReadWriter) (Reader, Writer)
+}
+
+// NewReadWriter returns a new [Reader] and [Writer] of p for the connection rw.
+// It calls the NewReadWriter method of p if it implements [DuplexProtocol].
+func NewReadWriter(p Protocol, rw io.ReadWriter) (Reader, Writer) {
+	if p, ok := p.(DuplexProtocol); ok {
+		return p.NewReadWriter(rw)
+	}
+	return p.NewReader(rw), p.NewWriter(rw)
+}
+
 // Reader is the interface that defines methods for reading Thrift values.
 //
 // ReadBytes reads the next string, appends it to the given buffer,
@@ -63,6 +83,23 @@ type Reader interface {
 	Reset(io.Reader)
 }
 
+// NamedFieldReader is the interface implemented by a [Reader] of a protocol
+// that identifies struct fields by name rather than by ID, and does not
+// encode the exact types of values, such as a simple JSON protocol.
+//
+// ReadFieldBegin returns a FieldHeader with the Name of the field, or with
+// only the ID if the field is identified by a number. The types in the
+// headers describe the shapes of the encoded values, which is enough for
+// [Skip]. Callers that know the schema of a value, such as the unmarshaler
+// of the thrift package, call the read methods of the types they expect
+// instead, and the Reader converts the encoded values to those types.
+//
+// NamedFields is a marker method that does nothing.
+type NamedFieldReader interface {
+	Reader
+	NamedFields()
+}
+
 // Writer is the interface that defines methods for writing Thrift values.
 //
 // Flush writes any buffered data to the underlying [io.Writer].
@@ -0,0 +1,572 @@
+package thriftwire
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// A Value is a Thrift value of any [Type], for tools that handle values
+// without knowing their Go types. Values are read with [ReadValue],
+// written with [WriteValue], and created with the functions named after
+// their types, such as [I32Value] and [StructValue].
+//
+// The zero Value has type Stop, and represents no value.
+// The methods returning the content of a Value panic if it has another type.
+type Value struct {
+	typ Type
+	num uint64 // Bool, Byte, I16, I32, I64 and Double, or the types of the elements
+	any any    // string, [16]byte, []FieldValue, []MapEntry or []Value
+}
+
+// A FieldValue is a field of a struct [Value].
+type FieldValue struct {
+	Name  string // may be empty
+	ID    int16
+	Value Value
+}
+
+// A MapEntry is an entry of a map [Value].
+type MapEntry struct {
+	Key, Value Value
+}
+
+// BoolValue returns a [Value] of type Bool.
+func BoolValue(v bool) Value {
+	var n uint64
+	if v {
+		n = 1
+	}
+	return Value{typ: Bool, num: n}
+}
+
+// ByteValue returns a [Value] of type Byte.
+func ByteValue(v byte) Value {
+	return Value{typ: Byte, num: uint64(v)}
+}
+
+// I16Value returns a [Value] of type I16.
+func I16Value(v int16) Value {
+	return Value{typ: I16, num: uint64(v)}
+}
+
+// I32Value returns a [Value] of type I32.
+func I32Value(v int32) Value {
+	return Value{typ: I32, num: uint64(v)}
+}
+
+// I64Value returns a [Value] of type I64.
+func I64Value(v int64) Value {
+	return Value{typ: I64, num: uint64(v)}
+}
+
+// DoubleValue returns a [Value] of type Double.
+func DoubleValue(v float64) Value {
+	return Value{typ: Double, num: math.Float64bits(v)}
+}
+
+// StringValue returns a [Value] of type String.
+func StringValue(v string) Value {
+	return Value{typ: String, any: v}
+}
+
+// BinaryValue returns a [Value] of type String holding a copy of v.
+func BinaryValue(v []byte) Value {
+	return Value{typ: String, any: string(v)}
+}
+
+// UUIDValue returns a [Value] of type UUID.
+func UUIDValue(v [16]byte) Value {
+	return Value{typ: UUID, any: v}
+}
+
+// StructValue returns a [Value] of type Struct with the given fields,
+// in the order they are written.
+func StructValue(fields ...FieldValue) Value {
+	return Value{typ: Struct, any: fields}
+}
+
+// MapValue returns a [Value] of type Map whose keys and values
+// are of the given types.
+func MapValue(key, value Type, entries ...MapEntry) Value {
+	return Value{typ: Map, num: uint64(key)<<8 | uint64(value), any: entries}
+}
+
+// SetValue returns a [Value] of type Set whose elements are of type elem.
+func SetValue(elem Type, elems ...Value) Value {
+	return Value{typ: Set, num: uint64(elem), any: elems}
+}
+
+// ListValue returns a [Value] of type List whose elements are of type elem.
+func ListValue(elem Type, elems ...Value) Value {
+	return Value{typ: List, num: uint64(elem), any: elems}
+}
+
+// Type returns the type of v.
+func (v Value) Type() Type {
+	return v.typ
+}
+
+func (v Value) mustBe(method string, types ...Type) {
+	for _, t := range types {
+		if v.typ == t {
+			return
+		}
+	}
+	panic("thriftwire: Value." + method + " of type " + v.typ.String())
+}
+
+// Bool returns the content of a Bool value.
+func (v Value) Bool() bool {
+	v.mustBe("Bool", Bool)
+	return v.num != 0
+}
+
+// Byte returns the content of a Byte value.
+func (v Value) Byte() byte {
+	v.mustBe("Byte", Byte)
+	return byte(v.num)
+}
+
+// I16 returns the content of an I16 value.
+func (v Value) I16() int16 {
+	v.mustBe("I16", I16)
+	return int16(v.num)
+}
+
+// I32 returns the content of an I32 value.
+func (v Value) I32() int32 {
+	v.mustBe("I32", I32)
+	return int32(v.num)
+}
+
+// I64 returns the content of an I64 value.
+func (v Value) I64() int64 {
+	v.mustBe("I64", I64)
+	return int64(v.num)
+}
+
+// Double returns the content of a Double value.
+func (v Value) Double() float64 {
+	v.mustBe("Double", Double)
+	return math.Float64frombits(v.num)
+}
+
+// Text returns the content of a String value as a string.
+func (v Value) Text() string {
+	v.mustBe("Text", String)
+	return v.any.(string)
+}
+
+// Binary returns a copy of the content of a String value.
+func (v Value) Binary() []byte {
+	v.mustBe("Binary", String)
+	return []byte(v.any.(string))
+}
+
+// UUID returns the content of a UUID value.
+func (v Value) UUID() [16]byte {
+	v.mustBe("UUID", UUID)
+	return v.any.([16]byte)
+}
+
+// Fields returns the fields of a Struct value.
+func (v Value) Fields() []FieldValue {
+	v.mustBe("Fields", Struct)
+	fields, _ := v.any.([]FieldValue)
+	return fields
+}
+
+// Field returns the value of the first field of a Struct value
+// with the given ID, and reports whether it exists.
+func (v Value) Field(id int16) (Value, bool) {
+	for _, f := range v.Fields() {
+		if f.ID == id {
+			return f.Value, true
+		}
+	}
+	return Value{}, false
+}
+
+// Entries returns the entries of a Map value.
+func (v Value) Entries() []MapEntry {
+	v.mustBe("Entries", Map)
+	entries, _ := v.any.([]MapEntry)
+	return entries
+}
+
+// Elems returns the elements of a Set or List value.
+func (v Value) Elems() []Value {
+	v.mustBe("Elems", Set, List)
+	elems, _ := v.any.([]Value)
+	return elems
+}
+
+// KeyType returns the type of the keys of a Map value.
+func (v Value) KeyType() Type {
+	v.mustBe("KeyType", Map)
+	return Type(v.num >> 8)
+}
+
+// ElemType returns the type of the values of a Map value,
+// or the type of the elements of a Set or List value.
+func (v Value) ElemType() Type {
+	v.mustBe("ElemType", Map, Set, List)
+	return Type(v.num)
+}
+
+// ReadValue reads the next value of type t.
+func ReadValue(r Reader, t Type) (v Value, err error) {
+	switch t {
+	default:
+		return Value{}, InvalidTypeError(t)
+	case Bool:
+		var x bool
+		x, err = r.ReadBool()
+		v = BoolValue(x)
+	case Byte:
+		var x byte
+		x, err = r.ReadByte()
+		v = ByteValue(x)
+	case Double:
+		var x float64
+		x, err = r.ReadDouble()
+		v = DoubleValue(x)
+	case I16:
+		var x int16
+		x, err = r.ReadI16()
+		v = I16Value(x)
+	case I32:
+		var x int32
+		x, err = r.ReadI32()
+		v = I32Value(x)
+	case I64:
+		var x int64
+		x, err = r.ReadI64()
+		v = I64Value(x)
+	case String:
+		var x string
+		x, err = r.ReadString()
+		v = StringValue(x)
+	case Struct:
+		if _, err := r.ReadStructBegin(); err != nil {
+			return Value{}, err
+		}
+		var fields []FieldValue
+		for {
+			h, err := r.ReadFieldBegin()
+			if err != nil {
+				return Value{}, err
+			}
+			if h.Type == Stop {
+				break
+			}
+			fv, err := ReadValue(r, h.Type)
+			if err != nil {
+				return Value{}, err
+			}
+			if err := r.ReadFieldEnd(); err != nil {
+				return Value{}, err
+			}
+			fields = append(fields, FieldValue{Name: h.Name, ID: h.ID, Value: fv})
+		}
+		if err := r.ReadStructEnd(); err != nil {
+			return Value{}, err
+		}
+		return StructValue(fields...), nil
+	case Map:
+		h, err := r.ReadMapBegin()
+		if err != nil {
+			return Value{}, err
+		}
+		var entries []MapEntry
+		for i := 0; i < h.Size; i++ {
+			k, err := ReadValue(r, h.Key)
+			if err != nil {
+				return Value{}, err
+			}
+			e, err := ReadValue(r, h.Value)
+			if err != nil {
+				return Value{}, err
+			}
+			entries = append(entries, MapEntry{k, e})
+		}
+		if err := r.ReadMapEnd(); err != nil {
+			return Value{}, err
+		}
+		return MapValue(h.Key, h.Value, entries...), nil
+	case Set:
+		h, err := r.ReadSetBegin()
+		if err != nil {
+			return Value{}, err
+		}
+		elems, err := readElems(r, h.Element, h.Size)
+		if err != nil {
+			return Value{}, err
+		}
+		if err := r.ReadSetEnd(); err != nil {
+			return Value{}, err
+		}
+		return SetValue(h.Element, elems...), nil
+	case List:
+		h, err := r.ReadListBegin()
+		if err != nil {
+			return Value{}, err
+		}
+		elems, err := readElems(r, h.Element, h.Size)
+		if err != nil {
+			return Value{}, err
+		}
+		if err := r.ReadListEnd(); err != nil {
+			return Value{}, err
+		}
+		return ListValue(h.Element, elems...), nil
+	case UUID:
+		var x [16]byte
+		err = r.ReadUUID(&x)
+		v = UUIDValue(x)
+	}
+	if err != nil {
+		return Value{}, err
+	}
+	return v, nil
+}
+
+func readElems(r Reader, t Type, n int) ([]Value, error) {
+	var elems []Value
+	for i := 0; i < n; i++ {
+		e, err := ReadValue(r, t)
+		if err != nil {
+			return nil, err
+		}
+		elems = append(elems, e)
+	}
+	return elems, nil
+}
+
+// WriteValue writes v.
+// The elements of maps, sets and lists must be of their declared types.
+func WriteValue(w Writer, v Value) error {
+	switch v.typ {
+	default:
+		return InvalidTypeError(v.typ)
+	case Bool:
+		return w.WriteBool(v.Bool())
+	case Byte:
+		return w.WriteByte(v.Byte())
+	case Double:
+		return w.WriteDouble(v.Double())
+	case I16:
+		return w.WriteI16(v.I16())
+	case I32:
+		return w.WriteI32(v.I32())
+	case I64:
+		return w.WriteI64(v.I64())
+	case String:
+		return w.WriteString(v.Text())
+	case Struct:
+		if err := w.WriteStructBegin(StructHeader{}); err != nil {
+			return err
+		}
+		for _, f := range v.Fields() {
+			if err := w.WriteFieldBegin(FieldHeader{Name: f.Name, Type: f.Value.typ, ID: f.ID}); err != nil {
+				return err
+			}
+			if err := WriteValue(w, f.Value); err != nil {
+				return err
+			}
+			if err := w.WriteFieldEnd(); err != nil {
+				return err
+			}
+		}
+		return w.WriteStructEnd()
+	case Map:
+		entries := v.Entries()
+		h := MapHeader{Key: v.KeyType(), Value: v.ElemType(), Size: len(entries)}
+		if err := w.WriteMapBegin(h); err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if err := writeElem(w, h.Key, e.Key); err != nil {
+				return err
+			}
+			if err := writeElem(w, h.Value, e.Value); err != nil {
+				return err
+			}
+		}
+		return w.WriteMapEnd()
+	case Set:
+		elems := v.Elems()
+		if err := w.WriteSetBegin(SetHeader{Element: v.ElemType(), Size: len(elems)}); err != nil {
+			return err
+		}
+		if err := writeElems(w, v.ElemType(), elems); err != nil {
+			return err
+		}
+		return w.WriteSetEnd()
+	case List:
+		elems := v.Elems()
+		if err := w.WriteListBegin(ListHeader{Element: v.ElemType(), Size: len(elems)}); err != nil {
+			return err
+		}
+		if err := writeElems(w, v.ElemType(), elems); err != nil {
+			return err
+		}
+		return w.WriteListEnd()
+	case UUID:
+		x := v.UUID()
+		return w.WriteUUID(&x)
+	}
+}
+
+func writeElems(w Writer, t Type, elems []Value) error {
+	for _, e := range elems {
+		if err := writeElem(w, t, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeElem writes e, which must be of type t.
+func writeElem(w Writer, t Type, e Value) error {
+	if e.typ != t {
+		return fmt.Errorf("thriftwire: %v value in container of %v", e.typ, t)
+	}
+	return WriteValue(w, e)
+}
+
+// String returns v formatted on a single line, like [Value.Indent] with
+// an empty indent.
+func (v Value) String() string {
+	return v.Indent("")
+}
+
+// Indent returns v formatted in a syntax resembling Thrift IDL constants:
+// structs are written as {1: value, ...} with the IDs or names of the
+// fields, maps as map<key,value>{key: value, ...}, sets as set<elem>{...},
+// lists as list<elem>[...], strings are quoted, and UUIDs are hyphenated.
+//
+// If indent is not empty, each field and element begins on a new line,
+// indented by one more copy of indent than its container.
+func (v Value) Indent(indent string) string {
+	var b []byte
+	b = appendValue(b, v, indent, 0)
+	return string(b)
+}
+
+func appendValue(b []byte, v Value, indent string, depth int) []byte {
+	switch v.typ {
+	default:
+		return append(b, "<invalid>"...)
+	case Bool:
+		return strconv.AppendBool(b, v.Bool())
+	case Byte:
+		return strconv.AppendInt(b, int64(int8(v.Byte())), 10)
+	case Double:
+		return strconv.AppendFloat(b, v.Double(), 'g', -1, 64)
+	case I16, I32, I64:
+		return strconv.AppendInt(b, int64(v.num), 10)
+	case String:
+		return strconv.AppendQuote(b, v.Text())
+	case UUID:
+		return appendUUID(b, v.UUID())
+	case Struct:
+		fields := v.Fields()
+		b = append(b, '{')
+		for i, f := range fields {
+			b = appendSep(b, i, indent, depth+1)
+			switch {
+			case f.Name == "":
+				b = strconv.AppendInt(b, int64(f.ID), 10)
+			case f.ID == 0:
+				b = append(b, f.Name...)
+			default:
+				b = strconv.AppendInt(b, int64(f.ID), 10)
+				b = append(b, ' ')
+				b = append(b, f.Name...)
+			}
+			b = append(b, ": "...)
+			b = appendValue(b, f.Value, indent, depth+1)
+		}
+		b = appendEnd(b, len(fields), indent, depth)
+		return append(b, '}')
+	case Map:
+		entries := v.Entries()
+		b = append(b, typeName(Map)...)
+		b = append(b, '<')
+		b = append(b, typeName(v.KeyType())...)
+		b = append(b, ',')
+		b = append(b, typeName(v.ElemType())...)
+		b = append(b, ">{"...)
+		for i, e := range entries {
+			b = appendSep(b, i, indent, depth+1)
+			b = appendValue(b, e.Key, indent, depth+1)
+			b = append(b, ": "...)
+			b = appendValue(b, e.Value, indent, depth+1)
+		}
+		b = appendEnd(b, len(entries), indent, depth)
+		return append(b, '}')
+	case Set, List:
+		open, close := byte('{'), byte('}')
+		if v.typ == List {
+			open, close = '[', ']'
+		}
+		elems := v.Elems()
+		b = append(b, typeName(v.typ)...)
+		b = append(b, '<')
+		b = append(b, typeName(v.ElemType())...)
+		b = append(b, '>', open)
+		for i, e := range elems {
+			b = appendSep(b, i, indent, depth+1)
+			b = appendValue(b, e, indent, depth+1)
+		}
+		b = appendEnd(b, len(elems), indent, depth)
+		return append(b, close)
+	}
+}
+
+// appendSep appends the separator before the i-th element of a container.
+func appendSep(b []byte, i int, indent string, depth int) []byte {
+	if i > 0 {
+		b = append(b, ',')
+		if indent == "" {
+			b = append(b, ' ')
+		}
+	}
+	if indent != "" {
+		b = append(b, '\n')
+		b = append(b, strings.Repeat(indent, depth)...)
+	}
+	return b
+}
+
+// appendEnd appends the separator before the end of a container of n elements.
+func appendEnd(b []byte, n int, indent string, depth int) []byte {
+	if indent != "" && n > 0 {
+		b = append(b, ",\n"...)
+		b = append(b, strings.Repeat(indent, depth)...)
+	}
+	return b
+}
+
+// typeName returns the name of t in Thrift IDL.
+func typeName(t Type) string {
+	return strings.ToLower(t.String())
+}
+
+func appendUUID(b []byte, u [16]byte) []byte {
+	var s [36]byte
+	hex.Encode(s[0:8], u[0:4])
+	s[8] = '-'
+	hex.Encode(s[9:13], u[4:6])
+	s[13] = '-'
+	hex.Encode(s[14:18], u[6:8])
+	s[18] = '-'
+	hex.Encode(s[19:23], u[8:10])
+	s[23] = '-'
+	hex.Encode(s[24:], u[10:])
+	return append(b, s[:]...)
+}
@@ -0,0 +1,109 @@
+package thriftwire_test
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/itstarsun/go-thrift/encoding/thriftbinary"
+	"github.com/itstarsun/go-thrift/encoding/thriftcompact"
+	"github.com/itstarsun/go-thrift/encoding/thriftjson"
+	"github.com/itstarsun/go-thrift/encoding/thriftwire"
+)
+
+var testValue = thriftwire.StructValue(
+	thriftwire.FieldValue{ID: 1, Value: thriftwire.BoolValue(true)},
+	thriftwire.FieldValue{ID: 2, Value: thriftwire.ByteValue(0xff)},
+	thriftwire.FieldValue{ID: 3, Value: thriftwire.I16Value(-2)},
+	thriftwire.FieldValue{ID: 4, Value: thriftwire.I32Value(1 << 20)},
+	thriftwire.FieldValue{ID: 5, Value: thriftwire.I64Value(-1 << 40)},
+	thriftwire.FieldValue{ID: 6, Value: thriftwire.DoubleValue(0.5)},
+	thriftwire.FieldValue{ID: 7, Value: thriftwire.StringValue("hi\n")},
+	thriftwire.FieldValue{ID: 8, Value: thriftwire.UUIDValue([16]byte{0: 0x01, 15: 0xff})},
+	thriftwire.FieldValue{ID: 9, Value: thriftwire.MapValue(thriftwire.String, thriftwire.I32,
+		thriftwire.MapEntry{Key: thriftwire.StringValue("a"), Value: thriftwire.I32Value(1)},
+		thriftwire.MapEntry{Key: thriftwire.StringValue("b"), Value: thriftwire.I32Value(2)},
+	)},
+	thriftwire.FieldValue{ID: 10, Value: thriftwire.SetValue(thriftwire.I16, thriftwire.I16Value(3))},
+	thriftwire.FieldValue{ID: 11, Value: thriftwire.ListValue(thriftwire.Struct,
+		thriftwire.StructValue(thriftwire.FieldValue{ID: 1, Value: thriftwire.StringValue("x")}),
+		thriftwire.StructValue(),
+	)},
+	thriftwire.FieldValue{ID: 12, Value: thriftwire.ListValue(thriftwire.I32)},
+)
+
+func TestValue(t *testing.T) {
+	for _, p := range []thriftwire.Protocol{thriftbinary.Protocol, thriftcompact.Protocol, thriftjson.Protocol} {
+		t.Run(fmt.Sprint(p), func(t *testing.T) {
+			var b bytes.Buffer
+			w := p.NewWriter(&b)
+			must(t, thriftwire.WriteValue(w, testValue))
+			must(t, w.Flush())
+			got, err := thriftwire.ReadValue(p.NewReader(&b), thriftwire.Struct)
+			must(t, err)
+			if got.String() != testValue.String() {
+				t.Errorf("got %v, want %v", got, testValue)
+			}
+			if b.Len() != 0 {
+				t.Errorf("%d bytes left", b.Len())
+			}
+		})
+	}
+}
+
+func TestValueString(t *testing.T) {
+	const want = `{1: true, 2: -1, 3: -2, 4: 1048576, 5: -1099511627776, 6: 0.5, 7: "hi\n", 8: 01000000-0000-0000-0000-0000000000ff, 9: map<string,i32>{"a": 1, "b": 2}, 10: set<i16>{3}, 11: list<struct>[{1: "x"}, {}], 12: list<i32>[]}`
+	if got := testValue.String(); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	v := thriftwire.StructValue(
+		thriftwire.FieldValue{Name: "name", Value: thriftwire.StringValue("x")},
+		thriftwire.FieldValue{Name: "list", ID: 2, Value: thriftwire.ListValue(thriftwire.I32, thriftwire.I32Value(1), thriftwire.I32Value(2))},
+		thriftwire.FieldValue{ID: 3, Value: thriftwire.MapValue(thriftwire.Stop, thriftwire.Stop)},
+	)
+	const wantIndent = `{
+  name: "x",
+  2 list: list<i32>[
+    1,
+    2,
+  ],
+  3: map<stop,stop>{},
+}`
+	if got := v.Indent("  "); got != wantIndent {
+		t.Errorf("got %s, want %s", got, wantIndent)
+	}
+}
+
+func TestValueAccessors(t *testing.T) {
+	if got, ok := testValue.Field(7); !ok || got.Text() != "hi\n" {
+		t.Errorf("Field(7) = %v, %v", got, ok)
+	}
+	if _, ok := testValue.Field(13); ok {
+		t.Errorf("Field(13) exists")
+	}
+	m, _ := testValue.Field(9)
+	if m.KeyType() != thriftwire.String || m.ElemType() != thriftwire.I32 || len(m.Entries()) != 2 {
+		t.Errorf("map = %v", m)
+	}
+	if got := thriftwire.BinaryValue([]byte{1, 2}).Binary(); !reflect.DeepEqual(got, []byte{1, 2}) {
+		t.Errorf("Binary() = %v", got)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("I32 of a Bool value did not panic")
+		}
+	}()
+	thriftwire.BoolValue(true).I32()
+}
+
+func TestWriteValueErrors(t *testing.T) {
+	w := thriftbinary.Protocol.NewWriter(new(bytes.Buffer))
+	if err := thriftwire.WriteValue(w, thriftwire.Value{}); err == nil {
+		t.Errorf("wrote the zero Value")
+	}
+	if err := thriftwire.WriteValue(w, thriftwire.ListValue(thriftwire.I32, thriftwire.I64Value(1))); err == nil {
+		t.Errorf("wrote a list with mismatched element")
+	}
+}
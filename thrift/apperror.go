@@ -0,0 +1,90 @@
+package thrift
+
+import "fmt"
+
+// An ApplicationError is the standard Thrift application exception,
+// which is the body of an exception message. It reports that a call
+// failed at the protocol level rather than with an exception declared
+// by the method.
+//
+// An ApplicationError matches the sentinel errors such as
+// [ErrUnknownMethod] of the same type with [errors.Is].
+type ApplicationError struct {
+	Message string               `thrift:"1"`
+	Type    ApplicationErrorType `thrift:"2"`
+}
+
+func (e *ApplicationError) Error() string {
+	if e.Message == "" {
+		return errorPrefix + e.Type.String()
+	}
+	return errorPrefix + e.Type.String() + ": " + e.Message
+}
+
+// Is reports whether target is an *ApplicationError without a message
+// of the same type as e, such as the sentinel errors.
+func (e *ApplicationError) Is(target error) bool {
+	t, ok := target.(*ApplicationError)
+	return ok && t.Message == "" && t.Type == e.Type
+}
+
+// An ApplicationErrorType is the type of an [ApplicationError].
+type ApplicationErrorType int32
+
+// Types of application errors.
+const (
+	UnknownApplicationError ApplicationErrorType = 0
+	UnknownMethod           ApplicationErrorType = 1
+	InvalidMessageType      ApplicationErrorType = 2
+	WrongMethodName         ApplicationErrorType = 3
+	BadSequenceID           ApplicationErrorType = 4
+	MissingResult           ApplicationErrorType = 5
+	InternalError           ApplicationErrorType = 6
+	ProtocolError           ApplicationErrorType = 7
+	InvalidTransform        ApplicationErrorType = 8
+	InvalidProtocol         ApplicationErrorType = 9
+	UnsupportedClientType   ApplicationErrorType = 10
+)
+
+// Sentinel errors matching an [ApplicationError] of each type with [errors.Is].
+var (
+	ErrUnknownApplicationError = &ApplicationError{Type: UnknownApplicationError}
+	ErrUnknownMethod           = &ApplicationError{Type: UnknownMethod}
+	ErrInvalidMessageType      = &ApplicationError{Type: InvalidMessageType}
+	ErrWrongMethodName         = &ApplicationError{Type: WrongMethodName}
+	ErrBadSequenceID           = &ApplicationError{Type: BadSequenceID}
+	ErrMissingResult           = &ApplicationError{Type: MissingResult}
+	ErrInternalError           = &ApplicationError{Type: InternalError}
+	ErrProtocolError           = &ApplicationError{Type: ProtocolError}
+	ErrInvalidTransform        = &ApplicationError{Type: InvalidTransform}
+	ErrInvalidProtocol         = &ApplicationError{Type: InvalidProtocol}
+	ErrUnsupportedClientType   = &ApplicationError{Type: UnsupportedClientType}
+)
+
+func (t ApplicationErrorType) String() string {
+	switch t {
+	case UnknownApplicationError:
+		return "unknown application error"
+	case UnknownMethod:
+		return "unknown method"
+	case InvalidMessageType:
+		return "invalid message type"
+	case WrongMethodName:
+		return "wrong method name"
+	case BadSequenceID:
+		return "bad sequence ID"
+	case MissingResult:
+		return "missing result"
+	case InternalError:
+		return "internal error"
+	case ProtocolError:
+		return "protocol error"
+	case InvalidTransform:
+		return "invalid transform"
+	case InvalidProtocol:
+		return "invalid protocol"
+	case UnsupportedClientType:
+		return "unsupported client type"
+	}
+	return fmt.Sprintf("ApplicationErrorType(%d)", int32(t))
+}
@@ -0,0 +1,60 @@
+package thrift
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/itstarsun/go-thrift/internal/thriftmemo"
+)
+
+func TestApplicationError(t *testing.T) {
+	err := fmt.Errorf("call failed: %w", &ApplicationError{Message: "no such method", Type: UnknownMethod})
+	if !errors.Is(err, ErrUnknownMethod) {
+		t.Errorf("errors.Is(%v, ErrUnknownMethod) = false", err)
+	}
+	if errors.Is(err, ErrInternalError) {
+		t.Errorf("errors.Is(%v, ErrInternalError) = true", err)
+	}
+	if errors.Is(ErrUnknownMethod, &ApplicationError{Message: "no such method", Type: UnknownMethod}) {
+		t.Errorf("a sentinel matches an error with a message")
+	}
+
+	for _, tt := range []struct {
+		err  *ApplicationError
+		want string
+	}{
+		{&ApplicationError{Message: "no such method", Type: UnknownMethod}, "thrift: unknown method: no such method"},
+		{ErrMissingResult, "thrift: missing result"},
+		{&ApplicationError{Type: 100}, "thrift: ApplicationErrorType(100)"},
+	} {
+		if got := tt.err.Error(); got != tt.want {
+			t.Errorf("Error() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestApplicationErrorArshal(t *testing.T) {
+	in := &ApplicationError{Message: "oops", Type: InternalError}
+	var m thriftmemo.Memo
+	if err := Marshal(m.Writer(), in); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"StructBegin",
+		"FieldBegin", "String", "FieldEnd",
+		"FieldBegin", "I32", "FieldEnd",
+		"FieldBegin", "StructEnd",
+	}
+	if got := m.Steps(); !slices.Equal(got, want) {
+		t.Fatalf("\ngot  %v\nwant %v", got, want)
+	}
+	var out ApplicationError
+	if err := Unmarshal(m.Reader(), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out != *in {
+		t.Fatalf("got %+v, want %+v", out, *in)
+	}
+}
@@ -11,6 +11,9 @@ import (
 type marshalOptions struct{}
 
 // Marshal serializes a Go value into a [thriftwire.Writer].
+//
+// If a Go type implements [Marshaler], its MarshalThrift method
+// is used instead of reflection.
 func Marshal(out thriftwire.Writer, in any) error {
 	return marshalOptions{}.Marshal(out, in)
 }
@@ -37,11 +40,38 @@ func (mo marshalOptions) Marshal(out thriftwire.Writer, in any) error {
 	return fncs.marshal(out, va, mo)
 }
 
-type unmarshalOptions struct{}
+// Validator is the interface implemented by types that can check
+// their own invariants.
+//
+// If a Go struct implements Validator, [Marshal] calls Validate before
+// the struct is written, and [Unmarshal] calls Validate after the struct
+// is read. Any error is wrapped in a [SemanticError].
+type Validator interface {
+	Validate() error
+}
+
+type unmarshalOptions struct {
+	// namedFields reports whether the reader identifies struct fields by name,
+	// and the expected types are used instead of the types in the headers.
+	namedFields bool
+}
+
+func makeUnmarshalOptions(r thriftwire.Reader) unmarshalOptions {
+	_, named := r.(thriftwire.NamedFieldReader)
+	return unmarshalOptions{namedFields: named}
+}
 
 // Unmarshal deserializes a Go value from a [thriftwire.Reader].
+//
+// If a Go type implements [Unmarshaler], its UnmarshalThrift method
+// is used instead of reflection.
+//
+// If in is a [thriftwire.NamedFieldReader], the fields of Go structs are
+// matched by their names, or by their IDs if only the IDs are read,
+// and values are read as the Thrift types of the Go types. Go structs are
+// then unmarshaled by reflection even if they implement Unmarshaler.
 func Unmarshal(in thriftwire.Reader, out any) error {
-	return unmarshalOptions{}.Unmarshal(in, out)
+	return makeUnmarshalOptions(in).Unmarshal(in, out)
 }
 
 func (uo unmarshalOptions) Unmarshal(in thriftwire.Reader, out any) error {
@@ -84,6 +114,10 @@ type arshaler struct {
 	wireType  thriftwire.Type
 	marshal   marshaler
 	unmarshal unmarshaler
+
+	// fields returns the fields of a Go struct.
+	// It is nil if the Go type is not a struct.
+	fields func() (*structFields, *SemanticError)
 }
 
 var lookupArshalerCache sync.Map // map[reflect.Type]*arshaler
@@ -94,6 +128,7 @@ func lookupArshaler(t reflect.Type) *arshaler {
 	}
 
 	fncs := makeDefaultArshaler(t)
+	fncs = makeMethodArshaler(fncs, t)
 
 	// Use the last stored so that duplicate arshalers can be garbage collected.
 	v, _ := lookupArshalerCache.LoadOrStore(t, fncs)
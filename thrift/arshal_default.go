@@ -12,6 +12,8 @@ var (
 	listType  = reflect.TypeOf((*interface{ list() })(nil)).Elem()
 	bytesType = reflect.TypeOf((*[]byte)(nil)).Elem()
 	uuidType  = reflect.TypeOf((*[16]byte)(nil)).Elem()
+
+	validatorType = reflect.TypeOf((*Validator)(nil)).Elem()
 )
 
 func makeDefaultArshaler(t reflect.Type) *arshaler {
@@ -265,7 +267,18 @@ func makeStructArshaler(t reflect.Type) *arshaler {
 	init := func() {
 		fields, errInit = makeStructFields(t)
 	}
+	var validate func(addressableValue) error
+	switch {
+	case t.Implements(validatorType):
+		validate = func(va addressableValue) error { return va.Interface().(Validator).Validate() }
+	case reflect.PointerTo(t).Implements(validatorType):
+		validate = func(va addressableValue) error { return va.Addr().Interface().(Validator).Validate() }
+	}
 	fncs.wireType = thriftwire.Struct
+	fncs.fields = func() (*structFields, *SemanticError) {
+		once.Do(init)
+		return &fields, errInit
+	}
 	fncs.marshal = func(w thriftwire.Writer, va addressableValue, mo marshalOptions) error {
 		once.Do(init)
 		if errInit != nil {
@@ -273,6 +286,11 @@ func makeStructArshaler(t reflect.Type) *arshaler {
 			err.action = "marshal"
 			return &err
 		}
+		if validate != nil {
+			if err := validate(va); err != nil {
+				return &SemanticError{action: "marshal", ThriftType: thriftwire.Struct, GoType: t, Err: err}
+			}
+		}
 		err := w.WriteStructBegin(thriftwire.StructHeader{
 			Name: t.Name(),
 		})
@@ -285,15 +303,9 @@ func makeStructArshaler(t reflect.Type) *arshaler {
 			if f.fncs.wireType == thriftwire.Stop {
 				return &SemanticError{action: "marshal", GoType: f.typ}
 			}
-			v := addressableValue{va.Field(f.index[0])} // addressable if struct value is addressable
-			if len(f.index) > 1 {
-				v = v.fieldByIndex(f.index[1:], false)
-				if !v.IsValid() {
-					continue // implies a nil inlined field
-				}
-			}
-			if !f.required && ((f.isZero == nil && v.IsZero()) || (f.isZero != nil && f.isZero(v))) {
-				continue
+			v := va.structField(f, false)
+			if !v.IsValid() || f.omit(v) {
+				continue // implies a nil inlined field or an omitted field
 			}
 			if err := w.WriteFieldBegin(thriftwire.FieldHeader{
 				Name: f.name,
@@ -342,16 +354,16 @@ func makeStructArshaler(t reflect.Type) *arshaler {
 			if h.Type == thriftwire.Stop {
 				break
 			}
-			f, ok := fields.byID[h.ID]
+			f, ok := fields.lookup(h, uo.namedFields)
 			if !ok {
 				if err := thriftwire.Skip(r, h.Type); err != nil {
 					return err
 				}
 			} else {
-				v := addressableValue{va.Field(f.index[0])} // addressable if struct value is addressable
-				if len(f.index) > 1 {
-					v = v.fieldByIndex(f.index[1:], true)
+				if uo.namedFields {
+					h.Type = f.fncs.wireType
 				}
+				v := va.structField(f, true)
 				if err := f.fncs.unmarshal(r, v, uo, h.Type); err != nil {
 					return err
 				}
@@ -366,11 +378,27 @@ func makeStructArshaler(t reflect.Type) *arshaler {
 			err := &wireError{action: "ReadStructEnd", err: err}
 			return &SemanticError{action: "unmarshal", ThriftType: thriftwire.Struct, GoType: t, Err: err}
 		}
+		if validate != nil {
+			if err := validate(va); err != nil {
+				return &SemanticError{action: "unmarshal", ThriftType: thriftwire.Struct, GoType: t, Err: err}
+			}
+		}
 		return nil
 	}
 	return &fncs
 }
 
+// structField returns the value of the struct field f.
+// It returns an invalid value if f is within a nil inlined field
+// and mayAlloc is false.
+func (va addressableValue) structField(f *structField, mayAlloc bool) addressableValue {
+	v := addressableValue{va.Field(f.index[0])} // addressable if struct value is addressable
+	if len(f.index) > 1 {
+		v = v.fieldByIndex(f.index[1:], mayAlloc)
+	}
+	return v
+}
+
 func (va addressableValue) fieldByIndex(index []int, mayAlloc bool) addressableValue {
 	for _, i := range index {
 		va = va.indirect(mayAlloc)
@@ -444,6 +472,9 @@ func makeMapArshaler(t reflect.Type) *arshaler {
 			err := &wireError{action: "ReadMapBegin", err: err}
 			return &SemanticError{action: "unmarshal", ThriftType: thriftwire.Map, GoType: t, Err: err}
 		}
+		if uo.namedFields {
+			h.Key, h.Value = keyFncs.wireType, valFncs.wireType
+		}
 		if h.Size > 0 {
 			if va.IsNil() {
 				va.Set(reflect.MakeMap(t))
@@ -528,6 +559,9 @@ func makeSetListArshaler[H thriftwire.SetHeader | thriftwire.ListHeader](
 			return &SemanticError{action: "unmarshal", ThriftType: wireType, GoType: t, Err: err}
 		}
 		sh := thriftwire.SetHeader(h)
+		if uo.namedFields {
+			sh.Element = valFncs.wireType
+		}
 		if sh.Size > 0 {
 			mustZero := true // we do not know the cleanliness of unused capacity
 			cap := va.Cap()
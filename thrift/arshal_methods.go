@@ -0,0 +1,104 @@
+package thrift
+
+import (
+	"errors"
+	"reflect"
+
+	"github.com/itstarsun/go-thrift/encoding/thriftwire"
+)
+
+// Marshaler is the interface implemented by types that
+// can marshal themselves into a [thriftwire.Writer].
+//
+// MarshalThrift must write exactly one Thrift value
+// of the type that the Go type would be marshaled as by default,
+// such as a struct for a Go struct.
+type Marshaler interface {
+	MarshalThrift(thriftwire.Writer) error
+}
+
+// Unmarshaler is the interface implemented by types that
+// can unmarshal themselves from a [thriftwire.Reader].
+//
+// UnmarshalThrift must read exactly one Thrift value
+// of the type that the Go type would be unmarshaled from by default.
+// Like [Unmarshal], it merges the value into the receiver.
+type Unmarshaler interface {
+	UnmarshalThrift(thriftwire.Reader) error
+}
+
+var (
+	marshalerType   = reflect.TypeOf((*Marshaler)(nil)).Elem()
+	unmarshalerType = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+)
+
+func makeMethodArshaler(fncs *arshaler, t reflect.Type) *arshaler {
+	// Avoid injecting method arshaler on the pointer or interface version
+	// to avoid ever calling the method on a nil pointer or interface receiver.
+	// Let it be injected on the value receiver (which is always addressable).
+	if t.Kind() == reflect.Pointer || t.Kind() == reflect.Interface {
+		return fncs
+	}
+
+	if needAddr, ok := implements(t, marshalerType); ok {
+		wireType := fncs.wireType
+		fncs.marshal = func(w thriftwire.Writer, va addressableValue, mo marshalOptions) error {
+			v, _ := va.addrWhen(needAddr).Interface().(Marshaler)
+			if err := v.MarshalThrift(w); err != nil {
+				return wrapMethodError(err, "marshal", wireType, t)
+			}
+			return nil
+		}
+	}
+
+	if needAddr, ok := implements(t, unmarshalerType); ok {
+		wireType := fncs.wireType
+		unmarshalDefault := fncs.unmarshal
+		fncs.unmarshal = func(r thriftwire.Reader, va addressableValue, uo unmarshalOptions, wt thriftwire.Type) error {
+			if uo.namedFields && t.Kind() == reflect.Struct {
+				// UnmarshalThrift methods, such as the generated ones,
+				// match the fields of structs by ID.
+				return unmarshalDefault(r, va, uo, wt)
+			}
+			if wt != wireType {
+				return &SemanticError{action: "unmarshal", ThriftType: wt, GoType: t}
+			}
+			v, _ := va.addrWhen(needAddr).Interface().(Unmarshaler)
+			if err := v.UnmarshalThrift(r); err != nil {
+				return wrapMethodError(err, "unmarshal", wireType, t)
+			}
+			return nil
+		}
+	}
+
+	return fncs
+}
+
+// implements reports whether t or reflect.PointerTo(t) implements ifaceType.
+// If only the pointer version implements the interface, then needAddr is true.
+func implements(t, ifaceType reflect.Type) (needAddr, ok bool) {
+	switch {
+	case t.Implements(ifaceType):
+		return false, true
+	case reflect.PointerTo(t).Implements(ifaceType):
+		return true, true
+	default:
+		return false, false
+	}
+}
+
+// addrWhen returns va.Addr if addr is specified, otherwise it returns itself.
+func (va addressableValue) addrWhen(addr bool) reflect.Value {
+	if addr {
+		return va.Addr()
+	}
+	return va.Value
+}
+
+func wrapMethodError(err error, action string, wireType thriftwire.Type, t reflect.Type) error {
+	var se *SemanticError
+	if errors.As(err, &se) {
+		return err
+	}
+	return &SemanticError{action: action, ThriftType: wireType, GoType: t, Err: err}
+}
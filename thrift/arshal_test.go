@@ -2,11 +2,13 @@ package thrift
 
 import (
 	"errors"
+	"fmt"
 	"math"
 	"reflect"
 	"slices"
 	"testing"
 
+	"github.com/itstarsun/go-thrift/encoding/thriftwire"
 	"github.com/itstarsun/go-thrift/internal/thriftmemo"
 )
 
@@ -23,6 +25,15 @@ type aRequiredField struct {
 	RequiredField string `thrift:"1,required"`
 }
 
+// aNegativeZero has fields holding negative zeros, which are not omitted.
+type aNegativeZero struct {
+	Float64 float64 `thrift:"1"`
+	Struct  struct {
+		Float32 float32 `thrift:"1"`
+	} `thrift:"2"`
+	Zero float64 `thrift:"3"`
+}
+
 func TestArshaler(t *testing.T) {
 	for _, tt := range []struct {
 		in    any
@@ -107,6 +118,31 @@ func TestArshaler(t *testing.T) {
 			"StructEnd",
 		},
 	}, {
+		in: func() (v aNegativeZero) {
+			v.Float64 = math.Copysign(0, -1)
+			v.Struct.Float32 = float32(math.Copysign(0, -1))
+			return v
+		}(),
+		steps: []string{
+			"StructBegin",
+
+			"FieldBegin",
+			"Double",
+			"FieldEnd",
+
+			"FieldBegin",
+			"StructBegin",
+			"FieldBegin",
+			"Double",
+			"FieldEnd",
+			"FieldStop",
+			"StructEnd",
+			"FieldEnd",
+
+			"FieldStop",
+			"StructEnd",
+		},
+	}, {
 		in: aRequiredField{},
 		steps: []string{
 			"StructBegin",
@@ -182,3 +218,115 @@ func TestArshaler(t *testing.T) {
 		}
 	}
 }
+
+var errOutOfRange = errors.New("out of range")
+
+type aValidatedStruct struct {
+	Percent int32 `thrift:"1"`
+}
+
+func (v *aValidatedStruct) Validate() error {
+	if v.Percent < 0 || v.Percent > 100 {
+		return errOutOfRange
+	}
+	return nil
+}
+
+func TestValidator(t *testing.T) {
+	var m thriftmemo.Memo
+	if err := Marshal(m.Writer(), &aValidatedStruct{Percent: 101}); !errors.Is(err, errOutOfRange) {
+		t.Fatalf("got %v, want %v", err, errOutOfRange)
+	} else if steps := m.Steps(); len(steps) != 0 {
+		t.Fatalf("got %v, want no steps", steps)
+	}
+
+	// Bypass the validator to write an invalid value.
+	type noValidate aValidatedStruct
+	if err := Marshal(m.Writer(), &noValidate{Percent: 101}); err != nil {
+		t.Fatal(err)
+	}
+	var out aValidatedStruct
+	err := Unmarshal(m.Reader(), &out)
+	var se *SemanticError
+	if !errors.As(err, &se) {
+		t.Fatalf("got %T, want %T", err, se)
+	}
+	if se.action != "unmarshal" || se.GoType != reflect.TypeOf(out) || !errors.Is(err, errOutOfRange) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	m.Reset()
+	if err := Marshal(m.Writer(), &aValidatedStruct{Percent: 100}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Unmarshal(m.Reader(), &out); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// aVersion is marshaled as a Thrift struct with a single string field like "1.2",
+// which cannot be done by reflection.
+type aVersion struct {
+	Major, Minor int8
+}
+
+func (v aVersion) MarshalThrift(w thriftwire.Writer) error {
+	return Marshal(w, struct {
+		S string `thrift:"1"`
+	}{fmt.Sprintf("%d.%d", v.Major, v.Minor)})
+}
+
+func (v *aVersion) UnmarshalThrift(r thriftwire.Reader) error {
+	var s struct {
+		S string `thrift:"1"`
+	}
+	if err := Unmarshal(r, &s); err != nil {
+		return err
+	}
+	_, err := fmt.Sscanf(s.S, "%d.%d", &v.Major, &v.Minor)
+	return err
+}
+
+func TestMarshaler(t *testing.T) {
+	type aVersions struct {
+		Version  aVersion            `thrift:"1"`
+		Versions map[string]aVersion `thrift:"2"`
+	}
+	in := aVersions{
+		Version:  aVersion{1, 2},
+		Versions: map[string]aVersion{"a": {3, 4}},
+	}
+
+	var m thriftmemo.Memo
+	if err := Marshal(m.Writer(), &in); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"StructBegin",
+		"FieldBegin", "StructBegin", "FieldBegin", "String", "FieldEnd", "FieldBegin", "StructEnd", "FieldEnd",
+		"FieldBegin", "MapBegin", "String", "StructBegin", "FieldBegin", "String", "FieldEnd", "FieldBegin", "StructEnd", "MapEnd", "FieldEnd",
+		"FieldBegin", "StructEnd",
+	}
+	if got := m.Steps(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("\ngot  %v\nwant %v", got, want)
+	}
+	var out aVersions
+	if err := Unmarshal(m.Reader(), &out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Fatalf("got %+v, want %+v", out, in)
+	}
+
+	m.Reset()
+	if err := Marshal(m.Writer(), &struct {
+		Version string `thrift:"1"`
+	}{"1.2"}); err != nil {
+		t.Fatal(err)
+	}
+	err := Unmarshal(m.Reader(), &out)
+	var se *SemanticError
+	if !errors.As(err, &se) || se.action != "unmarshal" || se.GoType != reflect.TypeOf(aVersion{}) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
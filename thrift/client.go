@@ -2,6 +2,12 @@ package thrift
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/itstarsun/go-thrift/encoding/thriftwire"
 )
 
 // A Client is the interface implemented by Thrift RPC clients.
@@ -10,3 +16,338 @@ type Client interface {
 	// Call invokes the named method. If the result is nil, a one-way message is sent.
 	Call(ctx context.Context, method string, args, result any) error
 }
+
+// ErrClientClosed is returned by [ClientConn.Call] after the client is closed.
+var ErrClientClosed = errors.New("thrift: client closed")
+
+// A ClientOption configures a [ClientConn] created by [NewClient].
+type ClientOption func(*clientOptions)
+
+type clientOptions struct {
+	pipelined bool
+}
+
+// Pipelined returns a [ClientOption] that allows many calls in flight
+// on the connection at once. Calls are written as they are made, and
+// replies, which may arrive in any order, are matched to the calls by their
+// sequence IDs. A call canceled by its context returns immediately without
+// breaking the connection, and its reply is discarded when it arrives.
+func Pipelined() ClientOption {
+	return func(o *clientOptions) {
+		o.pipelined = true
+	}
+}
+
+// A ClientConn is a [Client] making calls over a connection.
+//
+// By default, calls are made one at a time: a call writes the call message
+// and waits for the reply before the next call is made. See [Pipelined] for
+// making calls concurrently. If a call fails in a way that leaves the
+// connection in an unknown state, such as an I/O error or the cancellation
+// of its context while the call is in progress, the connection is closed,
+// and all pending and subsequent calls fail.
+type ClientConn struct {
+	conn io.ReadWriteCloser
+	r    thriftwire.Reader
+	w    thriftwire.Writer
+	opts clientOptions
+
+	sem   chan struct{} // held while writing, or making a call if not pipelined
+	seqID int32
+
+	mu      sync.Mutex
+	err     error // sticky error that broke the connection
+	pending map[int32]*pendingCall
+}
+
+// A pendingCall is a call waiting for its reply in pipelined mode.
+type pendingCall struct {
+	mh     thriftwire.MessageHeader
+	result any
+	header *map[string]string // receives the headers of the reply, if not nil
+	done   chan error         // receives the error of the call
+}
+
+var _ Client = (*ClientConn)(nil)
+
+// NewClient returns a new [ClientConn] making calls over conn
+// with messages encoded by p.
+func NewClient(conn io.ReadWriteCloser, p thriftwire.Protocol, opts ...ClientOption) *ClientConn {
+	r, w := thriftwire.NewReadWriter(p, conn)
+	c := &ClientConn{
+		conn: conn,
+		r:    r,
+		w:    w,
+		sem:  make(chan struct{}, 1),
+	}
+	for _, opt := range opts {
+		opt(&c.opts)
+	}
+	if c.opts.pipelined {
+		c.pending = make(map[int32]*pendingCall)
+		go c.readLoop()
+	}
+	return c
+}
+
+// Call implements [Client] by writing a call message with the args,
+// and unmarshaling the reply into the result. If the result is nil,
+// a one-way message is written, and Call returns without waiting for
+// a reply.
+//
+// If the server replies with an exception, Call returns it as an error.
+//
+// The headers set by [WithCallHeader] are sent with the call, and
+// the headers of the reply are stored as requested by [WithReplyHeader],
+// if the protocol carries headers.
+func (c *ClientConn) Call(ctx context.Context, method string, args, result any) error {
+	select {
+	case c.sem <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if c.opts.pipelined {
+		return c.callPipelined(ctx, method, args, result)
+	}
+	defer func() { <-c.sem }()
+
+	mh := c.header(method, result)
+	err := c.write(ctx, mh, args, nil)
+	if err == nil && result != nil {
+		err = c.read(ctx, mh, result)
+	}
+	return err
+}
+
+func (c *ClientConn) callPipelined(ctx context.Context, method string, args, result any) error {
+	mh := c.header(method, result)
+	var call *pendingCall
+	if result != nil {
+		call = &pendingCall{
+			mh:     mh,
+			result: result,
+			header: replyHeader(ctx),
+			done:   make(chan error, 1),
+		}
+	}
+	err := c.write(ctx, mh, args, call)
+	<-c.sem
+	if err != nil || call == nil {
+		return err
+	}
+
+	select {
+	case err := <-call.done:
+		return err
+	case <-ctx.Done():
+		c.mu.Lock()
+		_, ok := c.pending[mh.ID]
+		delete(c.pending, mh.ID)
+		c.mu.Unlock()
+		if ok {
+			return ctx.Err()
+		}
+		// The reply is being read into the result.
+		return <-call.done
+	}
+}
+
+// header returns the header of the next call message.
+// It must be called while holding c.sem.
+func (c *ClientConn) header(method string, result any) thriftwire.MessageHeader {
+	c.seqID++
+	mh := thriftwire.MessageHeader{
+		Name: method,
+		Type: thriftwire.Call,
+		ID:   c.seqID,
+	}
+	if result == nil {
+		mh.Type = thriftwire.OneWay
+	}
+	return mh
+}
+
+// write writes the call message with the header mh and the args,
+// registering the pending call, if any, before writing.
+// It must be called while holding c.sem.
+func (c *ClientConn) write(ctx context.Context, mh thriftwire.MessageHeader, args any, call *pendingCall) error {
+	c.mu.Lock()
+	err := c.err
+	if err == nil && call != nil {
+		c.pending[mh.ID] = call
+	}
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if w, ok := c.w.(HeaderWriter); ok {
+		w.SetHeader(callHeader(ctx))
+	}
+	stop := c.interruptOnDone(ctx)
+	defer stop()
+	if err := writeMessage(c.w, mh, args); err != nil {
+		return c.failCall(ctx, err)
+	}
+	return nil
+}
+
+// read reads the reply to the call with the header mh into result.
+// It must be called while holding c.sem.
+func (c *ClientConn) read(ctx context.Context, mh thriftwire.MessageHeader, result any) error {
+	stop := c.interruptOnDone(ctx)
+	defer stop()
+	rh, err := c.r.ReadMessageBegin()
+	if err != nil {
+		return c.failCall(ctx, err)
+	}
+	c.storeHeader(replyHeader(ctx))
+	exc, err := readReply(c.r, rh, mh, result)
+	if err != nil {
+		return c.failCall(ctx, err)
+	}
+	if exc != nil {
+		// The exception was read completely, leaving the connection usable.
+		return exc
+	}
+	return nil
+}
+
+// readLoop reads the replies to pending calls in pipelined mode
+// until the connection is broken.
+func (c *ClientConn) readLoop() {
+	for {
+		rh, err := c.r.ReadMessageBegin()
+		if err != nil {
+			c.fail(err)
+			return
+		}
+		c.mu.Lock()
+		call := c.pending[rh.ID]
+		delete(c.pending, rh.ID)
+		c.mu.Unlock()
+
+		if call == nil {
+			// Discard the reply to a canceled call.
+			if err := thriftwire.Skip(c.r, thriftwire.Struct); err != nil {
+				c.fail(err)
+				return
+			}
+			if err := c.r.ReadMessageEnd(); err != nil {
+				c.fail(err)
+				return
+			}
+			continue
+		}
+
+		c.storeHeader(call.header)
+		exc, err := readReply(c.r, rh, call.mh, call.result)
+		if err != nil {
+			call.done <- c.fail(err)
+			return
+		}
+		if exc != nil {
+			call.done <- exc
+		} else {
+			call.done <- nil
+		}
+	}
+}
+
+// storeHeader stores the headers of the reply being read into h
+// if h is not nil and c.r is a [HeaderReader].
+func (c *ClientConn) storeHeader(h *map[string]string) {
+	if r, ok := c.r.(HeaderReader); ok && h != nil {
+		*h = r.Header()
+	}
+}
+
+// interruptOnDone interrupts the I/O of a call by closing the connection
+// if ctx is done, since the state of the connection is unknown afterwards.
+// The returned function stops the interruption.
+func (c *ClientConn) interruptOnDone(ctx context.Context) (stop func() bool) {
+	return context.AfterFunc(ctx, func() {
+		c.fail(fmt.Errorf("%scall interrupted: %w", errorPrefix, ctx.Err()))
+	})
+}
+
+// failCall breaks the connection because of the error of a call,
+// and returns the error of the call.
+func (c *ClientConn) failCall(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		c.fail(err)
+		return ctx.Err()
+	}
+	return c.fail(err)
+}
+
+// Close closes the connection.
+// Pending calls fail, and subsequent calls return [ErrClientClosed].
+func (c *ClientConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return nil
+	}
+	return c.close(ErrClientClosed)
+}
+
+// fail closes the connection because of err, and returns the error
+// that broke the connection, which may have happened earlier.
+func (c *ClientConn) fail(err error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err == nil {
+		c.close(err)
+	}
+	return c.err
+}
+
+// close closes the connection because of err, and fails pending calls.
+// It must be called while holding c.mu.
+func (c *ClientConn) close(err error) error {
+	c.err = err
+	for id, call := range c.pending {
+		call.done <- err
+		delete(c.pending, id)
+	}
+	return c.conn.Close()
+}
+
+// readReply reads the body of the reply message with the header rh to the
+// call with the header mh into result. If the reply is an exception,
+// it is returned as exc. An error means that the connection is unusable.
+func readReply(r thriftwire.Reader, rh, mh thriftwire.MessageHeader, result any) (exc *ApplicationError, err error) {
+	switch {
+	case rh.ID != mh.ID:
+		return nil, &ApplicationError{
+			Message: fmt.Sprintf("reply to %s has sequence ID %d, want %d", mh.Name, rh.ID, mh.ID),
+			Type:    BadSequenceID,
+		}
+	case rh.Name != mh.Name:
+		return nil, &ApplicationError{
+			Message: fmt.Sprintf("reply to %s has method name %s", mh.Name, rh.Name),
+			Type:    WrongMethodName,
+		}
+	}
+	switch rh.Type {
+	case thriftwire.Reply:
+		if err := Unmarshal(r, result); err != nil {
+			return nil, err
+		}
+	case thriftwire.Exception:
+		exc = new(ApplicationError)
+		if err := Unmarshal(r, exc); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, &ApplicationError{
+			Message: fmt.Sprintf("reply to %s has message type %v", mh.Name, rh.Type),
+			Type:    InvalidMessageType,
+		}
+	}
+	if err := r.ReadMessageEnd(); err != nil {
+		return nil, err
+	}
+	return exc, nil
+}
@@ -0,0 +1,287 @@
+package thrift
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/itstarsun/go-thrift/encoding/thriftbinary"
+	"github.com/itstarsun/go-thrift/encoding/thriftwire"
+)
+
+// pipeClient returns a client connected to a goroutine serving p.
+func pipeClient(t *testing.T, p *Processor, opts ...ClientOption) *ClientConn {
+	t.Helper()
+	cc, sc := net.Pipe()
+	go func() {
+		defer sc.Close()
+		r := thriftbinary.Protocol.NewReader(sc)
+		w := thriftbinary.Protocol.NewWriter(sc)
+		for p.Process(context.Background(), r, w) == nil {
+		}
+	}()
+	c := NewClient(cc, thriftbinary.Protocol, opts...)
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestClient(t *testing.T) {
+	t.Run("Serial", func(t *testing.T) { testClient(t) })
+	t.Run("Pipelined", func(t *testing.T) { testClient(t, Pipelined()) })
+}
+
+func testClient(t *testing.T, opts ...ClientOption) {
+	var calls []string
+	c := pipeClient(t, newEchoProcessor(&calls), opts...)
+	ctx := context.Background()
+
+	var result echoResult
+	if err := c.Call(ctx, "echo", &echoArgs{Message: "hello"}, &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Success == nil || *result.Success != "hello" {
+		t.Fatalf("got %s, want hello", Format(&result))
+	}
+
+	err := c.Call(ctx, "echo", &echoArgs{}, &result)
+	var exc *ApplicationError
+	if !errors.As(err, &exc) || exc.Type != InternalError || exc.Message != "empty message" {
+		t.Fatalf("got %v, want an internal error", err)
+	}
+	err = c.Call(ctx, "missing", &echoArgs{}, &result)
+	if !errors.Is(err, ErrUnknownMethod) {
+		t.Fatalf("got %v, want an unknown method error", err)
+	}
+
+	if err := c.Call(ctx, "log", &echoArgs{Message: "logged"}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(msg string) {
+			defer wg.Done()
+			var result echoResult
+			if err := c.Call(ctx, "echo", &echoArgs{Message: msg}, &result); err != nil {
+				t.Error(err)
+			} else if *result.Success != msg {
+				t.Errorf("got %q, want %q", *result.Success, msg)
+			}
+		}(fmt.Sprint(i))
+	}
+	wg.Wait()
+
+	// The one-way call was processed before the calls that followed it.
+	if len(calls) != 1 || calls[0] != "logged" {
+		t.Errorf("got one-way calls %q, want [logged]", calls)
+	}
+	if c.seqID != 14 {
+		t.Errorf("got sequence ID %d, want 14", c.seqID)
+	}
+
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Call(ctx, "echo", &echoArgs{Message: "hello"}, &result); !errors.Is(err, ErrClientClosed) {
+		t.Fatalf("got %v, want %v", err, ErrClientClosed)
+	}
+}
+
+func TestClientBadReply(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		reply thriftwire.MessageHeader
+		want  error
+	}{
+		{"ID", thriftwire.MessageHeader{Name: "echo", Type: thriftwire.Reply, ID: 2}, ErrBadSequenceID},
+		{"Name", thriftwire.MessageHeader{Name: "other", Type: thriftwire.Reply, ID: 1}, ErrWrongMethodName},
+		{"Type", thriftwire.MessageHeader{Name: "echo", Type: thriftwire.Call, ID: 1}, ErrInvalidMessageType},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			cc, sc := net.Pipe()
+			go func() {
+				defer sc.Close()
+				r := thriftbinary.Protocol.NewReader(sc)
+				if _, err := r.ReadMessageBegin(); err != nil {
+					return
+				}
+				if thriftwire.Skip(r, thriftwire.Struct) != nil || r.ReadMessageEnd() != nil {
+					return
+				}
+				writeMessage(thriftbinary.Protocol.NewWriter(sc), tt.reply, &echoResult{})
+			}()
+			c := NewClient(cc, thriftbinary.Protocol)
+			defer c.Close()
+
+			var result echoResult
+			err := c.Call(context.Background(), "echo", &echoArgs{}, &result)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+			if err2 := c.Call(context.Background(), "echo", &echoArgs{}, &result); err2 != err {
+				t.Fatalf("got %v after a broken connection, want %v", err2, err)
+			}
+		})
+	}
+}
+
+func TestClientCancel(t *testing.T) {
+	cc, sc := net.Pipe()
+	defer sc.Close()
+	go func() {
+		// Read the call but never reply.
+		r := thriftbinary.Protocol.NewReader(sc)
+		r.ReadMessageBegin()
+		thriftwire.Skip(r, thriftwire.Struct)
+		r.ReadMessageEnd()
+	}()
+	c := NewClient(cc, thriftbinary.Protocol)
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	var result echoResult
+	if err := c.Call(ctx, "echo", &echoArgs{}, &result); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+	if err := c.Call(context.Background(), "echo", &echoArgs{}, &result); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v after an interrupted call, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+// readCall reads a call message from r, and returns its header.
+func readCall(r thriftwire.Reader) (thriftwire.MessageHeader, error) {
+	mh, err := r.ReadMessageBegin()
+	if err != nil {
+		return mh, err
+	}
+	if err := thriftwire.Skip(r, thriftwire.Struct); err != nil {
+		return mh, err
+	}
+	return mh, r.ReadMessageEnd()
+}
+
+func replyEcho(w thriftwire.Writer, mh thriftwire.MessageHeader, msg string) error {
+	mh.Type = thriftwire.Reply
+	return writeMessage(w, mh, &echoResult{Success: &msg})
+}
+
+func TestClientPipelined(t *testing.T) {
+	cc, sc := net.Pipe()
+	c := NewClient(cc, thriftbinary.Protocol, Pipelined())
+	defer c.Close()
+
+	// The server reads three calls, and replies to them in reverse order,
+	// except for the first one, which is canceled before the reply.
+	canceled := make(chan struct{})
+	go func() {
+		defer sc.Close()
+		r := thriftbinary.Protocol.NewReader(sc)
+		w := thriftbinary.Protocol.NewWriter(sc)
+		var calls []thriftwire.MessageHeader
+		for i := 0; i < 3; i++ {
+			mh, err := readCall(r)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			calls = append(calls, mh)
+		}
+		<-canceled
+		for i := len(calls) - 1; i >= 0; i-- {
+			if err := replyEcho(w, calls[i], fmt.Sprint(calls[i].ID)); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+		// Then it breaks the connection after reading a call.
+		readCall(r)
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error)
+	go func() {
+		var result echoResult
+		errc <- c.Call(ctx, "echo", &echoArgs{}, &result)
+	}()
+	// Wait for the call to be written before making the others.
+	for {
+		c.mu.Lock()
+		n := len(c.pending)
+		c.mu.Unlock()
+		if n == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var result echoResult
+			if err := c.Call(context.Background(), "echo", &echoArgs{}, &result); err != nil {
+				t.Error(err)
+			} else if *result.Success != "2" && *result.Success != "3" {
+				t.Errorf("got reply %q, want 2 or 3", *result.Success)
+			}
+		}()
+	}
+	cancel()
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want %v", err, context.Canceled)
+	}
+	close(canceled)
+	wg.Wait()
+
+	var result echoResult
+	if err := c.Call(context.Background(), "echo", &echoArgs{}, &result); err == nil {
+		t.Fatal("unexpected success on a broken connection")
+	}
+	if err := c.Call(context.Background(), "echo", &echoArgs{}, &result); err == nil {
+		t.Fatal("unexpected success after a broken connection")
+	}
+}
+
+func TestClientPipelinedClose(t *testing.T) {
+	cc, sc := net.Pipe()
+	defer sc.Close()
+	go func() {
+		r := thriftbinary.Protocol.NewReader(sc)
+		for {
+			if _, err := readCall(r); err != nil {
+				return
+			}
+		}
+	}()
+	c := NewClient(cc, thriftbinary.Protocol, Pipelined())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var result echoResult
+			if err := c.Call(context.Background(), "echo", &echoArgs{}, &result); !errors.Is(err, ErrClientClosed) {
+				t.Errorf("got %v, want %v", err, ErrClientClosed)
+			}
+		}()
+	}
+	for {
+		c.mu.Lock()
+		n := len(c.pending)
+		c.mu.Unlock()
+		if n == 3 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	c.Close()
+	wg.Wait()
+}
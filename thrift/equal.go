@@ -0,0 +1,471 @@
+package thrift
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/itstarsun/go-thrift/encoding/thriftwire"
+)
+
+// A Difference describes a difference between two Thrift values
+// as reported by [Diff].
+//
+// If the path passes through a field with the `sensitive` tag option,
+// the differing values and the map keys in the path are masked.
+type Difference struct {
+	Path Path // path to the differing value from the root
+	X, Y any  // the differing values; nil if the value is absent
+}
+
+// String returns the path and the differing values rendered by [Format].
+func (d Difference) String() string {
+	return fmt.Sprintf("%v: %s != %s", d.Path, formatDiff(d.X), formatDiff(d.Y))
+}
+
+// A redactedValue masks a differing value or a map key under
+// a sensitive field.
+type redactedValue struct{}
+
+func (redactedValue) String() string { return redacted }
+
+func formatDiff(v any) string {
+	if v, ok := v.(redactedValue); ok {
+		return v.String()
+	}
+	return Format(v)
+}
+
+// A Path is a sequence of steps from the root of a Thrift value.
+type Path []PathStep
+
+func (p Path) String() string {
+	var sb strings.Builder
+	for _, s := range p {
+		sb.WriteString(s.String())
+	}
+	return sb.String()
+}
+
+// A PathStep is a single step in a [Path].
+// It is one of [FieldStep], [IndexStep] or [KeyStep].
+type PathStep interface {
+	String() string
+	aPathStep()
+}
+
+// A FieldStep is a [PathStep] to the field of a struct.
+type FieldStep struct {
+	ID   int16
+	Name string // may be empty
+}
+
+func (s FieldStep) String() string { return "." + strconv.Itoa(int(s.ID)) }
+
+// An IndexStep is a [PathStep] to the element of a list or a set.
+//
+// For sets, the index refers to the position of the element
+// within the set that has the element.
+type IndexStep int
+
+func (s IndexStep) String() string { return "[" + strconv.Itoa(int(s)) + "]" }
+
+// A KeyStep is a [PathStep] to the value of a map.
+type KeyStep struct {
+	Key any
+}
+
+func (s KeyStep) String() string {
+	if k, ok := s.Key.(string); ok {
+		return "[" + strconv.Quote(k) + "]"
+	}
+	return fmt.Sprintf("[%v]", s.Key)
+}
+
+func (FieldStep) aPathStep() {}
+func (IndexStep) aPathStep() {}
+func (KeyStep) aPathStep()   {}
+
+// Equal reports whether x and y are equal according to Thrift semantics.
+//
+// Two values are equal if they have the same Go type and marshal to
+// the same Thrift data, ignoring the order of set elements and map entries.
+// In particular, nil and empty containers are equal unless one of them is
+// omitted from a struct, and a nil pointer in an optional field is unset,
+// which is not equal to a pointer to the zero value.
+// Floating-point values are compared by their bit patterns.
+//
+// Like [Marshal], a pointer is dereferenced before comparison.
+func Equal(x, y any) bool {
+	d := differ{first: true}
+	d.diff(x, y)
+	return len(d.diffs) == 0
+}
+
+// Diff reports the differences between x and y
+// according to the semantics of [Equal].
+// It returns nil if x and y are equal.
+func Diff(x, y any) []Difference {
+	var d differ
+	d.diff(x, y)
+	return d.diffs
+}
+
+type differ struct {
+	path      Path
+	diffs     []Difference
+	first     bool // whether to stop at the first difference
+	sensitive bool // whether the path passes through a sensitive field
+}
+
+func (d *differ) done() bool {
+	return d.first && len(d.diffs) > 0
+}
+
+func (d *differ) report(x, y any) {
+	if d.sensitive {
+		x, y = redact(x), redact(y)
+	}
+	d.diffs = append(d.diffs, Difference{
+		Path: append(Path(nil), d.path...),
+		X:    x,
+		Y:    y,
+	})
+}
+
+// redact masks v unless it is absent.
+func redact(v any) any {
+	if v == nil {
+		return nil
+	}
+	return redactedValue{}
+}
+
+func (d *differ) diff(x, y any) {
+	vx := reflect.ValueOf(x)
+	vy := reflect.ValueOf(y)
+	if !vx.IsValid() || !vy.IsValid() {
+		if vx.IsValid() || vy.IsValid() {
+			d.report(x, y)
+		}
+		return
+	}
+	if vx.Type() != vy.Type() {
+		d.report(x, y)
+		return
+	}
+	if vx.Kind() == reflect.Pointer {
+		if vx.IsNil() || vy.IsNil() {
+			if vx.IsNil() != vy.IsNil() {
+				d.report(x, y)
+			}
+			return
+		}
+		d.compare(vx.Type().Elem(), addressableValue{vx.Elem()}, addressableValue{vy.Elem()})
+		return
+	}
+	// Shallow copy non-pointer values to obtain addressable values.
+	ax := newAddressableValue(vx.Type())
+	ax.Set(vx)
+	ay := newAddressableValue(vy.Type())
+	ay.Set(vy)
+	d.compare(vx.Type(), ax, ay)
+}
+
+func (d *differ) compare(t reflect.Type, x, y addressableValue) {
+	if d.done() {
+		return
+	}
+	fncs := lookupArshaler(t)
+	switch t.Kind() {
+	case reflect.Bool:
+		if x.Bool() != y.Bool() {
+			d.report(x.Interface(), y.Interface())
+		}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if x.Int() != y.Int() {
+			d.report(x.Interface(), y.Interface())
+		}
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if x.Uint() != y.Uint() {
+			d.report(x.Interface(), y.Interface())
+		}
+	case reflect.Float32, reflect.Float64:
+		if math.Float64bits(x.Float()) != math.Float64bits(y.Float()) {
+			d.report(x.Interface(), y.Interface())
+		}
+	case reflect.String:
+		if x.String() != y.String() {
+			d.report(x.Interface(), y.Interface())
+		}
+	case reflect.Pointer:
+		d.compare(t.Elem(), x.indirectOrZero(), y.indirectOrZero())
+	case reflect.Struct:
+		d.compareStruct(fncs, x, y)
+	case reflect.Map:
+		d.compareMap(t, x, y)
+	case reflect.Slice:
+		switch fncs.wireType {
+		case thriftwire.String:
+			if !bytes.Equal(x.Bytes(), y.Bytes()) {
+				d.report(x.Interface(), y.Interface())
+			}
+		case thriftwire.Set:
+			d.compareSet(t, x, y)
+		default:
+			d.compareList(t, x, y)
+		}
+	default:
+		if !reflect.DeepEqual(x.Interface(), y.Interface()) {
+			d.report(x.Interface(), y.Interface())
+		}
+	}
+}
+
+// indirectOrZero dereferences a pointer value.
+// A nil pointer is treated as a pointer to the zero value,
+// since that is how it is marshaled.
+func (va addressableValue) indirectOrZero() addressableValue {
+	if va.IsNil() {
+		return newAddressableValue(va.Type().Elem())
+	}
+	return addressableValue{va.Elem()} // dereferenced pointer is always addressable
+}
+
+func (d *differ) compareStruct(fncs *arshaler, x, y addressableValue) {
+	fields, err := fncs.fields()
+	if err != nil {
+		if !reflect.DeepEqual(x.Interface(), y.Interface()) {
+			d.report(x.Interface(), y.Interface())
+		}
+		return
+	}
+	for i := range fields.sorted {
+		f := &fields.sorted[i]
+		vx := x.structField(f, false)
+		vy := y.structField(f, false)
+		hasX := vx.IsValid() && !f.omit(vx)
+		hasY := vy.IsValid() && !f.omit(vy)
+		if !hasX && !hasY {
+			continue
+		}
+		d.path = append(d.path, FieldStep{ID: f.id, Name: f.name})
+		sensitive := d.sensitive
+		d.sensitive = sensitive || f.sensitive
+		switch {
+		case !hasX:
+			d.report(nil, vy.Interface())
+		case !hasY:
+			d.report(vx.Interface(), nil)
+		default:
+			d.compare(f.typ, vx, vy)
+		}
+		d.sensitive = sensitive
+		d.path = d.path[:len(d.path)-1]
+		if d.done() {
+			return
+		}
+	}
+}
+
+func (d *differ) compareMap(t reflect.Type, x, y addressableValue) {
+	if x.Len() == 0 && y.Len() == 0 {
+		return
+	}
+	vx := newAddressableValue(t.Elem())
+	vy := newAddressableValue(t.Elem())
+	for _, k := range sortedMapKeys(x.Value) {
+		d.path = append(d.path, d.keyStep(k))
+		vx.Set(x.MapIndex(k))
+		if v := y.MapIndex(k); v.IsValid() {
+			vy.Set(v)
+			d.compare(t.Elem(), vx, vy)
+		} else {
+			d.report(vx.Interface(), nil)
+		}
+		d.path = d.path[:len(d.path)-1]
+		if d.done() {
+			return
+		}
+	}
+	for _, k := range sortedMapKeys(y.Value) {
+		if x.MapIndex(k).IsValid() {
+			continue
+		}
+		d.path = append(d.path, d.keyStep(k))
+		d.report(nil, y.MapIndex(k).Interface())
+		d.path = d.path[:len(d.path)-1]
+		if d.done() {
+			return
+		}
+	}
+}
+
+// keyStep returns the step to the value of the map key k.
+func (d *differ) keyStep(k reflect.Value) KeyStep {
+	if d.sensitive {
+		return KeyStep{Key: redactedValue{}}
+	}
+	return KeyStep{Key: k.Interface()}
+}
+
+func (d *differ) compareList(t reflect.Type, x, y addressableValue) {
+	n := max(x.Len(), y.Len())
+	for i := 0; i < n; i++ {
+		d.path = append(d.path, IndexStep(i))
+		switch {
+		case i >= x.Len():
+			d.report(nil, y.Index(i).Interface())
+		case i >= y.Len():
+			d.report(x.Index(i).Interface(), nil)
+		default:
+			// Indexed slice elements are always addressable.
+			d.compare(t.Elem(), addressableValue{x.Index(i)}, addressableValue{y.Index(i)})
+		}
+		d.path = d.path[:len(d.path)-1]
+		if d.done() {
+			return
+		}
+	}
+}
+
+func (d *differ) compareSet(t reflect.Type, x, y addressableValue) {
+	matched := make([]bool, y.Len())
+	var unmatched []int
+	for i := 0; i < x.Len(); i++ {
+		ex := addressableValue{x.Index(i)} // indexed slice element is always addressable
+		found := false
+		for j := 0; j < y.Len(); j++ {
+			if matched[j] {
+				continue
+			}
+			sub := differ{first: true}
+			sub.compare(t.Elem(), ex, addressableValue{y.Index(j)})
+			if len(sub.diffs) == 0 {
+				matched[j] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			unmatched = append(unmatched, i)
+		}
+	}
+	for _, i := range unmatched {
+		d.path = append(d.path, IndexStep(i))
+		d.report(x.Index(i).Interface(), nil)
+		d.path = d.path[:len(d.path)-1]
+		if d.done() {
+			return
+		}
+	}
+	for j, ok := range matched {
+		if ok {
+			continue
+		}
+		d.path = append(d.path, IndexStep(j))
+		d.report(nil, y.Index(j).Interface())
+		d.path = d.path[:len(d.path)-1]
+		if d.done() {
+			return
+		}
+	}
+}
+
+// sortedMapKeys returns the keys of the map v,
+// sorted if the keys are of a basic kind.
+func sortedMapKeys(v reflect.Value) []reflect.Value {
+	keys := v.MapKeys()
+	var less func(x, y reflect.Value) bool
+	switch v.Type().Key().Kind() {
+	case reflect.Bool:
+		less = func(x, y reflect.Value) bool { return !x.Bool() && y.Bool() }
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		less = func(x, y reflect.Value) bool { return x.Int() < y.Int() }
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		less = func(x, y reflect.Value) bool { return x.Uint() < y.Uint() }
+	case reflect.Float32, reflect.Float64:
+		less = func(x, y reflect.Value) bool { return x.Float() < y.Float() }
+	case reflect.String:
+		less = func(x, y reflect.Value) bool { return x.String() < y.String() }
+	default:
+		return keys
+	}
+	sort.Slice(keys, func(i, j int) bool { return less(keys[i], keys[j]) })
+	return keys
+}
+
+// Clone returns a deep copy of v.
+//
+// All Thrift fields are copied deeply, preserving the distinction
+// between nil and empty containers.
+// Go struct fields that are not Thrift fields are copied shallowly.
+func Clone[T any](v T) T {
+	src := reflect.ValueOf(&v).Elem()
+	dst := newAddressableValue(src.Type())
+	cloneValue(dst, addressableValue{src})
+	return dst.Interface().(T)
+}
+
+func cloneValue(dst, src addressableValue) {
+	dst.Set(src.Value)
+	switch t := src.Type(); t.Kind() {
+	case reflect.Interface:
+		if !src.IsNil() {
+			v := newAddressableValue(src.Elem().Type())
+			v.Set(src.Elem())
+			clone := newAddressableValue(v.Type())
+			cloneValue(clone, v)
+			dst.Set(clone.Value)
+		}
+	case reflect.Pointer:
+		if !src.IsNil() {
+			v := reflect.New(t.Elem())
+			cloneValue(addressableValue{v.Elem()}, addressableValue{src.Elem()})
+			dst.Set(v)
+		}
+	case reflect.Struct:
+		fields, err := lookupArshaler(t).fields()
+		if err != nil {
+			return
+		}
+		for i := range fields.sorted {
+			f := &fields.sorted[i]
+			v := src.structField(f, false)
+			if !v.IsValid() {
+				continue
+			}
+			cloneValue(dst.structField(f, true), v)
+		}
+	case reflect.Slice:
+		if !src.IsNil() {
+			n := src.Len()
+			v := reflect.MakeSlice(t, n, n)
+			for i := 0; i < n; i++ {
+				// Indexed slice elements are always addressable.
+				cloneValue(addressableValue{v.Index(i)}, addressableValue{src.Index(i)})
+			}
+			dst.Set(v)
+		}
+	case reflect.Map:
+		if !src.IsNil() {
+			v := reflect.MakeMapWithSize(t, src.Len())
+			k := newAddressableValue(t.Key())
+			e := newAddressableValue(t.Elem())
+			kc := newAddressableValue(t.Key())
+			ec := newAddressableValue(t.Elem())
+			for iter := src.MapRange(); iter.Next(); {
+				k.SetIterKey(iter)
+				e.SetIterValue(iter)
+				cloneValue(kc, k)
+				cloneValue(ec, e)
+				v.SetMapIndex(kc.Value, ec.Value)
+			}
+			dst.Set(v)
+		}
+	}
+}
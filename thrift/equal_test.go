@@ -0,0 +1,240 @@
+package thrift
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type anOptionalField struct {
+	Optional *string        `thrift:"1"`
+	Strings  []string       `thrift:"2"`
+	Map      map[string]int `thrift:"3"`
+}
+
+type aSetField struct {
+	Set Set[*aStruct] `thrift:"1"`
+}
+
+func TestEqual(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		x, y any
+		want bool
+	}{{
+		name: "Bool",
+		x:    true,
+		y:    true,
+		want: true,
+	}, {
+		name: "DifferentTypes",
+		x:    int32(1),
+		y:    int64(1),
+		want: false,
+	}, {
+		name: "Nil",
+		x:    nil,
+		y:    nil,
+		want: true,
+	}, {
+		name: "NilAndNonNil",
+		x:    nil,
+		y:    "",
+		want: false,
+	}, {
+		name: "Pointers",
+		x:    ptr("hello"),
+		y:    ptr("hello"),
+		want: true,
+	}, {
+		name: "NaN",
+		x:    math.NaN(),
+		y:    math.NaN(),
+		want: true,
+	}, {
+		name: "SignedZeros",
+		x:    0.0,
+		y:    math.Copysign(0, -1),
+		want: false,
+	}, {
+		name: "NilAndEmptyList",
+		x:    []string(nil),
+		y:    []string{},
+		want: true,
+	}, {
+		name: "NilAndEmptyBytes",
+		x:    []byte(nil),
+		y:    []byte{},
+		want: true,
+	}, {
+		name: "NilAndEmptyMap",
+		x:    map[string]string(nil),
+		y:    map[string]string{},
+		want: true,
+	}, {
+		name: "ListOrder",
+		x:    []int32{1, 2, 3},
+		y:    []int32{3, 2, 1},
+		want: false,
+	}, {
+		name: "SetOrder",
+		x:    Set[int32]{1, 2, 3},
+		y:    Set[int32]{3, 2, 1},
+		want: true,
+	}, {
+		name: "SetMultiplicity",
+		x:    Set[int32]{1, 1, 2},
+		y:    Set[int32]{1, 2, 2},
+		want: false,
+	}, {
+		name: "SetOfStructs",
+		x:    aSetField{Set: Set[*aStruct]{{String: "a"}, {String: "b"}}},
+		y:    aSetField{Set: Set[*aStruct]{{String: "b"}, {String: "a"}}},
+		want: true,
+	}, {
+		name: "NilPointerInList",
+		x:    []*string{nil},
+		y:    []*string{ptr("")},
+		want: true,
+	}, {
+		name: "UnsetOptionalPointer",
+		x:    anOptionalField{},
+		y:    anOptionalField{Optional: ptr("")},
+		want: false,
+	}, {
+		name: "OmittedAndEmptyField",
+		x:    anOptionalField{},
+		y:    anOptionalField{Strings: []string{}},
+		want: false,
+	}, {
+		name: "RequiredField",
+		x:    aRequiredField{},
+		y:    aRequiredField{RequiredField: ""},
+		want: true,
+	}, {
+		name: "Struct",
+		x:    &aStruct{String: "a", List: []*aStruct{{}}},
+		y:    &aStruct{String: "a", List: []*aStruct{nil}},
+		want: true,
+	}} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Equal(tt.x, tt.y); got != tt.want {
+				t.Errorf("Equal(%v, %v) = %t, want %t", tt.x, tt.y, got, tt.want)
+			}
+			if got := Equal(tt.y, tt.x); got != tt.want {
+				t.Errorf("Equal(%v, %v) = %t, want %t", tt.y, tt.x, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	x := &aStruct{
+		String: "x",
+		List:   []*aStruct{{String: "a"}, {String: "b"}},
+	}
+	y := &aStruct{
+		String: "y",
+		List:   []*aStruct{{String: "a"}, {}, {String: "c"}},
+	}
+	var got []string
+	for _, d := range Diff(x, y) {
+		got = append(got, d.String())
+	}
+	want := []string{
+		`.1: "x" != "y"`,
+		`.2[1].1: "b" != <nil>`,
+		`.2[2]: <nil> != aStruct{String(1): "c"}`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("\ngot  %q\nwant %q", got, want)
+	}
+
+	diffs := Diff(anOptionalField{Map: map[string]int{"a": 1, "b": 2}}, anOptionalField{Map: map[string]int{"b": 3, "c": 4}})
+	got = got[:0]
+	for _, d := range diffs {
+		got = append(got, d.Path.String())
+	}
+	want = []string{`.3["a"]`, `.3["b"]`, `.3["c"]`}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("\ngot  %q\nwant %q", got, want)
+	}
+
+	if diffs := Diff(x, x); diffs != nil {
+		t.Errorf("Diff(x, x) = %v, want nil", diffs)
+	}
+}
+
+type aKeyring struct {
+	Keys map[string]string `thrift:"1,sensitive"`
+}
+
+func TestDiffSensitive(t *testing.T) {
+	s := aSecret{Name: "gopher", Token: "hunter2"}
+	for _, tt := range []struct {
+		x, y any
+		want []string
+	}{{
+		x:    &s,
+		y:    &aSecret{Name: "gopher", Token: "swordfish"},
+		want: []string{`.2: <redacted> != <redacted>`},
+	}, {
+		x:    &s,
+		y:    &aSecret{Name: "gopher"},
+		want: []string{`.2: <redacted> != <nil>`},
+	}, {
+		x:    &aKeyring{Keys: map[string]string{"hunter2": "hunter2"}},
+		y:    &aKeyring{Keys: map[string]string{"hunter2": "swordfish"}},
+		want: []string{`.1[<redacted>]: <redacted> != <redacted>`},
+	}, {
+		x:    &aVault{All: []aSecret{s}},
+		y:    &aVault{},
+		want: []string{`.2: [aSecret{Name(1): "gopher", Token(2): <redacted>}] != <nil>`},
+	}} {
+		var got []string
+		for _, d := range Diff(tt.x, tt.y) {
+			got = append(got, d.String())
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Diff(%T):\ngot  %q\nwant %q", tt.x, got, tt.want)
+		}
+	}
+
+	d := Diff(&s, &aSecret{Name: "gopher", Token: "swordfish"})[0]
+	if strings.Contains(fmt.Sprint(d.X, d.Y), "hunter2") {
+		t.Errorf("Diff reported the sensitive values %v and %v", d.X, d.Y)
+	}
+}
+
+func TestClone(t *testing.T) {
+	x := anOptionalField{
+		Optional: ptr("hello"),
+		Strings:  []string{},
+		Map:      map[string]int{"a": 1},
+	}
+	y := Clone(x)
+	if !Equal(x, y) {
+		t.Fatalf("Clone(%v) = %v, want equal", x, y)
+	}
+	if y.Strings == nil {
+		t.Errorf("Clone did not preserve an empty slice")
+	}
+	*y.Optional = "world"
+	y.Map["a"] = 2
+	if *x.Optional != "hello" || x.Map["a"] != 1 {
+		t.Errorf("Clone did not copy deeply: %v", x)
+	}
+
+	s := &aStruct{List: []*aStruct{{String: "a"}}}
+	c := Clone(s)
+	if c == s || c.List[0] == s.List[0] || !Equal(c, s) {
+		t.Errorf("Clone(%v) = %v, want a deep copy", s, c)
+	}
+
+	var v any = Set[string]{"a"}
+	if c := Clone(v).(Set[string]); &c[0] == &v.(Set[string])[0] {
+		t.Errorf("Clone did not copy an interface value deeply")
+	}
+}
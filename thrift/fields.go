@@ -4,12 +4,15 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"reflect"
 	"sort"
 	"strconv"
 	"strings"
 	"unicode"
 	"unicode/utf8"
+
+	"github.com/itstarsun/go-thrift/encoding/thriftwire"
 )
 
 type isZeroer interface {
@@ -21,6 +24,7 @@ var isZeroerType = reflect.TypeOf((*isZeroer)(nil)).Elem()
 type structFields struct {
 	sorted []structField
 	byID   map[int16]*structField
+	byName map[string]*structField
 }
 
 type structField struct {
@@ -90,19 +94,76 @@ func makeStructFields(t reflect.Type) (structFields, *SemanticError) {
 	fs := structFields{
 		sorted: allFields,
 		byID:   make(map[int16]*structField),
+		byName: make(map[string]*structField),
 	}
 	for i := range fs.sorted {
 		f := &fs.sorted[i]
 		fs.byID[f.id] = f
+		fs.byName[f.name] = f
 	}
 
 	return fs, nil
 }
 
+// lookup returns the field read with the header h,
+// matching it by name if the header has one.
+func (fs *structFields) lookup(h thriftwire.FieldHeader, byName bool) (*structField, bool) {
+	if byName && h.Name != "" {
+		f, ok := fs.byName[h.Name]
+		return f, ok
+	}
+	f, ok := fs.byID[h.ID]
+	return f, ok
+}
+
+// omit reports whether the field with value v is omitted when marshaling.
+func (f *structField) omit(v addressableValue) bool {
+	if f.required {
+		return false
+	}
+	if f.isZero != nil {
+		return f.isZero(v)
+	}
+	return isZeroValue(v.Value)
+}
+
+// isZeroValue reports whether v is the zero value of its type.
+// Unlike reflect.Value.IsZero, a negative zero float is not zero on any
+// Go version, such that generated code can reproduce the same check.
+// Blank struct fields are ignored.
+func isZeroValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return math.Float64bits(v.Float()) == 0
+	case reflect.Array:
+		switch v.Type().Elem().Kind() {
+		case reflect.Float32, reflect.Float64, reflect.Array, reflect.Struct:
+		default:
+			return v.IsZero()
+		}
+		for i := 0; i < v.Len(); i++ {
+			if !isZeroValue(v.Index(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).Name != "_" && !isZeroValue(v.Field(i)) {
+				return false
+			}
+		}
+		return true
+	default:
+		return v.IsZero()
+	}
+}
+
 type fieldOptions struct {
-	id       int16
-	name     string
-	required bool
+	id        int16
+	name      string
+	required  bool
+	sensitive bool
 }
 
 func parseFieldOptions(sf reflect.StructField) (out fieldOptions, ignored bool, err error) {
@@ -160,12 +221,14 @@ func parseFieldOptions(sf reflect.StructField) (out fieldOptions, ignored bool,
 		switch opt {
 		case "required":
 			out.required = true
+		case "sensitive":
+			out.sensitive = true
 		default:
 			// Reject keys that resemble one of the supported options.
 			// This catches invalid mutants such as "omitEmpty" or "omit_empty".
 			normOpt := strings.ReplaceAll(strings.ToLower(opt), "_", "")
 			switch normOpt {
-			case "required":
+			case "required", "sensitive":
 				err = firstError(err, fmt.Errorf("Go struct field %s has invalid appearance of `%s` tag option; specify `%s` instead", sf.Name, opt, normOpt))
 			}
 
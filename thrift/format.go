@@ -0,0 +1,265 @@
+package thrift
+
+import (
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/itstarsun/go-thrift/encoding/thriftwire"
+)
+
+// redacted replaces the value of a sensitive field.
+const redacted = "<redacted>"
+
+// Format returns a human-readable representation of a Go value
+// as it would be seen by [Marshal].
+//
+// Struct fields are rendered with their names and field IDs,
+// and fields that would be omitted by [Marshal] are not rendered.
+// The value of a field with the `sensitive` tag option is masked.
+// The output format is not stable and may change over time.
+func Format(v any) string {
+	var f formatter
+	va, ok := formatRoot(v)
+	if !ok {
+		return "<nil>"
+	}
+	f.format(va.Type(), va)
+	return f.String()
+}
+
+// LogValuer returns a [slog.LogValuer] that renders v as a tree of groups
+// keyed by field names, lists by indexes, and maps by their keys.
+// Like [Format], the value of a field with the `sensitive` tag option is masked.
+func LogValuer(v any) slog.LogValuer {
+	return logValuer{v}
+}
+
+type logValuer struct{ v any }
+
+func (lv logValuer) LogValue() slog.Value {
+	va, ok := formatRoot(lv.v)
+	if !ok {
+		return slog.AnyValue(nil)
+	}
+	return logValue(va.Type(), va)
+}
+
+func (lv logValuer) String() string {
+	return Format(lv.v)
+}
+
+// formatRoot returns an addressable value for v like [Marshal].
+func formatRoot(v any) (addressableValue, bool) {
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() || (rv.Kind() == reflect.Pointer && rv.IsNil()) {
+		return addressableValue{}, false
+	}
+	if rv.Kind() == reflect.Pointer {
+		return addressableValue{rv.Elem()}, true // dereferenced pointer is always addressable
+	}
+	va := newAddressableValue(rv.Type())
+	va.Set(rv)
+	return va, true
+}
+
+type formatter struct {
+	strings.Builder
+}
+
+func (f *formatter) format(t reflect.Type, va addressableValue) {
+	fncs := lookupArshaler(t)
+	switch t.Kind() {
+	case reflect.Bool:
+		f.WriteString(strconv.FormatBool(va.Bool()))
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		f.WriteString(strconv.FormatInt(va.Int(), 10))
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		f.WriteString(strconv.FormatUint(va.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		f.WriteString(strconv.FormatFloat(va.Float(), 'g', -1, t.Bits()))
+	case reflect.String:
+		f.WriteString(strconv.Quote(va.String()))
+	case reflect.Pointer:
+		f.format(t.Elem(), va.indirectOrZero())
+	case reflect.Struct:
+		fields, err := fncs.fields()
+		if err != nil {
+			fmt.Fprint(f, va.Interface())
+			return
+		}
+		f.WriteString(t.Name())
+		f.WriteByte('{')
+		var n int
+		for i := range fields.sorted {
+			sf := &fields.sorted[i]
+			v := va.structField(sf, false)
+			if !v.IsValid() || sf.omit(v) {
+				continue
+			}
+			if n > 0 {
+				f.WriteString(", ")
+			}
+			n++
+			f.WriteString(sf.name)
+			f.WriteByte('(')
+			f.WriteString(strconv.Itoa(int(sf.id)))
+			f.WriteString("): ")
+			if sf.sensitive {
+				f.WriteString(redacted)
+			} else {
+				f.format(sf.typ, v)
+			}
+		}
+		f.WriteByte('}')
+	case reflect.Map:
+		f.WriteByte('{')
+		k := newAddressableValue(t.Key())
+		v := newAddressableValue(t.Elem())
+		for i, key := range sortedMapKeys(va.Value) {
+			if i > 0 {
+				f.WriteString(", ")
+			}
+			k.Set(key)
+			f.format(t.Key(), k)
+			f.WriteString(": ")
+			v.Set(va.MapIndex(key))
+			f.format(t.Elem(), v)
+		}
+		f.WriteByte('}')
+	case reflect.Slice, reflect.Array:
+		if t == uuidType {
+			f.WriteString(formatUUID(va.Interface().([16]byte)))
+			return
+		}
+		if t.Kind() == reflect.Slice && fncs.wireType == thriftwire.String {
+			f.WriteString(strconv.Quote(string(va.Bytes())))
+			return
+		}
+		open, close := byte('['), byte(']')
+		if fncs.wireType == thriftwire.Set {
+			open, close = '{', '}'
+		}
+		f.WriteByte(open)
+		for i := 0; i < va.Len(); i++ {
+			if i > 0 {
+				f.WriteString(", ")
+			}
+			f.format(t.Elem(), addressableValue{va.Index(i)}) // indexed element of addressable value is always addressable
+		}
+		f.WriteByte(close)
+	case reflect.Interface:
+		// Format the dynamic value, so that its sensitive fields are masked.
+		v, ok := interfaceElem(va)
+		if !ok {
+			f.WriteString("<nil>")
+			return
+		}
+		f.format(v.Type(), v)
+	default:
+		fmt.Fprint(f, va.Interface())
+	}
+}
+
+// interfaceElem returns an addressable copy of the dynamic value of
+// the interface va, reporting false if va is nil.
+func interfaceElem(va addressableValue) (addressableValue, bool) {
+	if va.IsNil() {
+		return addressableValue{}, false
+	}
+	e := va.Elem()
+	v := newAddressableValue(e.Type())
+	v.Set(e)
+	return v, true
+}
+
+func formatUUID(v [16]byte) string {
+	var buf [36]byte
+	hex.Encode(buf[0:8], v[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], v[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], v[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], v[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], v[10:])
+	return string(buf[:])
+}
+
+func logValue(t reflect.Type, va addressableValue) slog.Value {
+	fncs := lookupArshaler(t)
+	switch t.Kind() {
+	case reflect.Bool:
+		return slog.BoolValue(va.Bool())
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return slog.Int64Value(va.Int())
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return slog.Uint64Value(va.Uint())
+	case reflect.Float32, reflect.Float64:
+		return slog.Float64Value(va.Float())
+	case reflect.String:
+		return slog.StringValue(va.String())
+	case reflect.Pointer:
+		return logValue(t.Elem(), va.indirectOrZero())
+	case reflect.Struct:
+		fields, err := fncs.fields()
+		if err != nil {
+			return slog.AnyValue(va.Interface())
+		}
+		var attrs []slog.Attr
+		for i := range fields.sorted {
+			sf := &fields.sorted[i]
+			v := va.structField(sf, false)
+			if !v.IsValid() || sf.omit(v) {
+				continue
+			}
+			if sf.sensitive {
+				attrs = append(attrs, slog.String(sf.name, redacted))
+			} else {
+				attrs = append(attrs, slog.Attr{Key: sf.name, Value: logValue(sf.typ, v)})
+			}
+		}
+		return slog.GroupValue(attrs...)
+	case reflect.Map:
+		k := newAddressableValue(t.Key())
+		v := newAddressableValue(t.Elem())
+		var attrs []slog.Attr
+		for _, key := range sortedMapKeys(va.Value) {
+			k.Set(key)
+			var f formatter
+			if t.Key().Kind() == reflect.String {
+				f.WriteString(k.String())
+			} else {
+				f.format(t.Key(), k)
+			}
+			v.Set(va.MapIndex(key))
+			attrs = append(attrs, slog.Attr{Key: f.String(), Value: logValue(t.Elem(), v)})
+		}
+		return slog.GroupValue(attrs...)
+	case reflect.Slice, reflect.Array:
+		if t == uuidType {
+			return slog.StringValue(formatUUID(va.Interface().([16]byte)))
+		}
+		if t.Kind() == reflect.Slice && fncs.wireType == thriftwire.String {
+			return slog.StringValue(string(va.Bytes()))
+		}
+		attrs := make([]slog.Attr, va.Len())
+		for i := range attrs {
+			v := addressableValue{va.Index(i)} // indexed element of addressable value is always addressable
+			attrs[i] = slog.Attr{Key: strconv.Itoa(i), Value: logValue(t.Elem(), v)}
+		}
+		return slog.GroupValue(attrs...)
+	case reflect.Interface:
+		v, ok := interfaceElem(va)
+		if !ok {
+			return slog.AnyValue(nil)
+		}
+		return logValue(v.Type(), v)
+	default:
+		return slog.AnyValue(va.Interface())
+	}
+}
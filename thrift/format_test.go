@@ -0,0 +1,118 @@
+package thrift
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+type aLoginRequest struct {
+	User     string            `thrift:"1"`
+	Password string            `thrift:"2,sensitive"`
+	Tags     Set[string]       `thrift:"3"`
+	Extra    map[string][]byte `thrift:"4"`
+	ID       [16]byte          `thrift:"5,required"`
+}
+
+func TestFormat(t *testing.T) {
+	for _, tt := range []struct {
+		in   any
+		want string
+	}{{
+		in:   nil,
+		want: "<nil>",
+	}, {
+		in:   (*aStruct)(nil),
+		want: "<nil>",
+	}, {
+		in:   int32(-1),
+		want: "-1",
+	}, {
+		in:   []*string{ptr("hello"), nil},
+		want: `["hello", ""]`,
+	}, {
+		in:   map[int16]bool{2: false, 1: true},
+		want: `{1: true, 2: false}`,
+	}, {
+		in:   &aStruct{String: "hello", List: []*aStruct{{}}},
+		want: `aStruct{String(1): "hello", List(2): [aStruct{}]}`,
+	}, {
+		in: aLoginRequest{
+			User:     "gopher",
+			Password: "hunter2",
+			Tags:     Set[string]{"a"},
+			Extra:    map[string][]byte{"k": []byte("v")},
+		},
+		want: `aLoginRequest{User(1): "gopher", Password(2): <redacted>, Tags(3): {"a"}, Extra(4): {"k": "v"}, ID(5): 00000000-0000-0000-0000-000000000000}`,
+	}} {
+		if got := Format(tt.in); got != tt.want {
+			t.Errorf("Format(%#v):\ngot  %s\nwant %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestLogValuer(t *testing.T) {
+	var b bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&b, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+	logger.Info("login", "request", LogValuer(&aLoginRequest{
+		User:     "gopher",
+		Password: "hunter2",
+		Tags:     Set[string]{"a", "b"},
+	}))
+	got := strings.TrimSpace(b.String())
+	want := `level=INFO msg=login request.User=gopher request.Password=<redacted> request.Tags.0=a request.Tags.1=b request.ID=00000000-0000-0000-0000-000000000000`
+	if got != want {
+		t.Errorf("\ngot  %s\nwant %s", got, want)
+	}
+	if strings.Contains(got, "hunter2") {
+		t.Errorf("sensitive field is leaked")
+	}
+}
+
+type aSecret struct {
+	Name  string `thrift:"1"`
+	Token string `thrift:"2,sensitive"`
+}
+
+func (s aSecret) String() string { return s.Name + ":" + s.Token }
+
+type aVault struct {
+	ByName map[string]*aSecret `thrift:"1"`
+	All    []aSecret           `thrift:"2"`
+	Any    any                 `thrift:"3"`
+	Fixed  [1]aSecret          `thrift:"4"`
+}
+
+func TestFormatNestedSensitive(t *testing.T) {
+	s := aSecret{Name: "gopher", Token: "hunter2"}
+	for _, v := range []any{
+		map[string]*aSecret{"a": &s},
+		[]aSecret{s},
+		[]any{&s},
+		&aVault{
+			ByName: map[string]*aSecret{"a": &s},
+			All:    []aSecret{s},
+			Any:    &s,
+			Fixed:  [1]aSecret{s},
+		},
+	} {
+		got := Format(v)
+		if strings.Contains(got, "hunter2") || !strings.Contains(got, redacted) {
+			t.Errorf("Format(%T) = %s, want the sensitive field masked", v, got)
+		}
+
+		var b bytes.Buffer
+		slog.New(slog.NewTextHandler(&b, nil)).Info("", "v", LogValuer(v))
+		if got := b.String(); strings.Contains(got, "hunter2") || !strings.Contains(got, redacted) {
+			t.Errorf("LogValuer(%T) logged %s, want the sensitive field masked", v, got)
+		}
+	}
+}
@@ -0,0 +1,284 @@
+package thrift
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"io"
+	"math"
+	"sort"
+
+	"github.com/itstarsun/go-thrift/encoding/thriftwire"
+)
+
+// Hash writes a canonical encoding of a Go value into h.
+//
+// The canonical encoding is similar to the Thrift Binary protocol
+// encoding, except that struct fields are sorted by their field IDs,
+// and the elements of sets and the entries of maps are sorted by their
+// canonical encodings. Fields are omitted like [Marshal]. Since fields are
+// sorted regardless of the order in which they are written, a value whose
+// [Marshaler] writes fields in another order is encoded like an equal value
+// marshaled reflectively. Thus, values that are [Equal] have the same
+// canonical encoding, which is stable across processes and versions of Go.
+func Hash(h hash.Hash, v any) error {
+	var w hashWriter
+	w.reset()
+	if err := Marshal(&w, v); err != nil {
+		return err
+	}
+	_, err := h.Write(w.frames[0].buf)
+	return err
+}
+
+// hashFrame is a Thrift value being written by hashWriter.
+type hashFrame struct {
+	buf []byte
+
+	// entries is the fields of a struct, or the elements of a set or
+	// the entries of a map, which are sorted at the end of the value.
+	// It is nil for other values.
+	entries  [][]byte
+	isStruct bool
+	arity    int // number of values per entry; 1 for sets, 2 for maps
+	numValue int // number of values written into buf
+}
+
+// hashWriter is a [thriftwire.Writer] that writes the canonical encoding
+// used by [Hash].
+type hashWriter struct {
+	frames []hashFrame
+	tmp    [8]byte
+}
+
+var _ thriftwire.Writer = (*hashWriter)(nil)
+
+func (w *hashWriter) reset() {
+	w.frames = append(w.frames[:0], hashFrame{})
+}
+
+func (w *hashWriter) top() *hashFrame {
+	return &w.frames[len(w.frames)-1]
+}
+
+func (w *hashWriter) write(p ...byte) {
+	f := w.top()
+	f.buf = append(f.buf, p...)
+}
+
+func (w *hashWriter) writeString(s string) {
+	f := w.top()
+	f.buf = append(f.buf, s...)
+}
+
+// done marks the end of a value.
+func (w *hashWriter) done() {
+	f := w.top()
+	if f.arity == 0 {
+		return
+	}
+	f.numValue++
+	if f.numValue == f.arity {
+		f.entries = append(f.entries, f.buf)
+		f.buf = nil
+		f.numValue = 0
+	}
+}
+
+func (w *hashWriter) push(f hashFrame) {
+	w.frames = append(w.frames, f)
+}
+
+func (w *hashWriter) pop() error {
+	if len(w.frames) <= 1 {
+		return errors.New("unmatched end of a Thrift value")
+	}
+	f := w.top()
+	w.frames = w.frames[:len(w.frames)-1]
+	buf := f.buf
+	switch {
+	case f.isStruct:
+		// Each field starts with its type and big-endian ID.
+		sort.SliceStable(f.entries, func(i, j int) bool {
+			return int16(binary.BigEndian.Uint16(f.entries[i][1:])) < int16(binary.BigEndian.Uint16(f.entries[j][1:]))
+		})
+		for _, e := range f.entries {
+			buf = append(buf, e...)
+		}
+		buf = append(buf, byte(thriftwire.Stop))
+	case f.arity != 0:
+		sort.Slice(f.entries, func(i, j int) bool {
+			return bytes.Compare(f.entries[i], f.entries[j]) < 0
+		})
+		for _, e := range f.entries {
+			buf = append(buf, e...)
+		}
+	}
+	if p := w.top(); p.isStruct {
+		// The end of a field.
+		p.entries = append(p.entries, buf)
+		return nil
+	}
+	w.write(buf...)
+	w.done()
+	return nil
+}
+
+func (w *hashWriter) writeType(t thriftwire.Type) {
+	w.write(byte(t))
+}
+
+func (w *hashWriter) writeSize(n int) {
+	binary.BigEndian.PutUint32(w.tmp[:4], uint32(n))
+	w.write(w.tmp[:4]...)
+}
+
+func (w *hashWriter) WriteMessageBegin(h thriftwire.MessageHeader) error {
+	w.write(byte(h.Type))
+	w.writeSize(len(h.Name))
+	w.writeString(h.Name)
+	binary.BigEndian.PutUint32(w.tmp[:4], uint32(h.ID))
+	w.write(w.tmp[:4]...)
+	return nil
+}
+
+func (w *hashWriter) WriteMessageEnd() error {
+	return nil
+}
+
+func (w *hashWriter) WriteStructBegin(thriftwire.StructHeader) error {
+	w.push(hashFrame{isStruct: true})
+	return nil
+}
+
+func (w *hashWriter) WriteStructEnd() error {
+	if !w.top().isStruct {
+		return errors.New("unmatched end of a Thrift struct")
+	}
+	return w.pop()
+}
+
+func (w *hashWriter) WriteFieldBegin(h thriftwire.FieldHeader) error {
+	if !w.top().isStruct {
+		return errors.New("field outside of a Thrift struct")
+	}
+	w.push(hashFrame{})
+	w.writeType(h.Type)
+	binary.BigEndian.PutUint16(w.tmp[:2], uint16(h.ID))
+	w.write(w.tmp[:2]...)
+	return nil
+}
+
+func (w *hashWriter) WriteFieldEnd() error {
+	if len(w.frames) < 2 || !w.frames[len(w.frames)-2].isStruct {
+		return errors.New("unmatched end of a Thrift field")
+	}
+	return w.pop()
+}
+
+func (w *hashWriter) WriteMapBegin(h thriftwire.MapHeader) error {
+	w.writeType(h.Key)
+	w.writeType(h.Value)
+	w.writeSize(h.Size)
+	w.push(hashFrame{arity: 2})
+	return nil
+}
+
+func (w *hashWriter) WriteMapEnd() error {
+	return w.pop()
+}
+
+func (w *hashWriter) WriteSetBegin(h thriftwire.SetHeader) error {
+	w.writeType(h.Element)
+	w.writeSize(h.Size)
+	w.push(hashFrame{arity: 1})
+	return nil
+}
+
+func (w *hashWriter) WriteSetEnd() error {
+	return w.pop()
+}
+
+func (w *hashWriter) WriteListBegin(h thriftwire.ListHeader) error {
+	w.writeType(h.Element)
+	w.writeSize(h.Size)
+	w.push(hashFrame{})
+	return nil
+}
+
+func (w *hashWriter) WriteListEnd() error {
+	return w.pop()
+}
+
+func (w *hashWriter) WriteBool(v bool) error {
+	if v {
+		w.write(1)
+	} else {
+		w.write(0)
+	}
+	w.done()
+	return nil
+}
+
+func (w *hashWriter) WriteByte(v byte) error {
+	w.write(v)
+	w.done()
+	return nil
+}
+
+func (w *hashWriter) WriteDouble(v float64) error {
+	binary.BigEndian.PutUint64(w.tmp[:8], math.Float64bits(v))
+	w.write(w.tmp[:8]...)
+	w.done()
+	return nil
+}
+
+func (w *hashWriter) WriteI16(v int16) error {
+	binary.BigEndian.PutUint16(w.tmp[:2], uint16(v))
+	w.write(w.tmp[:2]...)
+	w.done()
+	return nil
+}
+
+func (w *hashWriter) WriteI32(v int32) error {
+	binary.BigEndian.PutUint32(w.tmp[:4], uint32(v))
+	w.write(w.tmp[:4]...)
+	w.done()
+	return nil
+}
+
+func (w *hashWriter) WriteI64(v int64) error {
+	binary.BigEndian.PutUint64(w.tmp[:8], uint64(v))
+	w.write(w.tmp[:8]...)
+	w.done()
+	return nil
+}
+
+func (w *hashWriter) WriteString(v string) error {
+	w.writeSize(len(v))
+	w.writeString(v)
+	w.done()
+	return nil
+}
+
+func (w *hashWriter) WriteBytes(v []byte) error {
+	w.writeSize(len(v))
+	w.write(v...)
+	w.done()
+	return nil
+}
+
+func (w *hashWriter) WriteUUID(v *[16]byte) error {
+	w.write(v[:]...)
+	w.done()
+	return nil
+}
+
+func (w *hashWriter) Flush() error {
+	return nil
+}
+
+func (w *hashWriter) Reset(io.Writer) {
+	w.reset()
+}
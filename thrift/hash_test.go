@@ -0,0 +1,110 @@
+package thrift
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/itstarsun/go-thrift/encoding/thriftwire"
+)
+
+func hashString(t *testing.T, v any) string {
+	t.Helper()
+	h := sha256.New()
+	if err := Hash(h, v); err != nil {
+		t.Fatal(err)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func TestHash(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		x, y any
+		want bool
+	}{{
+		name: "SetOrder",
+		x:    Set[string]{"a", "b", "c"},
+		y:    Set[string]{"c", "a", "b"},
+		want: true,
+	}, {
+		name: "ListOrder",
+		x:    List[string]{"a", "b", "c"},
+		y:    List[string]{"c", "a", "b"},
+		want: false,
+	}, {
+		name: "MapOfStructs",
+		x:    map[string]*aStruct{"a": {String: "a"}, "b": {List: []*aStruct{{}}}},
+		y:    map[string]*aStruct{"b": {List: []*aStruct{nil}}, "a": {String: "a"}},
+		want: true,
+	}, {
+		name: "SetOfSets",
+		x:    Set[Set[int32]]{{1, 2}, {3}},
+		y:    Set[Set[int32]]{{3}, {2, 1}},
+		want: true,
+	}, {
+		name: "OmittedField",
+		x:    anOptionalField{},
+		y:    anOptionalField{Strings: []string{}},
+		want: false,
+	}, {
+		name: "NilAndEmptyList",
+		x:    []string(nil),
+		y:    []string{},
+		want: true,
+	}, {
+		name: "DifferentStructs",
+		x:    aStruct{String: "a"},
+		y:    aStruct{String: "b"},
+		want: false,
+	}} {
+		t.Run(tt.name, func(t *testing.T) {
+			hx := hashString(t, tt.x)
+			hy := hashString(t, tt.y)
+			if got := hx == hy; got != tt.want {
+				t.Errorf("Hash(%v) == Hash(%v) = %t, want %t", tt.x, tt.y, got, tt.want)
+			}
+			if got := Equal(tt.x, tt.y); got != tt.want {
+				t.Errorf("Equal(%v, %v) = %t, want %t", tt.x, tt.y, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHashStable(t *testing.T) {
+	// The canonical encoding must never change.
+	v := &aLoginRequest{
+		User:  "gopher",
+		Tags:  Set[string]{"b", "a"},
+		Extra: map[string][]byte{"y": nil, "x": []byte("x")},
+	}
+	const want = "90c4a00f14902daac61f0ac154454bd6f3b886b83e59727385445227cf2895f2"
+	if got := hashString(t, v); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+// aReversedStruct marshals like aStruct with its fields in reverse order.
+type aReversedStruct aStruct
+
+func (s *aReversedStruct) MarshalThrift(w thriftwire.Writer) error {
+	w.WriteStructBegin(thriftwire.StructHeader{})
+	w.WriteFieldBegin(thriftwire.FieldHeader{ID: 2, Type: thriftwire.List})
+	w.WriteListBegin(thriftwire.ListHeader{Element: thriftwire.Struct, Size: len(s.List)})
+	for _, e := range s.List {
+		(*aReversedStruct)(e).MarshalThrift(w)
+	}
+	w.WriteListEnd()
+	w.WriteFieldEnd()
+	w.WriteFieldBegin(thriftwire.FieldHeader{ID: 1, Type: thriftwire.String})
+	w.WriteString(s.String)
+	w.WriteFieldEnd()
+	return w.WriteStructEnd()
+}
+
+func TestHashFieldOrder(t *testing.T) {
+	v := &aStruct{String: "a", List: []*aStruct{{String: "b", List: []*aStruct{}}}}
+	if got, want := hashString(t, (*aReversedStruct)(v)), hashString(t, v); got != want {
+		t.Errorf("got %s for fields in reverse order, want %s", got, want)
+	}
+}
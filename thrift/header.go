@@ -0,0 +1,102 @@
+package thrift
+
+import (
+	"context"
+	"sync"
+
+	"github.com/itstarsun/go-thrift/encoding/thriftwire"
+)
+
+// A HeaderReader is a [thriftwire.Reader] of a transport that carries
+// headers with messages, such as THeader.
+//
+// Header returns the headers of the message last read, or nil if there are
+// none. The returned map must not be modified, and is not modified by the
+// HeaderReader afterwards.
+type HeaderReader interface {
+	thriftwire.Reader
+	Header() map[string]string
+}
+
+// A HeaderWriter is a [thriftwire.Writer] of a transport that carries
+// headers with messages, such as THeader.
+//
+// SetHeader sets the headers of the next message written.
+// The map must not be modified until the message is flushed.
+type HeaderWriter interface {
+	thriftwire.Writer
+	SetHeader(map[string]string)
+}
+
+type (
+	callHeaderKey  struct{}
+	replyHeaderKey struct{}
+	serverCallKey  struct{}
+)
+
+// WithCallHeader returns a copy of ctx with the headers sent with the calls
+// made with it by a [ClientConn] whose writer is a [HeaderWriter].
+func WithCallHeader(ctx context.Context, h map[string]string) context.Context {
+	return context.WithValue(ctx, callHeaderKey{}, h)
+}
+
+// WithReplyHeader returns a copy of ctx that makes a [ClientConn] whose
+// reader is a [HeaderReader] store the headers of the reply to each call
+// made with it into *h.
+func WithReplyHeader(ctx context.Context, h *map[string]string) context.Context {
+	return context.WithValue(ctx, replyHeaderKey{}, h)
+}
+
+func callHeader(ctx context.Context) map[string]string {
+	h, _ := ctx.Value(callHeaderKey{}).(map[string]string)
+	return h
+}
+
+func replyHeader(ctx context.Context) *map[string]string {
+	h, _ := ctx.Value(replyHeaderKey{}).(*map[string]string)
+	return h
+}
+
+// A serverCall holds the headers of a call being processed by a [Processor].
+type serverCall struct {
+	header map[string]string
+
+	mu          sync.Mutex
+	replyHeader map[string]string
+}
+
+// CallHeader returns the headers of the call being processed by
+// a [Processor] with ctx, or nil if there are none.
+// The returned map must not be modified.
+func CallHeader(ctx context.Context) map[string]string {
+	if c, ok := ctx.Value(serverCallKey{}).(*serverCall); ok {
+		return c.header
+	}
+	return nil
+}
+
+// SetReplyHeader sets the header key to value in the reply to the call being
+// processed by a [Processor] with ctx, which is sent if the writer of the
+// reply is a [HeaderWriter]. It reports whether ctx is of such a call.
+func SetReplyHeader(ctx context.Context, key, value string) bool {
+	c, ok := ctx.Value(serverCallKey{}).(*serverCall)
+	if !ok {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.replyHeader == nil {
+		c.replyHeader = make(map[string]string)
+	}
+	c.replyHeader[key] = value
+	return true
+}
+
+// setReplyHeader sets the headers of the reply to c if w is a [HeaderWriter].
+func (c *serverCall) setReplyHeader(w thriftwire.Writer) {
+	if w, ok := w.(HeaderWriter); ok {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		w.SetHeader(c.replyHeader)
+	}
+}
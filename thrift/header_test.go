@@ -0,0 +1,54 @@
+package thrift
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/itstarsun/go-thrift/encoding/thriftheader"
+)
+
+func TestHeader(t *testing.T) {
+	p := new(Processor)
+	p.Handle("echo", (*echoArgs)(nil), (*echoResult)(nil), func(ctx context.Context, args, result any) error {
+		msg := CallHeader(ctx)["message"]
+		result.(*echoResult).Success = &msg
+		if !SetReplyHeader(ctx, "reply", args.(*echoArgs).Message) {
+			t.Error("SetReplyHeader reported no call")
+		}
+		return nil
+	})
+	proto := &thriftheader.Protocol{ProtocolID: thriftheader.CompactProtocol}
+	addr, _ := startServer(t, &Server{Processor: p, Protocol: proto})
+
+	for _, opts := range [][]ClientOption{nil, {Pipelined()}} {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := NewClient(conn, proto, opts...)
+		defer c.Close()
+
+		var reply map[string]string
+		ctx := WithCallHeader(context.Background(), map[string]string{"message": "from header"})
+		ctx = WithReplyHeader(ctx, &reply)
+		var result echoResult
+		if err := c.Call(ctx, "echo", &echoArgs{Message: "from args"}, &result); err != nil {
+			t.Fatal(err)
+		}
+		if *result.Success != "from header" {
+			t.Errorf("got %q, want %q", *result.Success, "from header")
+		}
+		if want := map[string]string{"reply": "from args"}; !reflect.DeepEqual(reply, want) {
+			t.Errorf("got reply header %v, want %v", reply, want)
+		}
+	}
+
+	if CallHeader(context.Background()) != nil {
+		t.Error("CallHeader returned headers outside of a call")
+	}
+	if SetReplyHeader(context.Background(), "k", "v") {
+		t.Error("SetReplyHeader reported a call outside of a call")
+	}
+}
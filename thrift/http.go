@@ -0,0 +1,213 @@
+package thrift
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/itstarsun/go-thrift/encoding/thriftwire"
+)
+
+// httpContentType is the media type of Thrift messages sent over HTTP.
+const httpContentType = "application/x-thrift"
+
+// An HTTPError is returned by [HTTPClient.Call] if the server responds
+// with a status other than 200 OK or 204 No Content.
+type HTTPError struct {
+	StatusCode int    // e.g. 503
+	Status     string // e.g. "503 Service Unavailable"
+}
+
+func (e *HTTPError) Error() string {
+	return errorPrefix + "HTTP " + e.Status
+}
+
+// An HTTPClient is a [Client] making each call as an HTTP POST request,
+// with the call message as the body of the request, and the reply message
+// as the body of the response.
+//
+// The context of a call is the context of its request, such that its
+// deadline and cancellation apply to the request. Calls are independent,
+// so a failed call does not affect subsequent calls, and an HTTPClient
+// is safe for concurrent use without [Pipelined].
+type HTTPClient struct {
+	// URL is the URL of the endpoint, typically served by an [HTTPHandler].
+	URL string
+
+	// Protocol encodes the messages.
+	Protocol thriftwire.Protocol
+
+	// Client makes the requests. If nil, http.DefaultClient is used.
+	Client *http.Client
+
+	seqID atomic.Int32
+}
+
+var _ Client = (*HTTPClient)(nil)
+
+// Call implements [Client] by sending a call message with the args,
+// and unmarshaling the reply into the result. If the result is nil,
+// a one-way message is sent, and the body of the response is ignored.
+//
+// If the server replies with an exception, Call returns it as an error.
+// If the server responds with an unexpected status, Call returns
+// an *[HTTPError]. The headers set by [WithCallHeader] and requested by
+// [WithReplyHeader] are handled as by [ClientConn.Call].
+func (c *HTTPClient) Call(ctx context.Context, method string, args, result any) error {
+	mh := thriftwire.MessageHeader{
+		Name: method,
+		Type: thriftwire.Call,
+		ID:   c.seqID.Add(1),
+	}
+	if result == nil {
+		mh.Type = thriftwire.OneWay
+	}
+
+	var body bytes.Buffer
+	w := c.Protocol.NewWriter(&body)
+	if w, ok := w.(HeaderWriter); ok {
+		w.SetHeader(callHeader(ctx))
+	}
+	if err := writeMessage(w, mh, args); err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", httpContentType)
+	req.Header.Set("Accept", httpContentType)
+
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent:
+	default:
+		return &HTTPError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+	if result == nil {
+		return nil
+	}
+
+	r := c.Protocol.NewReader(resp.Body)
+	rh, err := r.ReadMessageBegin()
+	if err != nil {
+		return httpReadError(ctx, err)
+	}
+	if r, ok := r.(HeaderReader); ok {
+		if h := replyHeader(ctx); h != nil {
+			*h = r.Header()
+		}
+	}
+	exc, err := readReply(r, rh, mh, result)
+	if err != nil {
+		return httpReadError(ctx, err)
+	}
+	if exc != nil {
+		return exc
+	}
+	return nil
+}
+
+// httpReadError returns the error of reading the response to a call,
+// which is the error of ctx if it is done.
+func httpReadError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// An HTTPHandler is an [http.Handler] serving Thrift RPC calls sent as
+// HTTP POST requests, such as by [HTTPClient].
+//
+// Each request carries a call message, which is processed with the context
+// of the request, and the reply, if any, is written as the body of the
+// response. A request that cannot be read as a message is answered with
+// 400 Bad Request, and a reply that cannot be written with
+// 500 Internal Server Error.
+type HTTPHandler struct {
+	// Processor processes the calls.
+	Processor MessageProcessor
+
+	// Protocol encodes the messages.
+	Protocol thriftwire.Protocol
+}
+
+func (h *HTTPHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	// The reply is buffered, so that the status can reflect an error
+	// that occurs after the processor has begun writing it.
+	var reply bytes.Buffer
+	// The reader and writer share the exchange, so that a duplex protocol
+	// such as THeader can reply in the protocol of the request.
+	pr, pw := thriftwire.NewReadWriter(h.Protocol, struct {
+		io.Reader
+		io.Writer
+	}{r.Body, &reply})
+	rw := &httpReplyWriter{Writer: pw}
+	err := h.Processor.Process(r.Context(), pr, rw)
+	if err != nil {
+		switch {
+		case r.Context().Err() != nil:
+			// The client is gone.
+		case !rw.begun || rw.flushed:
+			// The call could not be read, though an exception
+			// may have been written in reply.
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			// Do not expose the internals of the server to the client.
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+		return
+	}
+	w.Header().Set("Content-Type", httpContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(reply.Len()))
+	w.Write(reply.Bytes())
+}
+
+// An httpReplyWriter records the progress of writing a reply, which tells
+// the errors of writing the reply from those of reading the call.
+type httpReplyWriter struct {
+	thriftwire.Writer
+	begun   bool // whether a reply has begun
+	flushed bool // whether the reply has been written
+}
+
+func (w *httpReplyWriter) WriteMessageBegin(h thriftwire.MessageHeader) error {
+	w.begun, w.flushed = true, false
+	return w.Writer.WriteMessageBegin(h)
+}
+
+func (w *httpReplyWriter) Flush() error {
+	err := w.Writer.Flush()
+	w.flushed = err == nil
+	return err
+}
+
+// SetHeader implements [HeaderWriter] if the underlying Writer does.
+func (w *httpReplyWriter) SetHeader(h map[string]string) {
+	if hw, ok := w.Writer.(HeaderWriter); ok {
+		hw.SetHeader(h)
+	}
+}
@@ -0,0 +1,219 @@
+package thrift
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/itstarsun/go-thrift/encoding/thriftbinary"
+	"github.com/itstarsun/go-thrift/encoding/thriftcompact"
+	"github.com/itstarsun/go-thrift/encoding/thriftheader"
+	"github.com/itstarsun/go-thrift/encoding/thriftjson"
+	"github.com/itstarsun/go-thrift/encoding/thriftwire"
+)
+
+func TestHTTP(t *testing.T) {
+	for _, proto := range []thriftwire.Protocol{
+		thriftbinary.Protocol,
+		thriftcompact.Protocol,
+		thriftjson.Protocol,
+		&thriftheader.Protocol{},
+	} {
+		t.Run(fmt.Sprint(proto), func(t *testing.T) {
+			var calls []string
+			srv := httptest.NewServer(&HTTPHandler{Processor: newEchoProcessor(&calls), Protocol: proto})
+			defer srv.Close()
+			c := &HTTPClient{URL: srv.URL, Protocol: proto, Client: srv.Client()}
+			ctx := context.Background()
+
+			var result echoResult
+			if err := c.Call(ctx, "echo", &echoArgs{Message: "hello"}, &result); err != nil {
+				t.Fatal(err)
+			}
+			if result.Success == nil || *result.Success != "hello" {
+				t.Fatalf("got %s, want hello", Format(&result))
+			}
+
+			err := c.Call(ctx, "echo", &echoArgs{}, &result)
+			var exc *ApplicationError
+			if !errors.As(err, &exc) || exc.Type != InternalError {
+				t.Fatalf("got %v, want an internal error", err)
+			}
+			if err := c.Call(ctx, "missing", &echoArgs{}, &result); !errors.Is(err, ErrUnknownMethod) {
+				t.Fatalf("got %v, want an unknown method error", err)
+			}
+
+			if err := c.Call(ctx, "log", &echoArgs{Message: "logged"}, nil); err != nil {
+				t.Fatal(err)
+			}
+			if len(calls) != 1 || calls[0] != "logged" {
+				t.Fatalf("got one-way calls %q, want [logged]", calls)
+			}
+		})
+	}
+}
+
+func TestHTTPHeader(t *testing.T) {
+	p := new(Processor)
+	p.Handle("echo", (*echoArgs)(nil), (*echoResult)(nil), func(ctx context.Context, args, result any) error {
+		msg := CallHeader(ctx)["message"]
+		result.(*echoResult).Success = &msg
+		SetReplyHeader(ctx, "reply", args.(*echoArgs).Message)
+		return nil
+	})
+	proto := &thriftheader.Protocol{ProtocolID: thriftheader.CompactProtocol}
+	srv := httptest.NewServer(&HTTPHandler{Processor: p, Protocol: proto})
+	defer srv.Close()
+	c := &HTTPClient{URL: srv.URL, Protocol: proto}
+
+	var reply map[string]string
+	ctx := WithCallHeader(context.Background(), map[string]string{"message": "from header"})
+	ctx = WithReplyHeader(ctx, &reply)
+	var result echoResult
+	if err := c.Call(ctx, "echo", &echoArgs{Message: "from args"}, &result); err != nil {
+		t.Fatal(err)
+	}
+	if *result.Success != "from header" {
+		t.Errorf("got %q, want %q", *result.Success, "from header")
+	}
+	if want := map[string]string{"reply": "from args"}; !reflect.DeepEqual(reply, want) {
+		t.Errorf("got reply header %v, want %v", reply, want)
+	}
+}
+
+func TestHTTPHeaderMirror(t *testing.T) {
+	h := &HTTPHandler{Processor: newEchoProcessor(new([]string)), Protocol: &thriftheader.Protocol{}}
+	var body bytes.Buffer
+	mh := thriftwire.MessageHeader{Name: "echo", Type: thriftwire.Call, ID: 1}
+	w := (&thriftheader.Protocol{ProtocolID: thriftheader.CompactProtocol}).NewWriter(&body)
+	if err := writeMessage(w, mh, &echoArgs{Message: "hello"}); err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", &body))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	// length(4) magic(2) flags(2) sequence ID(4) header size(2) protocol ID
+	reply := rec.Body.Bytes()
+	if len(reply) < 15 || thriftheader.ProtocolID(reply[14]) != thriftheader.CompactProtocol {
+		t.Fatalf("got reply % x, want the compact protocol", reply)
+	}
+	r := (&thriftheader.Protocol{}).NewReader(bytes.NewReader(reply))
+	rh, err := r.ReadMessageBegin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result echoResult
+	if exc, err := readReply(r, rh, mh, &result); err != nil || exc != nil {
+		t.Fatalf("got %v, %v", exc, err)
+	}
+	if result.Success == nil || *result.Success != "hello" {
+		t.Fatalf("got %s, want hello", Format(&result))
+	}
+}
+
+func TestHTTPClientStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ct := r.Header.Get("Content-Type"); ct != "application/x-thrift" {
+			t.Errorf("got Content-Type %q", ct)
+		}
+		http.Error(w, "overloaded", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	c := &HTTPClient{URL: srv.URL, Protocol: thriftbinary.Protocol}
+
+	var result echoResult
+	err := c.Call(context.Background(), "echo", &echoArgs{Message: "hello"}, &result)
+	var herr *HTTPError
+	if !errors.As(err, &herr) || herr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("got %v, want an HTTP error with status 503", err)
+	}
+	if got, want := err.Error(), "thrift: HTTP 503 Service Unavailable"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestHTTPClientDeadline(t *testing.T) {
+	canceled := make(chan bool, 1)
+	p := new(Processor)
+	p.Handle("echo", (*echoArgs)(nil), (*echoResult)(nil), func(ctx context.Context, args, result any) error {
+		select {
+		case <-ctx.Done():
+			canceled <- true
+		case <-time.After(5 * time.Second):
+			canceled <- false
+		}
+		return ctx.Err()
+	})
+	srv := httptest.NewServer(&HTTPHandler{Processor: p, Protocol: thriftbinary.Protocol})
+	defer srv.Close()
+	c := &HTTPClient{URL: srv.URL, Protocol: thriftbinary.Protocol}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	var result echoResult
+	if err := c.Call(ctx, "echo", &echoArgs{Message: "hello"}, &result); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+	if !<-canceled {
+		t.Error("the call was not canceled on the server")
+	}
+}
+
+func TestHTTPHandlerErrors(t *testing.T) {
+	h := &HTTPHandler{Processor: newEchoProcessor(new([]string)), Protocol: thriftbinary.Protocol}
+	for _, tt := range []struct {
+		method string
+		body   string
+		want   int
+	}{
+		{http.MethodGet, "", http.StatusMethodNotAllowed},
+		{http.MethodPost, "", http.StatusBadRequest},
+		{http.MethodPost, "not a message", http.StatusBadRequest},
+	} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(tt.method, "/", strings.NewReader(tt.body)))
+		if w.Code != tt.want {
+			t.Errorf("%s %q: got status %d, want %d", tt.method, tt.body, w.Code, tt.want)
+		}
+	}
+}
+
+// anInvalidResult is a result that cannot be marshaled.
+type anInvalidResult struct {
+	Success *string `thrift:"0"`
+}
+
+func (*anInvalidResult) Validate() error {
+	return errors.New("internal details")
+}
+
+func TestHTTPHandlerWriteError(t *testing.T) {
+	p := new(Processor)
+	p.Handle("echo", (*echoArgs)(nil), (*anInvalidResult)(nil), func(ctx context.Context, args, result any) error {
+		return nil
+	})
+	h := &HTTPHandler{Processor: p, Protocol: thriftbinary.Protocol}
+	var body bytes.Buffer
+	mh := thriftwire.MessageHeader{Name: "echo", Type: thriftwire.Call, ID: 1}
+	if err := writeMessage(thriftbinary.Protocol.NewWriter(&body), mh, &echoArgs{Message: "hello"}); err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", &body))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("got status %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+	if strings.Contains(w.Body.String(), "internal details") {
+		t.Errorf("got body %q, want the error hidden", w.Body.String())
+	}
+}
@@ -0,0 +1,58 @@
+package thrift
+
+import (
+	"context"
+
+	"github.com/itstarsun/go-thrift/encoding/thriftwire"
+)
+
+// The ClientFunc type is an adapter to allow the use of ordinary functions
+// as a [Client]. If f is a function with the appropriate signature,
+// ClientFunc(f) is a Client that calls f.
+type ClientFunc func(ctx context.Context, method string, args, result any) error
+
+// Call calls f(ctx, method, args, result).
+func (f ClientFunc) Call(ctx context.Context, method string, args, result any) error {
+	return f(ctx, method, args, result)
+}
+
+// A ClientMiddleware wraps a [Client] to add behavior to its calls,
+// such as logging, metrics, retries or deadlines. The returned Client
+// must be safe for concurrent use like the next Client.
+type ClientMiddleware func(next Client) Client
+
+// ChainClient returns c wrapped by the middlewares. The first middleware
+// is the outermost one, which sees each call first.
+func ChainClient(c Client, middlewares ...ClientMiddleware) Client {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		c = middlewares[i](c)
+	}
+	return c
+}
+
+// A ServerInterceptor intercepts the calls dispatched by a [Processor].
+//
+// The interceptor is called with the header of the call message and the
+// unmarshaled args, and calls handler to continue the dispatch, or returns
+// an error without calling it. The result is nil for one-way calls.
+// An error is reported to the client as by the handler itself.
+type ServerInterceptor func(ctx context.Context, mh thriftwire.MessageHeader, args, result any, handler HandlerFunc) error
+
+// Use adds interceptors to the calls dispatched by p. The first interceptor
+// added is the outermost one, which sees each call first.
+// Interceptors must not be added concurrently with [Processor.Process].
+func (p *Processor) Use(interceptors ...ServerInterceptor) {
+	p.interceptors = append(p.interceptors, interceptors...)
+}
+
+// intercept returns the handler fn wrapped by the interceptors of p
+// for the call with the header mh.
+func (p *Processor) intercept(mh thriftwire.MessageHeader, fn HandlerFunc) HandlerFunc {
+	for i := len(p.interceptors) - 1; i >= 0; i-- {
+		interceptor, next := p.interceptors[i], fn
+		fn = func(ctx context.Context, args, result any) error {
+			return interceptor(ctx, mh, args, result, next)
+		}
+	}
+	return fn
+}
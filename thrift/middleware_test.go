@@ -0,0 +1,91 @@
+package thrift
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/itstarsun/go-thrift/encoding/thriftwire"
+)
+
+func TestChainClient(t *testing.T) {
+	var trace []string
+	record := func(name string) ClientMiddleware {
+		return func(next Client) Client {
+			return ClientFunc(func(ctx context.Context, method string, args, result any) error {
+				trace = append(trace, name+" "+method)
+				return next.Call(ctx, method, args, result)
+			})
+		}
+	}
+	errDenied := errors.New("denied")
+	c := ChainClient(ClientFunc(func(ctx context.Context, method string, args, result any) error {
+		trace = append(trace, "call "+method)
+		return errDenied
+	}), record("a"), record("b"))
+
+	if err := c.Call(context.Background(), "echo", nil, nil); err != errDenied {
+		t.Fatalf("got %v, want %v", err, errDenied)
+	}
+	if want := []string{"a echo", "b echo", "call echo"}; !slices.Equal(trace, want) {
+		t.Fatalf("got %q, want %q", trace, want)
+	}
+}
+
+func TestProcessorUse(t *testing.T) {
+	var calls []string
+	p := newEchoProcessor(&calls)
+
+	var trace []string
+	p.Use(func(ctx context.Context, mh thriftwire.MessageHeader, args, result any, handler HandlerFunc) (err error) {
+		trace = append(trace, fmt.Sprintf("recover %s %d", mh.Name, mh.ID))
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("panic: %v", r)
+			}
+		}()
+		return handler(ctx, args, result)
+	}, func(ctx context.Context, mh thriftwire.MessageHeader, args, result any, handler HandlerFunc) error {
+		trace = append(trace, "auth "+mh.Name)
+		switch args.(*echoArgs).Message {
+		case "secret":
+			return &ApplicationError{Message: "denied", Type: UnknownApplicationError}
+		case "panic":
+			panic("boom")
+		}
+		return handler(ctx, args, result)
+	})
+
+	c := pipeClient(t, p)
+	ctx := context.Background()
+	var result echoResult
+	if err := c.Call(ctx, "echo", &echoArgs{Message: "hello"}, &result); err != nil || *result.Success != "hello" {
+		t.Fatalf("got (%s, %v), want hello", Format(&result), err)
+	}
+	if err := c.Call(ctx, "echo", &echoArgs{Message: "secret"}, &result); !errors.Is(err, ErrUnknownApplicationError) {
+		t.Fatalf("got %v, want %v", err, ErrUnknownApplicationError)
+	}
+	if err := c.Call(ctx, "echo", &echoArgs{Message: "panic"}, &result); !errors.Is(err, ErrInternalError) {
+		t.Fatalf("got %v, want %v", err, ErrInternalError)
+	}
+	if err := c.Call(ctx, "log", &echoArgs{Message: "logged"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	// Wait for the one-way call to be processed.
+	if err := c.Call(ctx, "echo", &echoArgs{Message: "hello"}, &result); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"recover echo 1", "auth echo",
+		"recover echo 2", "auth echo",
+		"recover echo 3", "auth echo",
+		"recover log 4", "auth log",
+		"recover echo 5", "auth echo",
+	}
+	if !slices.Equal(trace, want) {
+		t.Fatalf("\ngot  %q\nwant %q", trace, want)
+	}
+}
@@ -0,0 +1,160 @@
+package thrift
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/itstarsun/go-thrift/encoding/thriftwire"
+)
+
+// multiplexSeparator separates the service name from the method name
+// in the names of multiplexed messages.
+const multiplexSeparator = ":"
+
+// MultiplexedProtocol returns a new [thriftwire.Protocol] that uses p,
+// but prefixes the names of the call and one-way messages written with
+// the service name and a colon, like TMultiplexedProtocol of Apache Thrift.
+// It is used to call a service served by a [MultiplexedProcessor].
+func MultiplexedProtocol(p thriftwire.Protocol, service string) thriftwire.Protocol {
+	return &multiplexedProtocol{p: p, prefix: service + multiplexSeparator}
+}
+
+type multiplexedProtocol struct {
+	p      thriftwire.Protocol
+	prefix string
+}
+
+var _ thriftwire.DuplexProtocol = (*multiplexedProtocol)(nil)
+
+func (p *multiplexedProtocol) NewReader(r io.Reader) thriftwire.Reader {
+	return p.p.NewReader(r)
+}
+
+func (p *multiplexedProtocol) NewWriter(w io.Writer) thriftwire.Writer {
+	return &multiplexedWriter{Writer: p.p.NewWriter(w), prefix: p.prefix}
+}
+
+func (p *multiplexedProtocol) NewReadWriter(rw io.ReadWriter) (thriftwire.Reader, thriftwire.Writer) {
+	r, w := thriftwire.NewReadWriter(p.p, rw)
+	return r, &multiplexedWriter{Writer: w, prefix: p.prefix}
+}
+
+func (p *multiplexedProtocol) String() string {
+	return fmt.Sprintf("thrift.MultiplexedProtocol(%v, %q)", p.p, strings.TrimSuffix(p.prefix, multiplexSeparator))
+}
+
+type multiplexedWriter struct {
+	thriftwire.Writer
+	prefix string
+}
+
+func (w *multiplexedWriter) WriteMessageBegin(h thriftwire.MessageHeader) error {
+	if h.Type == thriftwire.Call || h.Type == thriftwire.OneWay {
+		h.Name = w.prefix + h.Name
+	}
+	return w.Writer.WriteMessageBegin(h)
+}
+
+// SetHeader implements [HeaderWriter] if the underlying Writer does.
+func (w *multiplexedWriter) SetHeader(h map[string]string) {
+	if hw, ok := w.Writer.(HeaderWriter); ok {
+		hw.SetHeader(h)
+	}
+}
+
+// A MultiplexedProcessor serves many services on the same connections,
+// like TMultiplexedProcessor of Apache Thrift. It dispatches each call to
+// the [MessageProcessor] of the service named by the prefix of the method
+// name, as written by [MultiplexedProtocol], which is removed from the name
+// seen by the MessageProcessor and the reply.
+//
+// The zero value is an empty MultiplexedProcessor ready to use.
+// Services must not be registered concurrently with
+// [MultiplexedProcessor.Process].
+type MultiplexedProcessor struct {
+	services map[string]MessageProcessor
+	fallback MessageProcessor
+}
+
+var _ MessageProcessor = (*MultiplexedProcessor)(nil)
+
+// Register registers the processor of the named service.
+// Register panics if a processor already exists for service.
+func (m *MultiplexedProcessor) Register(service string, p MessageProcessor) {
+	if _, ok := m.services[service]; ok {
+		panic("thrift: multiple registrations for service " + service)
+	}
+	if m.services == nil {
+		m.services = make(map[string]MessageProcessor)
+	}
+	m.services[service] = p
+}
+
+// RegisterDefault registers the processor of the calls without a service name,
+// such as those from clients that are not multiplexed.
+func (m *MultiplexedProcessor) RegisterDefault(p MessageProcessor) {
+	m.fallback = p
+}
+
+// Process reads a call message from r, and dispatches it to the processor
+// of its service. If there is no such processor, an exception message with
+// an [ApplicationError] of type [UnknownMethod] is written to w.
+func (m *MultiplexedProcessor) Process(ctx context.Context, r thriftwire.Reader, w thriftwire.Writer) error {
+	mh, err := r.ReadMessageBegin()
+	if err != nil {
+		return err
+	}
+	var p MessageProcessor
+	service, method, ok := strings.Cut(mh.Name, multiplexSeparator)
+	if ok {
+		p = m.services[service]
+		mh.Name = method // the reply is named without the service
+	} else {
+		p = m.fallback
+	}
+	if p == nil {
+		if err := thriftwire.Skip(r, thriftwire.Struct); err != nil {
+			return err
+		}
+		if err := r.ReadMessageEnd(); err != nil {
+			return err
+		}
+		if mh.Type == thriftwire.OneWay {
+			return nil
+		}
+		exc := &ApplicationError{Type: UnknownMethod}
+		if ok {
+			exc.Message = fmt.Sprintf("unknown service %s", service)
+		} else {
+			exc.Message = fmt.Sprintf("unknown method %s without a service name", mh.Name)
+		}
+		return writeException(w, mh, exc)
+	}
+	return p.Process(ctx, &storedMessageReader{Reader: r, mh: mh}, w)
+}
+
+// A storedMessageReader is a [thriftwire.Reader] that returns the stored
+// header of a message already read on the first call to ReadMessageBegin.
+type storedMessageReader struct {
+	thriftwire.Reader
+	mh   thriftwire.MessageHeader
+	read bool
+}
+
+func (r *storedMessageReader) ReadMessageBegin() (thriftwire.MessageHeader, error) {
+	if r.read {
+		return r.Reader.ReadMessageBegin()
+	}
+	r.read = true
+	return r.mh, nil
+}
+
+// Header implements [HeaderReader] if the underlying Reader does.
+func (r *storedMessageReader) Header() map[string]string {
+	if hr, ok := r.Reader.(HeaderReader); ok {
+		return hr.Header()
+	}
+	return nil
+}
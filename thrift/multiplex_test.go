@@ -0,0 +1,77 @@
+package thrift
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/itstarsun/go-thrift/encoding/thriftbinary"
+	"github.com/itstarsun/go-thrift/encoding/thriftwire"
+)
+
+func newPrefixProcessor(prefix string) *Processor {
+	p := new(Processor)
+	p.Handle("echo", (*echoArgs)(nil), (*echoResult)(nil), func(ctx context.Context, args, result any) error {
+		msg := prefix + args.(*echoArgs).Message
+		result.(*echoResult).Success = &msg
+		return nil
+	})
+	return p
+}
+
+func TestMultiplexed(t *testing.T) {
+	m := new(MultiplexedProcessor)
+	m.Register("A", newPrefixProcessor("a:"))
+	m.Register("B", newPrefixProcessor("b:"))
+	addr, _ := startServer(t, &Server{Processor: m, Protocol: thriftbinary.Protocol})
+	ctx := context.Background()
+
+	call := func(p thriftwire.Protocol) (string, error) {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := NewClient(conn, p)
+		defer c.Close()
+		var result echoResult
+		if err := c.Call(ctx, "echo", &echoArgs{Message: "hi"}, &result); err != nil {
+			return "", err
+		}
+		return *result.Success, nil
+	}
+
+	for _, tt := range []struct {
+		service string
+		want    string
+	}{
+		{"A", "a:hi"},
+		{"B", "b:hi"},
+	} {
+		got, err := call(MultiplexedProtocol(thriftbinary.Protocol, tt.service))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("got %q, want %q", got, tt.want)
+		}
+	}
+
+	for _, p := range []thriftwire.Protocol{
+		MultiplexedProtocol(thriftbinary.Protocol, "C"),
+		thriftbinary.Protocol,
+	} {
+		if _, err := call(p); !errors.Is(err, ErrUnknownMethod) {
+			t.Errorf("got %v, want %v", err, ErrUnknownMethod)
+		}
+	}
+
+	m.RegisterDefault(newPrefixProcessor("default:"))
+	got, err := call(thriftbinary.Protocol)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "default:hi"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,181 @@
+package thrift
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/itstarsun/go-thrift/encoding/thriftwire"
+)
+
+// A HandlerFunc handles a Thrift RPC call.
+//
+// The args are the unmarshaled arguments of the call, and the handler fills
+// in the result, which is nil for one-way methods. Both are pointers to new
+// values of the Go types registered by [Processor.Handle].
+type HandlerFunc func(ctx context.Context, args, result any) error
+
+// A Processor dispatches Thrift RPC calls to handlers by method name.
+//
+// The zero value is an empty Processor ready to use.
+// Handlers must not be registered concurrently with [Processor.Process].
+type Processor struct {
+	handlers     map[string]*handler
+	interceptors []ServerInterceptor
+}
+
+type handler struct {
+	args   reflect.Type
+	result reflect.Type // nil for one-way methods
+	fn     HandlerFunc
+}
+
+// Handle registers the handler for the named method.
+//
+// The args and result are pointers, typically nil, to the Go types of the
+// arguments and the result of the method; a new value of each type is
+// allocated for every call. If result is nil, the method is one-way, and no
+// reply is sent. Handle panics if a handler already exists for method.
+func (p *Processor) Handle(method string, args, result any, fn HandlerFunc) {
+	if _, ok := p.handlers[method]; ok {
+		panic("thrift: multiple registrations for " + method)
+	}
+	h := &handler{
+		args: reflect.TypeOf(args).Elem(),
+		fn:   fn,
+	}
+	if result != nil {
+		h.result = reflect.TypeOf(result).Elem()
+	}
+	if p.handlers == nil {
+		p.handlers = make(map[string]*handler)
+	}
+	p.handlers[method] = h
+}
+
+// Process reads a call message from r, dispatches it to the handler of
+// the method, and writes the reply message to w.
+//
+// If the call fails, an exception message with an [ApplicationError] is
+// written instead of the reply. An error returned by the handler is written
+// as an [InternalError] unless it is an *ApplicationError itself. A call
+// message for a one-way method, or a one-way message for a method that is
+// not one-way, is answered with an [InvalidMessageType] exception.
+//
+// If r is a [HeaderReader], the headers of the call are available to the
+// handler by [CallHeader], and the headers set by [SetReplyHeader] are
+// written with the reply if w is a [HeaderWriter].
+//
+// Process returns an error only if messages cannot be read or written,
+// after which the underlying connection should be closed.
+func (p *Processor) Process(ctx context.Context, r thriftwire.Reader, w thriftwire.Writer) error {
+	mh, err := r.ReadMessageBegin()
+	if err != nil {
+		return err
+	}
+	call := new(serverCall)
+	if r, ok := r.(HeaderReader); ok {
+		call.header = r.Header()
+	}
+	ctx = context.WithValue(ctx, serverCallKey{}, call)
+
+	h := p.handlers[mh.Name]
+	var exc *ApplicationError
+	switch {
+	case mh.Type != thriftwire.Call && mh.Type != thriftwire.OneWay:
+		exc = &ApplicationError{
+			Message: fmt.Sprintf("invalid message type %v", mh.Type),
+			Type:    InvalidMessageType,
+		}
+	case h == nil:
+		exc = &ApplicationError{
+			Message: fmt.Sprintf("unknown method %s", mh.Name),
+			Type:    UnknownMethod,
+		}
+	case (mh.Type == thriftwire.OneWay) != (h.result == nil):
+		// The client disagrees about whether the method is one-way.
+		// Reply anyway, rather than leaving the client waiting for a reply
+		// that never comes, or dropping the result of the handler.
+		exc = &ApplicationError{
+			Message: fmt.Sprintf("invalid message type %v for method %s", mh.Type, mh.Name),
+			Type:    InvalidMessageType,
+		}
+	}
+	if exc != nil {
+		if err := thriftwire.Skip(r, thriftwire.Struct); err != nil {
+			return err
+		}
+		if err := r.ReadMessageEnd(); err != nil {
+			return err
+		}
+		if mh.Type == thriftwire.OneWay && h == nil {
+			return nil
+		}
+		call.setReplyHeader(w)
+		return writeException(w, mh, exc)
+	}
+
+	args := reflect.New(h.args)
+	if err := Unmarshal(r, args.Interface()); err != nil {
+		if mh.Type == thriftwire.Call {
+			// Report the error to the client on a best-effort basis.
+			call.setReplyHeader(w)
+			_ = writeException(w, mh, &ApplicationError{
+				Message: err.Error(),
+				Type:    ProtocolError,
+			})
+		}
+		return err
+	}
+	if err := r.ReadMessageEnd(); err != nil {
+		return err
+	}
+
+	var result any
+	if h.result != nil {
+		result = reflect.New(h.result).Interface()
+	}
+	err = p.intercept(mh, h.fn)(ctx, args.Interface(), result)
+	if result == nil {
+		return nil
+	}
+	call.setReplyHeader(w)
+	if err != nil {
+		var exc *ApplicationError
+		if !errors.As(err, &exc) {
+			exc = &ApplicationError{
+				Message: err.Error(),
+				Type:    InternalError,
+			}
+		}
+		return writeException(w, mh, exc)
+	}
+	return writeMessage(w, thriftwire.MessageHeader{
+		Name: mh.Name,
+		Type: thriftwire.Reply,
+		ID:   mh.ID,
+	}, result)
+}
+
+func writeException(w thriftwire.Writer, mh thriftwire.MessageHeader, exc *ApplicationError) error {
+	return writeMessage(w, thriftwire.MessageHeader{
+		Name: mh.Name,
+		Type: thriftwire.Exception,
+		ID:   mh.ID,
+	}, exc)
+}
+
+// writeMessage writes a message with the body v, and flushes w.
+func writeMessage(w thriftwire.Writer, mh thriftwire.MessageHeader, v any) error {
+	if err := w.WriteMessageBegin(mh); err != nil {
+		return err
+	}
+	if err := Marshal(w, v); err != nil {
+		return err
+	}
+	if err := w.WriteMessageEnd(); err != nil {
+		return err
+	}
+	return w.Flush()
+}
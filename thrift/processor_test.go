@@ -0,0 +1,131 @@
+package thrift
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/itstarsun/go-thrift/encoding/thriftbinary"
+	"github.com/itstarsun/go-thrift/encoding/thriftwire"
+)
+
+type echoArgs struct {
+	Message string `thrift:"1"`
+}
+
+type echoResult struct {
+	Success *string `thrift:"0"`
+}
+
+func newEchoProcessor(calls *[]string) *Processor {
+	var p Processor
+	p.Handle("echo", (*echoArgs)(nil), (*echoResult)(nil), func(ctx context.Context, args, result any) error {
+		a := args.(*echoArgs)
+		switch a.Message {
+		case "":
+			return errors.New("empty message")
+		case "invalid":
+			return fmt.Errorf("echo: %w", &ApplicationError{Message: "invalid message", Type: ProtocolError})
+		}
+		result.(*echoResult).Success = &a.Message
+		return nil
+	})
+	p.Handle("log", (*echoArgs)(nil), nil, func(ctx context.Context, args, result any) error {
+		*calls = append(*calls, args.(*echoArgs).Message)
+		return nil
+	})
+	return &p
+}
+
+func TestProcessor(t *testing.T) {
+	var calls []string
+	p := newEchoProcessor(&calls)
+
+	tests := []struct {
+		name string
+		mh   thriftwire.MessageHeader
+		args any
+
+		wantType   thriftwire.MessageType // zero if no reply
+		wantResult any
+	}{{
+		name:       "Reply",
+		mh:         thriftwire.MessageHeader{Name: "echo", Type: thriftwire.Call, ID: 1},
+		args:       &echoArgs{Message: "hello"},
+		wantType:   thriftwire.Reply,
+		wantResult: &echoResult{Success: ptr("hello")},
+	}, {
+		name:       "HandlerError",
+		mh:         thriftwire.MessageHeader{Name: "echo", Type: thriftwire.Call, ID: 2},
+		args:       &echoArgs{},
+		wantType:   thriftwire.Exception,
+		wantResult: &ApplicationError{Message: "empty message", Type: InternalError},
+	}, {
+		name:       "ApplicationError",
+		mh:         thriftwire.MessageHeader{Name: "echo", Type: thriftwire.Call, ID: 2},
+		args:       &echoArgs{Message: "invalid"},
+		wantType:   thriftwire.Exception,
+		wantResult: &ApplicationError{Message: "invalid message", Type: ProtocolError},
+	}, {
+		name:       "UnknownMethod",
+		mh:         thriftwire.MessageHeader{Name: "missing", Type: thriftwire.Call, ID: 3},
+		args:       &echoArgs{},
+		wantType:   thriftwire.Exception,
+		wantResult: &ApplicationError{Message: "unknown method missing", Type: UnknownMethod},
+	}, {
+		name:       "CallOneWay",
+		mh:         thriftwire.MessageHeader{Name: "log", Type: thriftwire.Call, ID: 5},
+		args:       &echoArgs{Message: "not logged"},
+		wantType:   thriftwire.Exception,
+		wantResult: &ApplicationError{Message: "invalid message type Call for method log", Type: InvalidMessageType},
+	}, {
+		name:       "OneWayCall",
+		mh:         thriftwire.MessageHeader{Name: "echo", Type: thriftwire.OneWay, ID: 6},
+		args:       &echoArgs{Message: "hello"},
+		wantType:   thriftwire.Exception,
+		wantResult: &ApplicationError{Message: "invalid message type OneWay for method echo", Type: InvalidMessageType},
+	}, {
+		name: "OneWay",
+		mh:   thriftwire.MessageHeader{Name: "log", Type: thriftwire.OneWay, ID: 4},
+		args: &echoArgs{Message: "logged"},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var in, out bytes.Buffer
+			w := thriftbinary.Protocol.NewWriter(&in)
+			if err := writeMessage(w, tt.mh, tt.args); err != nil {
+				t.Fatal(err)
+			}
+			if err := p.Process(context.Background(), thriftbinary.Protocol.NewReader(&in), thriftbinary.Protocol.NewWriter(&out)); err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantType == 0 {
+				if out.Len() != 0 {
+					t.Fatalf("got a reply of %d bytes, want none", out.Len())
+				}
+				return
+			}
+
+			r := thriftbinary.Protocol.NewReader(&out)
+			mh, err := r.ReadMessageBegin()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := (thriftwire.MessageHeader{Name: tt.mh.Name, Type: tt.wantType, ID: tt.mh.ID}); mh != want {
+				t.Fatalf("got header %+v, want %+v", mh, want)
+			}
+			got := Clone(tt.wantResult)
+			if err := Unmarshal(r, got); err != nil {
+				t.Fatal(err)
+			}
+			if !Equal(got, tt.wantResult) {
+				t.Fatalf("got %s, want %s", Format(got), Format(tt.wantResult))
+			}
+		})
+	}
+	if len(calls) != 1 || calls[0] != "logged" {
+		t.Errorf("got one-way calls %q, want [logged]", calls)
+	}
+}
@@ -0,0 +1,288 @@
+package thrift
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/itstarsun/go-thrift/encoding/thriftwire"
+)
+
+// A MessageProcessor processes Thrift RPC messages.
+//
+// Process reads a message from r, and writes the reply, if any, to w.
+// An error means that the connection is unusable and should be closed.
+// [Processor] is the standard implementation.
+type MessageProcessor interface {
+	Process(ctx context.Context, r thriftwire.Reader, w thriftwire.Writer) error
+}
+
+var _ MessageProcessor = (*Processor)(nil)
+
+// ErrServerClosed is returned by [Server.Serve] after a call to
+// [Server.Shutdown] or [Server.Close].
+var ErrServerClosed = errors.New("thrift: server closed")
+
+// A Server serves Thrift RPC calls over network connections.
+//
+// Each connection is served by its own goroutine, which processes the calls
+// on the connection one at a time, such that the replies are written in the
+// order of the calls.
+type Server struct {
+	// Processor processes the calls.
+	Processor MessageProcessor
+
+	// Protocol encodes the messages.
+	Protocol thriftwire.Protocol
+
+	// MaxConcurrency is the maximum number of calls processed concurrently
+	// across all connections. If zero, there is no limit.
+	MaxConcurrency int
+
+	mu           sync.Mutex
+	listeners    map[*net.Listener]struct{}
+	conns        map[*serverConn]struct{}
+	sem          chan struct{}
+	shuttingDown bool
+	wg           sync.WaitGroup
+}
+
+// A serverConn is a connection served by a [Server].
+type serverConn struct {
+	s      *Server
+	conn   net.Conn
+	ctx    context.Context // canceled when the connection is closed
+	cancel context.CancelFunc
+	active bool // whether a call is being processed; guarded by s.mu
+}
+
+// ListenAndServe listens on the TCP network address addr,
+// and then calls [Server.Serve] to serve calls on incoming connections.
+func (s *Server) ListenAndServe(addr string) error {
+	if s.isShuttingDown() {
+		return ErrServerClosed
+	}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve accepts incoming connections on l, and serves calls on them
+// in new goroutines. Temporary errors accepting connections, such as
+// running out of file descriptors, are retried with an exponential backoff.
+// Serve always closes l, and returns a non-nil error.
+// After [Server.Shutdown] or [Server.Close], the error is [ErrServerClosed].
+func (s *Server) Serve(l net.Listener) error {
+	if !s.trackListener(&l, true) {
+		l.Close()
+		return ErrServerClosed
+	}
+	defer s.trackListener(&l, false)
+	defer l.Close()
+
+	var delay time.Duration // how long to sleep on a temporary accept error
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if s.isShuttingDown() {
+				return ErrServerClosed
+			}
+			if isTemporary(err) {
+				if delay == 0 {
+					delay = 5 * time.Millisecond
+				} else {
+					delay *= 2
+				}
+				delay = min(delay, time.Second)
+				time.Sleep(delay)
+				continue
+			}
+			return err
+		}
+		delay = 0
+		c := &serverConn{s: s, conn: conn}
+		c.ctx, c.cancel = context.WithCancel(context.Background())
+		if !s.trackConn(c, true) {
+			c.cancel()
+			conn.Close()
+			return ErrServerClosed
+		}
+		go c.serve()
+	}
+}
+
+// Shutdown gracefully shuts down the server. It closes all listeners and
+// idle connections, and then waits for the calls being processed to finish,
+// closing their connections afterwards. If ctx is done before all calls
+// finish, Shutdown returns the error of ctx, leaving the remaining
+// connections open; see [Server.Close] to close them.
+//
+// Once Shutdown has been called, the server cannot be reused.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.shuttingDown = true
+	for l := range s.listeners {
+		(*l).Close()
+	}
+	for c := range s.conns {
+		if !c.active {
+			c.conn.Close()
+		}
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close immediately closes all listeners and connections, and cancels
+// the contexts of the calls being processed.
+// For a graceful shutdown, use [Server.Shutdown].
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.shuttingDown = true
+	var err error
+	for l := range s.listeners {
+		if cerr := (*l).Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	for c := range s.conns {
+		c.cancel()
+		c.conn.Close()
+	}
+	return err
+}
+
+// isTemporary reports whether err is a temporary error,
+// such as syscall.EMFILE, as reported by the deprecated net.Error.Temporary.
+func isTemporary(err error) bool {
+	var te interface{ Temporary() bool }
+	return errors.As(err, &te) && te.Temporary()
+}
+
+func (s *Server) isShuttingDown() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.shuttingDown
+}
+
+// trackListener adds or removes l, reporting whether the server is
+// not shutting down when adding it.
+func (s *Server) trackListener(l *net.Listener, add bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !add {
+		delete(s.listeners, l)
+		return true
+	}
+	if s.shuttingDown {
+		return false
+	}
+	if s.listeners == nil {
+		s.listeners = make(map[*net.Listener]struct{})
+	}
+	s.listeners[l] = struct{}{}
+	return true
+}
+
+// trackConn adds or removes c, reporting whether the server is
+// not shutting down when adding it.
+func (s *Server) trackConn(c *serverConn, add bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !add {
+		delete(s.conns, c)
+		s.wg.Done()
+		return true
+	}
+	if s.shuttingDown {
+		return false
+	}
+	if s.conns == nil {
+		s.conns = make(map[*serverConn]struct{})
+	}
+	if s.MaxConcurrency > 0 && s.sem == nil {
+		s.sem = make(chan struct{}, s.MaxConcurrency)
+	}
+	s.conns[c] = struct{}{}
+	s.wg.Add(1)
+	return true
+}
+
+// setActive marks c as processing a call or not, reporting whether
+// c should keep serving calls.
+func (c *serverConn) setActive(active bool) bool {
+	c.s.mu.Lock()
+	defer c.s.mu.Unlock()
+	c.active = active
+	return active || !c.s.shuttingDown
+}
+
+func (c *serverConn) serve() {
+	defer c.s.trackConn(c, false)
+	defer c.conn.Close()
+	defer c.cancel()
+
+	pr, w := thriftwire.NewReadWriter(c.s.Protocol, c.conn)
+	r := &serverReader{Reader: pr, c: c}
+	for {
+		err := c.s.Processor.Process(c.ctx, r, w)
+		if r.acquired {
+			<-c.s.sem
+			r.acquired = false
+		}
+		if err != nil || !c.setActive(false) {
+			return
+		}
+	}
+}
+
+// A serverReader is a [thriftwire.Reader] that marks its connection as
+// active and acquires the concurrency limit of the server after reading
+// the header of a message.
+type serverReader struct {
+	thriftwire.Reader
+	c        *serverConn
+	acquired bool
+}
+
+func (r *serverReader) ReadMessageBegin() (thriftwire.MessageHeader, error) {
+	mh, err := r.Reader.ReadMessageBegin()
+	if err != nil {
+		return mh, err
+	}
+	r.c.setActive(true)
+	if sem := r.c.s.sem; sem != nil {
+		select {
+		case sem <- struct{}{}:
+			r.acquired = true
+		case <-r.c.ctx.Done():
+			return mh, r.c.ctx.Err()
+		}
+	}
+	return mh, nil
+}
+
+// Header implements [HeaderReader], returning the headers of the message
+// last read if the underlying Reader is a HeaderReader.
+func (r *serverReader) Header() map[string]string {
+	if hr, ok := r.Reader.(HeaderReader); ok {
+		return hr.Header()
+	}
+	return nil
+}
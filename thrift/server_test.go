@@ -0,0 +1,263 @@
+package thrift
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/itstarsun/go-thrift/encoding/thriftbinary"
+)
+
+// startServer starts s on a local listener, and returns the address
+// and a channel receiving the error of Serve.
+func startServer(t *testing.T, s *Server) (string, <-chan error) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	errc := make(chan error, 1)
+	go func() { errc <- s.Serve(l) }()
+	t.Cleanup(func() { s.Close() })
+	return l.Addr().String(), errc
+}
+
+func dial(t *testing.T, addr string) *ClientConn {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewClient(conn, thriftbinary.Protocol)
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestServer(t *testing.T) {
+	var calls []string
+	s := &Server{
+		Processor: newEchoProcessor(&calls),
+		Protocol:  thriftbinary.Protocol,
+	}
+	addr, errc := startServer(t, s)
+	ctx := context.Background()
+
+	c := dial(t, addr)
+	for _, msg := range []string{"hello", "world"} {
+		var result echoResult
+		if err := c.Call(ctx, "echo", &echoArgs{Message: msg}, &result); err != nil {
+			t.Fatal(err)
+		}
+		if *result.Success != msg {
+			t.Fatalf("got %q, want %q", *result.Success, msg)
+		}
+	}
+	if err := c.Call(ctx, "log", &echoArgs{Message: "logged"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Call(ctx, "missing", &echoArgs{}, &echoResult{}); !errors.Is(err, ErrUnknownMethod) {
+		t.Fatalf("got %v, want %v", err, ErrUnknownMethod)
+	}
+
+	if err := s.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errc; err != ErrServerClosed {
+		t.Fatalf("Serve returned %v, want %v", err, ErrServerClosed)
+	}
+	if len(calls) != 1 || calls[0] != "logged" {
+		t.Errorf("got one-way calls %q, want [logged]", calls)
+	}
+	if err := c.Call(ctx, "echo", &echoArgs{Message: "hello"}, &echoResult{}); err == nil {
+		t.Error("unexpected success after shutdown")
+	}
+}
+
+func TestServerShutdown(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	var p Processor
+	p.Handle("wait", (*echoArgs)(nil), (*echoResult)(nil), func(ctx context.Context, args, result any) error {
+		close(started)
+		<-release
+		result.(*echoResult).Success = ptr("done")
+		return nil
+	})
+	s := &Server{Processor: &p, Protocol: thriftbinary.Protocol}
+	addr, errc := startServer(t, s)
+
+	idle := dial(t, addr)
+	if err := idle.Call(context.Background(), "missing", &echoArgs{}, &echoResult{}); err == nil {
+		t.Fatal("unexpected success")
+	}
+
+	c := dial(t, addr)
+	callErr := make(chan error, 1)
+	go func() {
+		var result echoResult
+		callErr <- c.Call(context.Background(), "wait", &echoArgs{}, &result)
+	}()
+	<-started
+
+	shutdownErr := make(chan error, 1)
+	go func() { shutdownErr <- s.Shutdown(context.Background()) }()
+	if err := <-errc; err != ErrServerClosed {
+		t.Fatalf("Serve returned %v, want %v", err, ErrServerClosed)
+	}
+	select {
+	case err := <-shutdownErr:
+		t.Fatalf("Shutdown returned %v while a call is being processed", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	// The idle connection is closed.
+	if err := idle.Call(context.Background(), "missing", &echoArgs{}, &echoResult{}); err == nil {
+		t.Error("unexpected success on an idle connection after shutdown")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Shutdown returned %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	close(release)
+	if err := <-callErr; err != nil {
+		t.Fatal(err)
+	}
+	if err := <-shutdownErr; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestServerMaxConcurrency(t *testing.T) {
+	var (
+		mu        sync.Mutex
+		active    int
+		maxActive int
+	)
+	var p Processor
+	p.Handle("echo", (*echoArgs)(nil), (*echoResult)(nil), func(ctx context.Context, args, result any) error {
+		mu.Lock()
+		active++
+		maxActive = max(maxActive, active)
+		mu.Unlock()
+		time.Sleep(time.Millisecond)
+		mu.Lock()
+		active--
+		mu.Unlock()
+		result.(*echoResult).Success = &args.(*echoArgs).Message
+		return nil
+	})
+	s := &Server{Processor: &p, Protocol: thriftbinary.Protocol, MaxConcurrency: 2}
+	addr, _ := startServer(t, s)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		c := dial(t, addr)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 5; j++ {
+				if err := c.Call(context.Background(), "echo", &echoArgs{Message: "hello"}, &echoResult{}); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if maxActive != 2 {
+		t.Errorf("got %d concurrent calls at most, want 2", maxActive)
+	}
+}
+
+func TestServerClose(t *testing.T) {
+	started := make(chan struct{})
+	var p Processor
+	p.Handle("wait", (*echoArgs)(nil), (*echoResult)(nil), func(ctx context.Context, args, result any) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	s := &Server{Processor: &p, Protocol: thriftbinary.Protocol}
+	addr, errc := startServer(t, s)
+
+	c := dial(t, addr)
+	callErr := make(chan error, 1)
+	go func() {
+		callErr <- c.Call(context.Background(), "wait", &echoArgs{}, &echoResult{})
+	}()
+	<-started
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errc; err != ErrServerClosed {
+		t.Fatalf("Serve returned %v, want %v", err, ErrServerClosed)
+	}
+	if err := <-callErr; err == nil {
+		t.Fatal("unexpected success after close")
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Serve(l); err != ErrServerClosed {
+		t.Fatalf("Serve after close returned %v, want %v", err, ErrServerClosed)
+	}
+}
+
+// flakyListener is a [net.Listener] failing to accept connections with
+// the error err the first n times.
+type flakyListener struct {
+	net.Listener
+	mu  sync.Mutex
+	n   int
+	err error
+}
+
+func (l *flakyListener) Accept() (net.Conn, error) {
+	l.mu.Lock()
+	if l.n > 0 {
+		l.n--
+		l.mu.Unlock()
+		return nil, l.err
+	}
+	l.mu.Unlock()
+	return l.Listener.Accept()
+}
+
+func TestServerAcceptError(t *testing.T) {
+	newListener := func(n int, err error) *flakyListener {
+		l, lerr := net.Listen("tcp", "127.0.0.1:0")
+		if lerr != nil {
+			t.Fatal(lerr)
+		}
+		return &flakyListener{Listener: l, n: n, err: err}
+	}
+	s := &Server{Processor: newEchoProcessor(new([]string)), Protocol: thriftbinary.Protocol}
+	t.Cleanup(func() { s.Close() })
+
+	emfile := &net.OpError{Op: "accept", Net: "tcp", Err: syscall.EMFILE}
+	l := newListener(3, emfile)
+	errc := make(chan error, 1)
+	go func() { errc <- s.Serve(l) }()
+	c := dial(t, l.Addr().String())
+	var result echoResult
+	if err := c.Call(context.Background(), "echo", &echoArgs{Message: "hello"}, &result); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-errc:
+		t.Fatalf("Serve returned %v after a temporary error", err)
+	default:
+	}
+
+	permanent := errors.New("permanent")
+	if err := s.Serve(newListener(1, permanent)); err != permanent {
+		t.Fatalf("Serve returned %v, want %v", err, permanent)
+	}
+}
@@ -0,0 +1,92 @@
+package thrift
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/itstarsun/go-thrift/encoding/thriftwire"
+)
+
+// ReadList reads a Thrift list from a [thriftwire.Reader] one element at
+// a time without materializing the whole list, calling yield for each element.
+//
+// Each element is decoded into a reused Go value of type T that is reset to
+// its zero value beforehand. If yield returns an error, ReadList stops and
+// returns that error, leaving the rest of the list unread.
+func ReadList[T any](r thriftwire.Reader, yield func(T) error) error {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	fncs := lookupArshaler(t)
+	h, err := r.ReadListBegin()
+	if err != nil {
+		err := &wireError{action: "ReadListBegin", err: err}
+		return &SemanticError{action: "unmarshal", ThriftType: thriftwire.List, GoType: reflect.SliceOf(t), Err: err}
+	}
+	uo := makeUnmarshalOptions(r)
+	if uo.namedFields {
+		h.Element = fncs.wireType
+	}
+	v := newAddressableValue(t)
+	p := v.Addr().Interface().(*T)
+	for i := 0; i < h.Size; i++ {
+		v.SetZero()
+		if err := fncs.unmarshal(r, v, uo, h.Element); err != nil {
+			return err
+		}
+		if err := yield(*p); err != nil {
+			return err
+		}
+	}
+	if err := r.ReadListEnd(); err != nil {
+		err := &wireError{action: "ReadListEnd", err: err}
+		return &SemanticError{action: "unmarshal", ThriftType: thriftwire.List, GoType: reflect.SliceOf(t), Err: err}
+	}
+	return nil
+}
+
+// WriteList writes a Thrift list of n elements to a [thriftwire.Writer],
+// marshaling the elements one at a time as they are produced by seq.
+//
+// The signature of seq matches iter.Seq[T]. It must produce exactly
+// n elements; otherwise WriteList reports an error, and the written data
+// is no longer valid.
+func WriteList[T any](w thriftwire.Writer, n int, seq func(yield func(T) bool)) error {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	fncs := lookupArshaler(t)
+	if fncs.wireType == thriftwire.Stop {
+		return &SemanticError{action: "marshal", ThriftType: thriftwire.List, GoType: reflect.SliceOf(t)}
+	}
+	err := w.WriteListBegin(thriftwire.ListHeader{
+		Element: fncs.wireType,
+		Size:    n,
+	})
+	if err != nil {
+		err := &wireError{action: "WriteListBegin", err: err}
+		return &SemanticError{action: "marshal", ThriftType: thriftwire.List, GoType: reflect.SliceOf(t), Err: err}
+	}
+	v := newAddressableValue(t)
+	p := v.Addr().Interface().(*T)
+	var i int
+	seq(func(e T) bool {
+		if i++; i > n {
+			return false
+		}
+		*p = e
+		err = fncs.marshal(w, v, marshalOptions{})
+		return err == nil
+	})
+	if err != nil {
+		return err
+	}
+	if i != n {
+		err := fmt.Errorf("got %d or more elements, want %d", i, n)
+		if i < n {
+			err = fmt.Errorf("got %d elements, want %d", i, n)
+		}
+		return &SemanticError{action: "marshal", ThriftType: thriftwire.List, GoType: reflect.SliceOf(t), Err: err}
+	}
+	if err := w.WriteListEnd(); err != nil {
+		err := &wireError{action: "WriteListEnd", err: err}
+		return &SemanticError{action: "marshal", ThriftType: thriftwire.List, GoType: reflect.SliceOf(t), Err: err}
+	}
+	return nil
+}
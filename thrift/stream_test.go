@@ -0,0 +1,82 @@
+package thrift
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/itstarsun/go-thrift/internal/thriftmemo"
+)
+
+func seqOf[T any](values ...T) func(yield func(T) bool) {
+	return func(yield func(T) bool) {
+		for _, v := range values {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+func TestStreamList(t *testing.T) {
+	want := []aStruct{
+		{String: "a", List: []*aStruct{{String: "b"}}},
+		{String: "c"},
+		{},
+	}
+
+	var m thriftmemo.Memo
+	if err := WriteList(m.Writer(), len(want), seqOf(want...)); err != nil {
+		t.Fatal(err)
+	}
+	var got []aStruct
+	if err := Unmarshal(m.Reader(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("\ngot  %v\nwant %v", got, want)
+	}
+
+	m.Reset()
+	if err := Marshal(m.Writer(), want); err != nil {
+		t.Fatal(err)
+	}
+	got = got[:0]
+	if err := ReadList(m.Reader(), func(v aStruct) error {
+		got = append(got, v)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("\ngot  %v\nwant %v", got, want)
+	}
+}
+
+func TestStreamListErrors(t *testing.T) {
+	var m thriftmemo.Memo
+	for _, n := range []int{2, 4} {
+		err := WriteList(m.Writer(), n, seqOf[int32](1, 2, 3))
+		var se *SemanticError
+		if !errors.As(err, &se) || se.action != "marshal" {
+			t.Errorf("WriteList(%d) = %v, want a marshal error", n, err)
+		}
+	}
+
+	m.Reset()
+	if err := Marshal(m.Writer(), []int32{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+	errStop := errors.New("stop")
+	var got []int32
+	err := ReadList(m.Reader(), func(v int32) error {
+		got = append(got, v)
+		if len(got) == 2 {
+			return errStop
+		}
+		return nil
+	})
+	if err != errStop || len(got) != 2 {
+		t.Errorf("ReadList = (%v, %v), want (%v, [1 2])", err, got, errStop)
+	}
+}
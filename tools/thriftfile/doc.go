@@ -1,2 +1,3 @@
-// Package thriftfile implements a parser for .thrift files.
+// Package thriftfile implements a parser for .thrift files,
+// and a loader of the files they include.
 package thriftfile
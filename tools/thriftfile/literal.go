@@ -0,0 +1,100 @@
+package thriftfile
+
+import (
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ErrSyntax indicates that a literal does not have the right syntax.
+var ErrSyntax = errors.New("invalid syntax")
+
+// Unquote interprets s as a single-quoted or double-quoted Thrift string
+// literal, such as the value of a [String] node, returning the string value
+// that s quotes.
+func Unquote(s string) (string, error) {
+	if len(s) < 2 {
+		return "", ErrSyntax
+	}
+	quote := s[0]
+	if (quote != '"' && quote != '\'') || s[len(s)-1] != quote {
+		return "", ErrSyntax
+	}
+	s = s[1 : len(s)-1]
+	if strings.IndexByte(s, '\\') < 0 && strings.IndexByte(s, quote) < 0 && strings.IndexByte(s, '\n') < 0 {
+		return s, nil
+	}
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch c {
+		case quote, '\n':
+			return "", ErrSyntax
+		case '\\':
+			i++
+			if i == len(s) {
+				return "", ErrSyntax
+			}
+			switch c = s[i]; c {
+			case quote, '\\':
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			default:
+				return "", ErrSyntax
+			}
+		}
+		b.WriteByte(c)
+	}
+	return b.String(), nil
+}
+
+// ParseInt interprets s as a Thrift integer literal, such as the value of
+// an [Int] node, which is either decimal or hexadecimal with the 0x prefix,
+// and may have a sign. If the value does not fit into an integer of the
+// given bit size, the error matches [strconv.ErrRange] by [errors.Is].
+func ParseInt(s string, bitSize int) (int64, error) {
+	digits := strings.TrimLeft(s, "+-")
+	if strings.HasPrefix(digits, "0x") || strings.HasPrefix(digits, "0X") {
+		n, err := strconv.ParseUint(digits[2:], 16, 64)
+		if err != nil {
+			return 0, err
+		}
+		if strings.HasPrefix(s, "-") {
+			if n > 1<<(bitSize-1) {
+				return 0, strconv.ErrRange
+			}
+			return -int64(n), nil
+		}
+		if n > 1<<(bitSize-1)-1 {
+			return 0, strconv.ErrRange
+		}
+		return int64(n), nil
+	}
+	n, err := strconv.ParseInt(s, 10, bitSize)
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// ParseUUID interprets s as a UUID in the canonical form, such as
+// 00112233-4455-6677-8899-aabbccddeeff, optionally enclosed in curly braces.
+func ParseUUID(s string) ([16]byte, error) {
+	var u [16]byte
+	if strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}") {
+		s = s[1 : len(s)-1]
+	}
+	if len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return u, ErrSyntax
+	}
+	if _, err := hex.Decode(u[:], []byte(strings.ReplaceAll(s, "-", ""))); err != nil {
+		return [16]byte{}, ErrSyntax
+	}
+	return u, nil
+}
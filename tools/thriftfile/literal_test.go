@@ -0,0 +1,95 @@
+package thriftfile
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+)
+
+func TestUnquote(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		ok   bool
+	}{
+		{`""`, "", true},
+		{`''`, "", true},
+		{`"hello"`, "hello", true},
+		{`'hello'`, "hello", true},
+		{`"it's"`, "it's", true},
+		{`'say "hi"'`, `say "hi"`, true},
+		{`"a\tb\nc\r\\"`, "a\tb\nc\r\\", true},
+		{`"\""`, `"`, true},
+		{`'\''`, `'`, true},
+		{`"`, "", false},
+		{`hello`, "", false},
+		{`"hello'`, "", false},
+		{`"a"b"`, "", false},
+		{`'\"'`, "", false},
+		{`"\x"`, "", false},
+		{`"\"`, "", false},
+		{"\"a\nb\"", "", false},
+	}
+	for _, tt := range tests {
+		got, err := Unquote(tt.in)
+		if ok := err == nil; got != tt.want || ok != tt.ok {
+			t.Errorf("Unquote(%s) = (%q, %v), want (%q, ok=%v)", tt.in, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParseInt(t *testing.T) {
+	tests := []struct {
+		in      string
+		bitSize int
+		want    int64
+		ok      bool
+	}{
+		{"0", 32, 0, true},
+		{"-42", 32, -42, true},
+		{"+7", 8, 7, true},
+		{"0x10", 32, 16, true},
+		{"0X7fff", 16, 32767, true},
+		{"-0x8000", 16, -32768, true},
+		{"0x8000", 16, 0, false},
+		{"-0x8001", 16, 0, false},
+		{"128", 8, 0, false},
+		{"0x", 32, 0, false},
+		{"1.5", 32, 0, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseInt(tt.in, tt.bitSize)
+		if ok := err == nil; got != tt.want || ok != tt.ok {
+			t.Errorf("ParseInt(%s, %d) = (%d, %v), want (%d, ok=%v)", tt.in, tt.bitSize, got, err, tt.want, tt.ok)
+		}
+	}
+	for _, in := range []string{"0x100", "256"} {
+		if _, err := ParseInt(in, 8); !errors.Is(err, strconv.ErrRange) {
+			t.Errorf("ParseInt(%s, 8) returned %v, want %v", in, err, strconv.ErrRange)
+		}
+	}
+}
+
+func TestParseUUID(t *testing.T) {
+	want := [16]byte{0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88, 0x99, 0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0xff}
+	for _, in := range []string{
+		"00112233-4455-6677-8899-aabbccddeeff",
+		"{00112233-4455-6677-8899-AABBCCDDEEFF}",
+	} {
+		if got, err := ParseUUID(in); err != nil || got != want {
+			t.Errorf("ParseUUID(%s) = (%x, %v), want %x", in, got, err, want)
+		}
+	}
+	for _, in := range []string{
+		"",
+		"00112233445566778899aabbccddeeff",
+		"00112233-4455-6677-8899-aabbccddeef",
+		"0011223-34455-6677-8899-aabbccddeeff",
+		"00112233-4455-6677-8899-aabbccddeegg",
+		"{00112233-4455-6677-8899-aabbccddeeff",
+	} {
+		if _, err := ParseUUID(in); err != ErrSyntax {
+			t.Errorf("ParseUUID(%s) returned %v, want %v", in, err, ErrSyntax)
+		}
+	}
+}
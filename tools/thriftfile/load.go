@@ -0,0 +1,116 @@
+package thriftfile
+
+import (
+	"errors"
+	"fmt"
+	gotoken "go/token"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// A LoadedFile is a .thrift file loaded by a [Loader],
+// with the files it includes.
+type LoadedFile struct {
+	// Path is the path of the file, as given to [Loader.Load],
+	// or joined with the directory it was found in if it is included.
+	Path string
+
+	// File is the parsed file.
+	File *File
+
+	// Includes maps the names qualifying the definitions of the included
+	// files, which are their base names without extension, to the files.
+	Includes map[string]*LoadedFile
+}
+
+// A Loader loads .thrift files and the files they include.
+// Each file is loaded at most once by a Loader.
+//
+// The zero value is a Loader ready to use.
+type Loader struct {
+	// Fset records the positions of the parsed files.
+	// If nil, a new FileSet is used.
+	Fset *gotoken.FileSet
+
+	// IncludeDirs are the directories searched for included files
+	// after the directory of the including file.
+	IncludeDirs []string
+
+	files   map[string]*LoadedFile // keyed by absolute path
+	loading map[string]bool
+}
+
+// Load parses the .thrift file at path and the files it includes.
+func (l *Loader) Load(path string) (*LoadedFile, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	if f, ok := l.files[abs]; ok {
+		return f, nil
+	}
+	if l.loading[abs] {
+		return nil, fmt.Errorf("%s: include cycle", path)
+	}
+	if l.files == nil {
+		l.files = make(map[string]*LoadedFile)
+		l.loading = make(map[string]bool)
+	}
+	if l.Fset == nil {
+		l.Fset = gotoken.NewFileSet()
+	}
+	l.loading[abs] = true
+	defer delete(l.loading, abs)
+
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	ast, err := Parse(l.Fset, path, src)
+	if err != nil {
+		return nil, err
+	}
+	f := &LoadedFile{
+		Path:     path,
+		File:     ast,
+		Includes: make(map[string]*LoadedFile),
+	}
+	for _, h := range ast.Headers {
+		h, ok := h.(*Include)
+		if !ok {
+			continue
+		}
+		name, err := Unquote(h.Path.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid include path %s", path, h.Path.Value)
+		}
+		inc, err := l.loadInclude(filepath.Dir(path), name)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		f.Includes[IncludeName(name)] = inc
+	}
+	l.files[abs] = f
+	return f, nil
+}
+
+func (l *Loader) loadInclude(dir, name string) (*LoadedFile, error) {
+	for _, dir := range append([]string{dir}, l.IncludeDirs...) {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if _, err := os.Stat(path); err == nil {
+			return l.Load(path)
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("include %q not found", name)
+}
+
+// IncludeName returns the name qualifying the definitions of the file
+// included by the slash-separated path, which is its base name without
+// extension.
+func IncludeName(path string) string {
+	base := path[strings.LastIndexByte(path, '/')+1:]
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
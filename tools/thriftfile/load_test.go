@@ -0,0 +1,86 @@
+package thriftfile
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, src := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0o666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoader(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a.thrift":         `include "b.thrift"` + "\n" + `include "c.thrift"`,
+		"b.thrift":         `include "c.thrift"`,
+		"include/c.thrift": `struct C {}`,
+	})
+	l := &Loader{IncludeDirs: []string{filepath.Join(dir, "include")}}
+	a, err := l.Load(filepath.Join(dir, "a.thrift"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, c := a.Includes["b"], a.Includes["c"]
+	if b == nil || c == nil || len(a.Includes) != 2 {
+		t.Fatalf("got includes %v, want b and c", a.Includes)
+	}
+	if b.Includes["c"] != c {
+		t.Errorf("c is loaded more than once")
+	}
+	if want := filepath.Join(dir, "include", "c.thrift"); c.Path != want {
+		t.Errorf("got path %s, want %s", c.Path, want)
+	}
+	if len(c.File.Defs) != 1 {
+		t.Errorf("got %d definitions in c, want 1", len(c.File.Defs))
+	}
+	if again, err := l.Load(filepath.Join(dir, "b.thrift")); err != nil || again != b {
+		t.Errorf("Load(b) = (%p, %v), want %p", again, err, b)
+	}
+}
+
+func TestLoaderErrors(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"cycle.thrift":   `include "cycle2.thrift"`,
+		"cycle2.thrift":  `include "cycle.thrift"`,
+		"missing.thrift": `include "none.thrift"`,
+		"invalid.thrift": `struct {`,
+	})
+	for name, want := range map[string]string{
+		"cycle.thrift":   "include cycle",
+		"missing.thrift": `include "none.thrift" not found`,
+		"invalid.thrift": "invalid.thrift",
+		"none.thrift":    "none.thrift",
+	} {
+		var l Loader
+		_, err := l.Load(filepath.Join(dir, name))
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Load(%s) returned %v, want an error containing %q", name, err, want)
+		}
+	}
+}
+
+func TestIncludeName(t *testing.T) {
+	for in, want := range map[string]string{
+		"shared.thrift":         "shared",
+		"dir/sub/shared.thrift": "shared",
+		"shared":                "shared",
+		"dir/shared.v2.thrift":  "shared.v2",
+	} {
+		if got := IncludeName(in); got != want {
+			t.Errorf("IncludeName(%s) = %s, want %s", in, got, want)
+		}
+	}
+}
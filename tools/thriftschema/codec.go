@@ -0,0 +1,632 @@
+package thriftschema
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/itstarsun/go-thrift/encoding/thriftwire"
+	"github.com/itstarsun/go-thrift/tools/thriftfile"
+)
+
+// An Error describes a value that does not match its type in the schema.
+type Error struct {
+	Path string // path to the value, such as "Item.children[0].name"
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return "thriftschema: " + e.Path + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// errorf returns an *Error at the current path, which is completed
+// by the callers with [atPath].
+func errorf(format string, args ...any) error {
+	return &Error{Err: fmt.Errorf(format, args...)}
+}
+
+// atPath prepends elem to the path of err, if it is an *Error.
+func atPath(err error, elem string) error {
+	if e, ok := err.(*Error); ok {
+		e.Path = elem + e.Path
+	}
+	return err
+}
+
+// Marshal writes the JSON-like value v, which must be a map[string]any,
+// as the struct st.
+func (st *Struct) Marshal(w thriftwire.Writer, v any) error {
+	return atPath(encodeStruct(w, st, v), st.name)
+}
+
+// Unmarshal reads a value of the struct st, and returns it as a JSON-like value.
+// Unknown fields, and fields whose wire type differs from the schema, are skipped.
+func (st *Struct) Unmarshal(r thriftwire.Reader) (map[string]any, error) {
+	_, named := r.(thriftwire.NamedFieldReader)
+	d := &decoder{r: r, named: named}
+	m, err := d.decodeStruct(st)
+	return m, atPath(err, st.name)
+}
+
+// A Value is a JSON-like value of a [Struct] that implements
+// the Marshaler and Unmarshaler interfaces of the thrift package,
+// such that it can be passed to its Marshal and Unmarshal functions
+// and to Thrift clients.
+type Value struct {
+	Struct *Struct
+	Fields map[string]any
+}
+
+// MarshalThrift writes v.Fields with [Struct.Marshal].
+func (v *Value) MarshalThrift(w thriftwire.Writer) error {
+	return v.Struct.Marshal(w, v.Fields)
+}
+
+// UnmarshalThrift reads a value with [Struct.Unmarshal],
+// and merges its fields into v.Fields.
+func (v *Value) UnmarshalThrift(r thriftwire.Reader) error {
+	m, err := v.Struct.Unmarshal(r)
+	if err != nil {
+		return err
+	}
+	if v.Fields == nil {
+		v.Fields = m
+		return nil
+	}
+	for k, fv := range m {
+		v.Fields[k] = fv
+	}
+	return nil
+}
+
+func encode(w thriftwire.Writer, t *Type, v any) error {
+	switch t.kind {
+	case boolKind:
+		b, ok := v.(bool)
+		if !ok {
+			return errorf("cannot use %s as %v", describe(v), t)
+		}
+		return w.WriteBool(b)
+	case byteKind:
+		n, err := toInt(t, v, 8)
+		if err != nil {
+			return err
+		}
+		return w.WriteByte(byte(n))
+	case i16Kind:
+		n, err := toInt(t, v, 16)
+		if err != nil {
+			return err
+		}
+		return w.WriteI16(int16(n))
+	case i32Kind:
+		n, err := toInt(t, v, 32)
+		if err != nil {
+			return err
+		}
+		return w.WriteI32(int32(n))
+	case i64Kind:
+		n, err := toInt(t, v, 64)
+		if err != nil {
+			return err
+		}
+		return w.WriteI64(n)
+	case doubleKind:
+		f, err := toFloat(t, v)
+		if err != nil {
+			return err
+		}
+		return w.WriteDouble(f)
+	case stringKind:
+		s, ok := v.(string)
+		if !ok {
+			return errorf("cannot use %s as %v", describe(v), t)
+		}
+		return w.WriteString(s)
+	case binaryKind:
+		switch v := v.(type) {
+		case []byte:
+			return w.WriteBytes(v)
+		case string:
+			b, err := base64.StdEncoding.DecodeString(v)
+			if err != nil {
+				return errorf("invalid base64 binary: %v", err)
+			}
+			return w.WriteBytes(b)
+		}
+		return errorf("cannot use %s as %v", describe(v), t)
+	case uuidKind:
+		switch v := v.(type) {
+		case [16]byte:
+			return w.WriteUUID(&v)
+		case string:
+			u, err := thriftfile.ParseUUID(v)
+			if err != nil {
+				return errorf("invalid UUID %q", v)
+			}
+			return w.WriteUUID(&u)
+		}
+		return errorf("cannot use %s as %v", describe(v), t)
+	case enumKind:
+		var n int64
+		if s, ok := v.(string); ok {
+			x, ok := t.enum.values[s]
+			if !ok {
+				return errorf("%s is not a value of enum %s", s, t.name)
+			}
+			n = int64(x)
+		} else {
+			// Undeclared values are written as they are decoded,
+			// such as values of a newer version of the enum.
+			var err error
+			if n, err = toInt(t, v, 32); err != nil {
+				return err
+			}
+		}
+		return w.WriteI32(int32(n))
+	case structKind:
+		return encodeStruct(w, t.strct, v)
+	case mapKind:
+		return encodeMap(w, t, v)
+	case setKind, listKind:
+		elems, ok := v.([]any)
+		if !ok {
+			return errorf("cannot use %s as %v", describe(v), t)
+		}
+		var err error
+		if t.kind == setKind {
+			err = w.WriteSetBegin(thriftwire.SetHeader{Element: t.elem.Wire(), Size: len(elems)})
+		} else {
+			err = w.WriteListBegin(thriftwire.ListHeader{Element: t.elem.Wire(), Size: len(elems)})
+		}
+		if err != nil {
+			return err
+		}
+		for i, e := range elems {
+			if err := encode(w, t.elem, e); err != nil {
+				return atPath(err, "["+strconv.Itoa(i)+"]")
+			}
+		}
+		if t.kind == setKind {
+			return w.WriteSetEnd()
+		}
+		return w.WriteListEnd()
+	}
+	panic("unreachable")
+}
+
+func encodeStruct(w thriftwire.Writer, st *Struct, v any) error {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return errorf("cannot use %s as struct %s", describe(v), st.name)
+	}
+	for name := range m {
+		if _, ok := st.byName[name]; !ok {
+			return errorf("unknown field %s", name)
+		}
+	}
+	n := 0
+	for _, f := range st.fields {
+		if m[f.name] != nil {
+			n++
+		} else if f.required {
+			return errorf("missing required field %s", f.name)
+		}
+	}
+	if err := checkSet(st, n); err != nil {
+		return err
+	}
+
+	if err := w.WriteStructBegin(thriftwire.StructHeader{Name: st.name}); err != nil {
+		return err
+	}
+	for _, f := range st.fields {
+		fv := m[f.name]
+		if fv == nil {
+			continue
+		}
+		if err := w.WriteFieldBegin(thriftwire.FieldHeader{Name: f.name, Type: f.typ.Wire(), ID: f.id}); err != nil {
+			return err
+		}
+		if err := encode(w, f.typ, fv); err != nil {
+			return atPath(err, "."+f.name)
+		}
+		if err := w.WriteFieldEnd(); err != nil {
+			return err
+		}
+	}
+	return w.WriteStructEnd()
+}
+
+func encodeMap(w thriftwire.Writer, t *Type, v any) error {
+	h := thriftwire.MapHeader{Key: t.key.Wire(), Value: t.elem.Wire()}
+	switch v := v.(type) {
+	case map[string]any:
+		if !isScalar(t.key) {
+			return errorf("cannot use %s as %v with %v keys", describe(v), t, t.key)
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		h.Size = len(keys)
+		if err := w.WriteMapBegin(h); err != nil {
+			return err
+		}
+		for _, k := range keys {
+			path := "[" + strconv.Quote(k) + "]"
+			if err := encode(w, t.key, parseKey(t.key, k)); err != nil {
+				return atPath(err, path)
+			}
+			if err := encode(w, t.elem, v[k]); err != nil {
+				return atPath(err, path)
+			}
+		}
+	case []any:
+		h.Size = len(v)
+		if err := w.WriteMapBegin(h); err != nil {
+			return err
+		}
+		for i, e := range v {
+			path := "[" + strconv.Itoa(i) + "]"
+			pair, ok := e.([]any)
+			if !ok || len(pair) != 2 {
+				return atPath(errorf("cannot use %s as a [key, value] pair", describe(e)), path)
+			}
+			if err := encode(w, t.key, pair[0]); err != nil {
+				return atPath(err, path)
+			}
+			if err := encode(w, t.elem, pair[1]); err != nil {
+				return atPath(err, path)
+			}
+		}
+	default:
+		return errorf("cannot use %s as %v", describe(v), t)
+	}
+	return w.WriteMapEnd()
+}
+
+// isScalar reports whether values of type t can be map keys of JSON objects.
+func isScalar(t *Type) bool {
+	switch t.kind {
+	case structKind, mapKind, setKind, listKind:
+		return false
+	}
+	return true
+}
+
+// parseKey returns the JSON-like value of the map key k of type t.
+func parseKey(t *Type, k string) any {
+	switch t.kind {
+	case boolKind:
+		if b, err := strconv.ParseBool(k); err == nil {
+			return b
+		}
+	case byteKind, i16Kind, i32Kind, i64Kind, doubleKind:
+		return json.Number(k)
+	case enumKind:
+		if _, ok := t.enum.values[k]; !ok {
+			return json.Number(k)
+		}
+	}
+	return k
+}
+
+// toInt converts the number v to an integer of the given size.
+func toInt(t *Type, v any, bitSize int) (int64, error) {
+	var n int64
+	switch x := v.(type) {
+	case json.Number:
+		i, err := strconv.ParseInt(string(x), 10, 64)
+		if err != nil {
+			f, err := x.Float64()
+			if err != nil {
+				return 0, errorf("cannot use %s as %v", x, t)
+			}
+			return toInt(t, f, bitSize)
+		}
+		n = i
+	case float64, float32:
+		f := reflect.ValueOf(x).Float()
+		if f != math.Trunc(f) || f < -(1<<63) || f >= 1<<63 {
+			return 0, errorf("cannot use %v as %v", f, t)
+		}
+		n = int64(f)
+	default:
+		rv := reflect.ValueOf(v)
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			n = rv.Int()
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			if rv.Uint() > math.MaxInt64 {
+				return 0, errorf("%d overflows %v", rv.Uint(), t)
+			}
+			n = int64(rv.Uint())
+		default:
+			return 0, errorf("cannot use %s as %v", describe(v), t)
+		}
+	}
+	if bitSize < 64 && (n < -1<<(bitSize-1) || n >= 1<<(bitSize-1)) {
+		return 0, errorf("%d overflows %v", n, t)
+	}
+	return n, nil
+}
+
+// toFloat converts the number v to a double.
+func toFloat(t *Type, v any) (float64, error) {
+	switch x := v.(type) {
+	case json.Number:
+		f, err := x.Float64()
+		if err != nil {
+			return 0, errorf("cannot use %s as %v", x, t)
+		}
+		return f, nil
+	case float64:
+		return x, nil
+	case float32:
+		return float64(x), nil
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(rv.Uint()), nil
+	}
+	return 0, errorf("cannot use %s as %v", describe(v), t)
+}
+
+// describe describes the JSON-like value v in errors.
+func describe(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string " + strconv.Quote(v)
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case bool, float64, json.Number:
+		return fmt.Sprintf("%T %v", v, v)
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// A decoder decodes JSON-like values.
+type decoder struct {
+	r thriftwire.Reader
+
+	// named reports whether the reader identifies struct fields by name,
+	// and the expected types are used instead of the types in the headers.
+	named bool
+}
+
+func (d *decoder) decode(t *Type) (any, error) {
+	switch t.kind {
+	case boolKind:
+		return d.r.ReadBool()
+	case byteKind:
+		b, err := d.r.ReadByte()
+		return int8(b), err
+	case i16Kind:
+		return d.r.ReadI16()
+	case i32Kind:
+		return d.r.ReadI32()
+	case i64Kind:
+		return d.r.ReadI64()
+	case doubleKind:
+		return d.r.ReadDouble()
+	case stringKind:
+		return d.r.ReadString()
+	case binaryKind:
+		b, err := d.r.ReadBytes(nil)
+		if b == nil && err == nil {
+			b = []byte{}
+		}
+		return b, err
+	case uuidKind:
+		var u [16]byte
+		if err := d.r.ReadUUID(&u); err != nil {
+			return nil, err
+		}
+		return formatUUID(u), nil
+	case enumKind:
+		n, err := d.r.ReadI32()
+		if err != nil {
+			return nil, err
+		}
+		if name, ok := t.enum.names[n]; ok {
+			return name, nil
+		}
+		return n, nil // unknown values are kept
+	case structKind:
+		return d.decodeStruct(t.strct)
+	case mapKind:
+		return d.decodeMap(t)
+	case setKind:
+		h, err := d.r.ReadSetBegin()
+		if err != nil {
+			return nil, err
+		}
+		elems, err := d.decodeElems(t, h.Element, h.Size)
+		if err != nil {
+			return nil, err
+		}
+		return elems, d.r.ReadSetEnd()
+	case listKind:
+		h, err := d.r.ReadListBegin()
+		if err != nil {
+			return nil, err
+		}
+		elems, err := d.decodeElems(t, h.Element, h.Size)
+		if err != nil {
+			return nil, err
+		}
+		return elems, d.r.ReadListEnd()
+	}
+	panic("unreachable")
+}
+
+// checkType checks that the type of a value on the wire is the type of t.
+func (d *decoder) checkType(got thriftwire.Type, t *Type, size int) error {
+	if d.named || size <= 0 || got == t.Wire() {
+		return nil
+	}
+	return errorf("cannot read %v as %v", got, t)
+}
+
+func (d *decoder) decodeElems(t *Type, elem thriftwire.Type, size int) ([]any, error) {
+	if err := d.checkType(elem, t.elem, size); err != nil {
+		return nil, err
+	}
+	elems := []any{}
+	for i := 0; i < size; i++ {
+		e, err := d.decode(t.elem)
+		if err != nil {
+			return nil, atPath(err, "["+strconv.Itoa(i)+"]")
+		}
+		elems = append(elems, e)
+	}
+	return elems, nil
+}
+
+func (d *decoder) decodeStruct(st *Struct) (map[string]any, error) {
+	if _, err := d.r.ReadStructBegin(); err != nil {
+		return nil, err
+	}
+	m := make(map[string]any)
+	for {
+		h, err := d.r.ReadFieldBegin()
+		if err != nil {
+			return nil, err
+		}
+		if h.Type == thriftwire.Stop {
+			break
+		}
+		var f *Field
+		if d.named && h.Name != "" {
+			f = st.byName[h.Name]
+		} else {
+			f = st.byID[h.ID]
+		}
+		if f != nil && d.checkType(h.Type, f.typ, 1) != nil {
+			// Like an unknown field, a field of another type is skipped,
+			// since its type may have changed in another version of the schema.
+			f = nil
+		}
+		if f == nil {
+			if err := thriftwire.Skip(d.r, h.Type); err != nil {
+				return nil, err
+			}
+		} else {
+			v, err := d.decode(f.typ)
+			if err != nil {
+				return nil, atPath(err, "."+f.name)
+			}
+			m[f.name] = v
+		}
+		if err := d.r.ReadFieldEnd(); err != nil {
+			return nil, err
+		}
+	}
+	if err := d.r.ReadStructEnd(); err != nil {
+		return nil, err
+	}
+	for _, f := range st.fields {
+		if _, ok := m[f.name]; !ok && f.required {
+			return nil, errorf("missing required field %s", f.name)
+		}
+	}
+	if err := checkSet(st, len(m)); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// checkSet checks the number of fields of st that are set.
+// Exactly one field of a union, and at most one field of a result are set.
+func checkSet(st *Struct, n int) error {
+	switch {
+	case st.union && n != 1:
+		return errorf("%d fields are set, want exactly one", n)
+	case st.result && n > 1:
+		return errorf("%d fields are set, want at most one", n)
+	}
+	return nil
+}
+
+func (d *decoder) decodeMap(t *Type) (any, error) {
+	h, err := d.r.ReadMapBegin()
+	if err != nil {
+		return nil, err
+	}
+	if err := d.checkType(h.Key, t.key, h.Size); err != nil {
+		return nil, err
+	}
+	if err := d.checkType(h.Value, t.elem, h.Size); err != nil {
+		return nil, err
+	}
+	var v any
+	if isScalar(t.key) {
+		m := make(map[string]any)
+		for i := 0; i < h.Size; i++ {
+			k, err := d.decode(t.key)
+			if err != nil {
+				return nil, atPath(err, "["+strconv.Itoa(i)+"]")
+			}
+			key := formatKey(k)
+			e, err := d.decode(t.elem)
+			if err != nil {
+				return nil, atPath(err, "["+strconv.Quote(key)+"]")
+			}
+			m[key] = e
+		}
+		v = m
+	} else {
+		pairs := []any{}
+		for i := 0; i < h.Size; i++ {
+			k, err := d.decode(t.key)
+			if err != nil {
+				return nil, atPath(err, "["+strconv.Itoa(i)+"]")
+			}
+			e, err := d.decode(t.elem)
+			if err != nil {
+				return nil, atPath(err, "["+strconv.Itoa(i)+"]")
+			}
+			pairs = append(pairs, []any{k, e})
+		}
+		v = pairs
+	}
+	return v, d.r.ReadMapEnd()
+}
+
+// formatKey formats the JSON-like value of a scalar map key,
+// like encoding/json formats the keys and values of JSON objects.
+func formatKey(k any) string {
+	switch k := k.(type) {
+	case string:
+		return k
+	case []byte:
+		return base64.StdEncoding.EncodeToString(k)
+	case float64:
+		return strconv.FormatFloat(k, 'g', -1, 64)
+	}
+	return fmt.Sprint(k)
+}
+
+// formatUUID formats u in the canonical form.
+func formatUUID(u [16]byte) string {
+	s := hex.EncodeToString(u[:])
+	return s[0:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:]
+}
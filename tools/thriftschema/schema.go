@@ -0,0 +1,619 @@
+// Package thriftschema encodes and decodes Thrift values described by
+// parsed .thrift files, without generated Go types.
+//
+// Values are represented by JSON-like Go values, which are checked against
+// the types of the .thrift file:
+//
+//	bool                      bool
+//	byte, i8                  int8
+//	i16                       int16
+//	i32                       int32
+//	i64                       int64
+//	double                    float64
+//	string                    string
+//	binary                    []byte
+//	uuid                      string in the canonical form
+//	enums                     string of the name of the value, or int32
+//	                          if the value is not declared by the enum
+//	structs                   map[string]any keyed by field names
+//	maps                      map[string]any, or []any of [key, value] pairs
+//	sets and lists            []any
+//
+// When encoding, integers and doubles may be any Go number or
+// [encoding/json.Number], binary values may also be base64 strings, UUIDs
+// may also be [16]byte, and enum values may also be numbers, even if they
+// are not declared by the enum. Maps whose keys are not structs or containers
+// are decoded into map[string]any, with the keys formatted as strings,
+// and such map keys are parsed from strings when encoding.
+//
+// Fields that are missing or nil are not written, and required fields must
+// be present. Exactly one field of a union must be present.
+package thriftschema
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/itstarsun/go-thrift/encoding/thriftwire"
+	"github.com/itstarsun/go-thrift/tools/thriftfile"
+)
+
+// A Schema holds the resolved definitions of a .thrift file.
+// A Schema is safe for concurrent use by multiple goroutines.
+type Schema struct {
+	includes map[string]*Schema
+	defs     map[string]thriftfile.Def
+	structs  map[*thriftfile.Struct]*Struct
+	enums    map[*thriftfile.Enum]*enum
+	services []*Service
+}
+
+// New returns the Schema of the .thrift file f.
+// Includes maps the base names without extension of the files included by f,
+// which qualify the names of their definitions, to their Schemas.
+func New(f *thriftfile.File, includes map[string]*Schema) (*Schema, error) {
+	s := &Schema{
+		includes: make(map[string]*Schema),
+		defs:     make(map[string]thriftfile.Def),
+		structs:  make(map[*thriftfile.Struct]*Struct),
+		enums:    make(map[*thriftfile.Enum]*enum),
+	}
+	for _, h := range f.Headers {
+		if h, ok := h.(*thriftfile.Include); ok {
+			path, err := thriftfile.Unquote(h.Path.Value)
+			if err != nil {
+				return nil, fmt.Errorf("thriftschema: invalid include path %s", h.Path.Value)
+			}
+			name := thriftfile.IncludeName(path)
+			inc, ok := includes[name]
+			if !ok {
+				return nil, fmt.Errorf("thriftschema: missing schema of include %q", path)
+			}
+			s.includes[name] = inc
+		}
+	}
+
+	for _, d := range f.Defs {
+		var name string
+		switch d := d.(type) {
+		case *thriftfile.Typedef:
+			name = d.Name.Name
+		case *thriftfile.Enum:
+			name = d.Name.Name
+			e, err := newEnum(d)
+			if err != nil {
+				return nil, err
+			}
+			s.enums[d] = e
+		case *thriftfile.Struct:
+			name = d.Name.Name
+			s.structs[d] = &Struct{name: name, union: d.Union, exception: d.Exception}
+		case *thriftfile.Const:
+			name = d.Name.Name
+		case *thriftfile.Service:
+			name = d.Name.Name
+		default:
+			continue
+		}
+		if _, ok := s.defs[name]; ok {
+			return nil, fmt.Errorf("thriftschema: %s redeclared", name)
+		}
+		s.defs[name] = d
+	}
+
+	for _, d := range f.Defs {
+		switch d := d.(type) {
+		case *thriftfile.Struct:
+			if err := s.initStruct(s.structs[d], d.Fields); err != nil {
+				return nil, err
+			}
+		case *thriftfile.Service:
+			svc, err := s.newService(d)
+			if err != nil {
+				return nil, err
+			}
+			s.services = append(s.services, svc)
+		}
+	}
+	return s, nil
+}
+
+// Load parses the .thrift file at path and the files it includes, which
+// are searched for in the directory of the including file and then in
+// includeDirs, and returns the Schema of the file at path.
+func Load(path string, includeDirs ...string) (*Schema, error) {
+	l := &thriftfile.Loader{IncludeDirs: includeDirs}
+	f, err := l.Load(path)
+	if err != nil {
+		return nil, fmt.Errorf("thriftschema: %w", err)
+	}
+	return newLoaded(f, make(map[*thriftfile.LoadedFile]*Schema))
+}
+
+// newLoaded returns the Schema of the loaded file f, reusing the Schemas
+// of the files already converted.
+func newLoaded(f *thriftfile.LoadedFile, schemas map[*thriftfile.LoadedFile]*Schema) (*Schema, error) {
+	if s, ok := schemas[f]; ok {
+		return s, nil
+	}
+	includes := make(map[string]*Schema)
+	for name, inc := range f.Includes {
+		s, err := newLoaded(inc, schemas)
+		if err != nil {
+			return nil, err
+		}
+		includes[name] = s
+	}
+	s, err := New(f.File, includes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", f.Path, err)
+	}
+	schemas[f] = s
+	return s, nil
+}
+
+// lookup returns the definition named by name, which may be qualified by
+// the name of an included file, and the Schema it belongs to.
+func (s *Schema) lookup(name string) (*Schema, thriftfile.Def) {
+	if d, ok := s.defs[name]; ok {
+		return s, d
+	}
+	if i := strings.IndexByte(name, '.'); i >= 0 {
+		if inc, ok := s.includes[name[:i]]; ok {
+			if d, ok := inc.defs[name[i+1:]]; ok {
+				return inc, d
+			}
+		}
+	}
+	return nil, nil
+}
+
+// Struct returns the struct, union or exception named by name,
+// which may be qualified by the name of an included file,
+// or be a typedef of one.
+func (s *Schema) Struct(name string) (*Struct, error) {
+	t, err := s.resolve(&thriftfile.TypeRef{Name: &thriftfile.Ident{Name: name}})
+	if err != nil {
+		return nil, err
+	}
+	if t.kind != structKind {
+		return nil, fmt.Errorf("thriftschema: %s is not a struct", name)
+	}
+	return t.strct, nil
+}
+
+// Services returns the services defined in the file of s.
+func (s *Schema) Services() []*Service {
+	return s.services
+}
+
+// Service returns the service named by name,
+// which may be qualified by the name of an included file.
+func (s *Schema) Service(name string) (*Service, error) {
+	ds, d := s.lookup(name)
+	if d, ok := d.(*thriftfile.Service); ok {
+		for _, svc := range ds.services {
+			if svc.name == d.Name.Name {
+				return svc, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("thriftschema: undefined service %s", name)
+}
+
+// Method returns the method named by name, which is the name of
+// the service and the method separated by a dot, such as "Store.get".
+// The method may be inherited by the service.
+func (s *Schema) Method(name string) (*Method, error) {
+	i := strings.LastIndexByte(name, '.')
+	if i < 0 {
+		return nil, fmt.Errorf("thriftschema: method %s is not qualified by a service", name)
+	}
+	svc, err := s.Service(name[:i])
+	if err != nil {
+		return nil, err
+	}
+	m := svc.Method(name[i+1:])
+	if m == nil {
+		return nil, fmt.Errorf("thriftschema: undefined method %s", name)
+	}
+	return m, nil
+}
+
+// A Service is a service of a [Schema].
+type Service struct {
+	name    string
+	parent  *Service
+	methods []*Method
+}
+
+// Name returns the name of the service.
+func (svc *Service) Name() string {
+	return svc.name
+}
+
+// Methods returns the methods defined by the service,
+// excluding the inherited ones.
+func (svc *Service) Methods() []*Method {
+	return svc.methods
+}
+
+// Method returns the named method of the service, which may be inherited,
+// or nil if there is no such method.
+func (svc *Service) Method(name string) *Method {
+	for ; svc != nil; svc = svc.parent {
+		for _, m := range svc.methods {
+			if m.name == name {
+				return m
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Schema) newService(d *thriftfile.Service) (*Service, error) {
+	svc := &Service{name: d.Name.Name}
+	if d.Extends != nil {
+		var err error
+		if svc.parent, err = s.Service(d.Extends.Name); err != nil {
+			return nil, err
+		}
+	}
+	for _, dm := range d.Methods.List {
+		m := &Method{
+			name:   dm.Name.Name,
+			oneWay: dm.OneWay,
+			args:   &Struct{name: dm.Name.Name + "_args"},
+		}
+		if err := s.initStruct(m.args, dm.Arguments); err != nil {
+			return nil, err
+		}
+		if !dm.OneWay {
+			m.result = &Struct{name: dm.Name.Name + "_result", result: true}
+			var fields []*thriftfile.Field
+			if !dm.Void {
+				fields = append(fields, &thriftfile.Field{
+					ID:   &thriftfile.Int{Value: "0"},
+					Type: dm.Return,
+					Name: &thriftfile.Ident{Name: "success"},
+				})
+			}
+			if dm.Throws != nil {
+				fields = append(fields, dm.Throws.List...)
+			}
+			if err := s.initStruct(m.result, &thriftfile.FieldList{List: fields}); err != nil {
+				return nil, err
+			}
+		}
+		svc.methods = append(svc.methods, m)
+	}
+	return svc, nil
+}
+
+// A Method is a method of a [Service].
+type Method struct {
+	name   string
+	oneWay bool
+	args   *Struct
+	result *Struct
+}
+
+// Name returns the name of the method.
+func (m *Method) Name() string {
+	return m.name
+}
+
+// OneWay reports whether the method is one-way.
+func (m *Method) OneWay() bool {
+	return m.oneWay
+}
+
+// Args returns the struct of the arguments of the method,
+// whose fields are the parameters.
+func (m *Method) Args() *Struct {
+	return m.args
+}
+
+// Result returns the struct of the result of the method, or nil if the
+// method is one-way. The return value is the field "success" with ID 0,
+// unless the method is void, and the other fields are the declared
+// exceptions.
+func (m *Method) Result() *Struct {
+	return m.result
+}
+
+// A Struct is a struct, union or exception of a [Schema],
+// or the arguments or result of a [Method].
+type Struct struct {
+	name      string
+	union     bool
+	exception bool
+	result    bool
+	fields    []*Field
+	byName    map[string]*Field
+	byID      map[int16]*Field
+}
+
+// A Field is a field of a [Struct].
+type Field struct {
+	id       int16
+	name     string
+	required bool
+	typ      *Type
+}
+
+// ID returns the ID of the field.
+func (f *Field) ID() int16 {
+	return f.id
+}
+
+// Name returns the name of the field.
+func (f *Field) Name() string {
+	return f.name
+}
+
+// Required reports whether the field is required.
+func (f *Field) Required() bool {
+	return f.required
+}
+
+// Type returns the type of the field.
+func (f *Field) Type() *Type {
+	return f.typ
+}
+
+// Name returns the name of the struct.
+func (st *Struct) Name() string {
+	return st.name
+}
+
+// Fields returns the fields of the struct in declaration order.
+func (st *Struct) Fields() []*Field {
+	return st.fields
+}
+
+// FieldByID returns the field of the struct with the given ID,
+// or nil if there is no such field.
+func (st *Struct) FieldByID(id int16) *Field {
+	return st.byID[id]
+}
+
+// FieldByName returns the named field of the struct,
+// or nil if there is no such field.
+func (st *Struct) FieldByName(name string) *Field {
+	return st.byName[name]
+}
+
+func (s *Schema) initStruct(st *Struct, fl *thriftfile.FieldList) error {
+	st.byName = make(map[string]*Field)
+	st.byID = make(map[int16]*Field)
+	nextID := int16(-1) // implicit field IDs are negative like Apache Thrift
+	for _, df := range fl.List {
+		f := &Field{name: df.Name.Name, required: df.Required}
+		if df.ID != nil {
+			id, err := thriftfile.ParseInt(df.ID.Value, 16)
+			if err != nil {
+				return fmt.Errorf("thriftschema: invalid ID of field %s.%s: %v", st.name, f.name, err)
+			}
+			f.id = int16(id)
+		} else {
+			f.id = nextID
+			nextID--
+		}
+		if _, ok := st.byName[f.name]; ok {
+			return fmt.Errorf("thriftschema: duplicate field %s.%s", st.name, f.name)
+		}
+		if _, ok := st.byID[f.id]; ok {
+			return fmt.Errorf("thriftschema: duplicate ID %d of field %s.%s", f.id, st.name, f.name)
+		}
+		t, err := s.resolve(df.Type)
+		if err != nil {
+			return err
+		}
+		if st.result && f.id != 0 && (t.kind != structKind || !t.strct.exception) {
+			return fmt.Errorf("thriftschema: %s throws %s, which is not an exception", strings.TrimSuffix(st.name, "_result"), f.name)
+		}
+		f.typ = t
+		st.fields = append(st.fields, f)
+		st.byName[f.name] = f
+		st.byID[f.id] = f
+	}
+	return nil
+}
+
+// An enum is an enum of a Schema.
+type enum struct {
+	name   string
+	values map[string]int32
+	names  map[int32]string
+}
+
+func newEnum(d *thriftfile.Enum) (*enum, error) {
+	e := &enum{
+		name:   d.Name.Name,
+		values: make(map[string]int32),
+		names:  make(map[int32]string),
+	}
+	var next int64
+	for _, v := range d.Values.List {
+		if v.Value != nil {
+			n, err := thriftfile.ParseInt(v.Value.Value, 32)
+			if err != nil {
+				return nil, fmt.Errorf("thriftschema: invalid value of %s.%s: %v", e.name, v.Name.Name, err)
+			}
+			next = n
+		}
+		e.values[v.Name.Name] = int32(next)
+		if _, ok := e.names[int32(next)]; !ok {
+			e.names[int32(next)] = v.Name.Name
+		}
+		next++
+	}
+	return e, nil
+}
+
+// A kind is the category of a Thrift type.
+type kind uint8
+
+const (
+	boolKind kind = iota + 1
+	byteKind
+	i16Kind
+	i32Kind
+	i64Kind
+	doubleKind
+	stringKind
+	binaryKind
+	uuidKind
+	enumKind
+	structKind
+	mapKind
+	setKind
+	listKind
+)
+
+var baseKinds = map[string]kind{
+	"bool":   boolKind,
+	"byte":   byteKind,
+	"i8":     byteKind,
+	"i16":    i16Kind,
+	"i32":    i32Kind,
+	"i64":    i64Kind,
+	"double": doubleKind,
+	"string": stringKind,
+	"binary": binaryKind,
+	"uuid":   uuidKind,
+}
+
+// A Type is a type of a [Schema], with the typedefs resolved.
+type Type struct {
+	kind      kind
+	name      string // of base types, enums and structs
+	enum      *enum
+	strct     *Struct
+	key, elem *Type
+}
+
+// Wire returns the type of values of type t on the wire.
+func (t *Type) Wire() thriftwire.Type {
+	switch t.kind {
+	case boolKind:
+		return thriftwire.Bool
+	case byteKind:
+		return thriftwire.Byte
+	case i16Kind:
+		return thriftwire.I16
+	case i32Kind, enumKind:
+		return thriftwire.I32
+	case i64Kind:
+		return thriftwire.I64
+	case doubleKind:
+		return thriftwire.Double
+	case stringKind, binaryKind:
+		return thriftwire.String
+	case uuidKind:
+		return thriftwire.UUID
+	case structKind:
+		return thriftwire.Struct
+	case mapKind:
+		return thriftwire.Map
+	case setKind:
+		return thriftwire.Set
+	case listKind:
+		return thriftwire.List
+	}
+	panic("unreachable")
+}
+
+// Struct returns the struct of a struct type, or nil.
+func (t *Type) Struct() *Struct {
+	return t.strct
+}
+
+// Key returns the type of the keys of a map type, or nil.
+func (t *Type) Key() *Type {
+	return t.key
+}
+
+// Elem returns the type of the values of a map type,
+// or the type of the elements of a set or list type, or nil.
+func (t *Type) Elem() *Type {
+	return t.elem
+}
+
+// EnumName returns the name of the value v of an enum type,
+// and reports whether it is declared by the enum.
+func (t *Type) EnumName(v int32) (string, bool) {
+	if t.enum == nil {
+		return "", false
+	}
+	name, ok := t.enum.names[v]
+	return name, ok
+}
+
+// String returns the type as written in Thrift IDL.
+func (t *Type) String() string {
+	switch t.kind {
+	case mapKind:
+		return "map<" + t.key.String() + "," + t.elem.String() + ">"
+	case setKind:
+		return "set<" + t.elem.String() + ">"
+	case listKind:
+		return "list<" + t.elem.String() + ">"
+	}
+	return t.name
+}
+
+// resolve resolves the type t declared in the file of s.
+func (s *Schema) resolve(t thriftfile.Type) (*Type, error) {
+	switch t := t.(type) {
+	case *thriftfile.Map:
+		key, err := s.resolve(t.Key)
+		if err != nil {
+			return nil, err
+		}
+		elem, err := s.resolve(t.Value)
+		if err != nil {
+			return nil, err
+		}
+		return &Type{kind: mapKind, key: key, elem: elem}, nil
+	case *thriftfile.Set:
+		elem, err := s.resolve(t.Element)
+		if err != nil {
+			return nil, err
+		}
+		return &Type{kind: setKind, elem: elem}, nil
+	case *thriftfile.List:
+		elem, err := s.resolve(t.Element)
+		if err != nil {
+			return nil, err
+		}
+		return &Type{kind: listKind, elem: elem}, nil
+	case *thriftfile.TypeRef:
+		name := t.Name.Name
+		for i := 0; i < 100; i++ {
+			if k, ok := baseKinds[name]; ok {
+				return &Type{kind: k, name: name}, nil
+			}
+			ds, d := s.lookup(name)
+			switch d := d.(type) {
+			case *thriftfile.Typedef:
+				if ref, ok := d.Type.(*thriftfile.TypeRef); ok {
+					s, name = ds, ref.Name.Name
+					continue
+				}
+				return ds.resolve(d.Type)
+			case *thriftfile.Enum:
+				return &Type{kind: enumKind, name: d.Name.Name, enum: ds.enums[d]}, nil
+			case *thriftfile.Struct:
+				return &Type{kind: structKind, name: d.Name.Name, strct: ds.structs[d]}, nil
+			case nil:
+				return nil, fmt.Errorf("thriftschema: undefined type %s", name)
+			default:
+				return nil, fmt.Errorf("thriftschema: %s is not a type", name)
+			}
+		}
+		return nil, fmt.Errorf("thriftschema: typedef cycle at %s", name)
+	}
+	return nil, fmt.Errorf("thriftschema: invalid type %T", t)
+}
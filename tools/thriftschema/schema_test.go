@@ -0,0 +1,337 @@
+package thriftschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/itstarsun/go-thrift/encoding/thriftbinary"
+	"github.com/itstarsun/go-thrift/encoding/thriftcompact"
+	"github.com/itstarsun/go-thrift/encoding/thriftjson"
+	"github.com/itstarsun/go-thrift/encoding/thriftsimplejson"
+	"github.com/itstarsun/go-thrift/encoding/thriftwire"
+	"github.com/itstarsun/go-thrift/thrift"
+)
+
+func load(t *testing.T) *Schema {
+	t.Helper()
+	s, err := Load("testdata/store.thrift")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func decodeJSON(t *testing.T, s string) map[string]any {
+	t.Helper()
+	d := json.NewDecoder(strings.NewReader(s))
+	d.UseNumber()
+	var m map[string]any
+	if err := d.Decode(&m); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+const itemJSON = `{
+	"id": "00112233-4455-6677-8899-aabbccddeeff",
+	"name": "item",
+	"color": "BLUE",
+	"tags": ["a", "b"],
+	"children": [{"id": "00000000-0000-0000-0000-000000000001", "owner": {"name": "child"}}],
+	"weights": {"RED": 0.5, "16": 2},
+	"created": 1700000000000,
+	"data": "AQID",
+	"owner": {"name": "me", "level": 1},
+	"flags": -1,
+	"groups": [[[1, 2], true]]
+}`
+
+var wantItem = map[string]any{
+	"id":       "00112233-4455-6677-8899-aabbccddeeff",
+	"name":     "item",
+	"color":    "BLUE",
+	"tags":     []any{"a", "b"},
+	"children": []any{map[string]any{"id": "00000000-0000-0000-0000-000000000001", "owner": map[string]any{"name": "child"}}},
+	"weights":  map[string]any{"RED": 0.5, "BLUE": 2.0},
+	"created":  int64(1700000000000),
+	"data":     []byte{1, 2, 3},
+	"owner":    map[string]any{"name": "me", "level": "HIGH"},
+	"flags":    int8(-1),
+	"groups":   []any{[]any{[]any{int16(1), int16(2)}, true}},
+}
+
+func TestStruct(t *testing.T) {
+	s := load(t)
+	st, err := s.Struct("Entry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []thriftwire.Protocol{
+		thriftbinary.Protocol,
+		thriftcompact.Protocol,
+		thriftjson.Protocol,
+		thriftsimplejson.Protocol,
+	} {
+		t.Run(fmt.Sprint(p), func(t *testing.T) {
+			in, want := decodeJSON(t, itemJSON), wantItem
+			if p == thriftsimplejson.Protocol {
+				// Simple JSON does not support maps with list keys.
+				want = make(map[string]any)
+				for k, v := range wantItem {
+					want[k] = v
+				}
+				delete(in, "groups")
+				delete(want, "groups")
+			}
+			var b bytes.Buffer
+			w := p.NewWriter(&b)
+			if err := st.Marshal(w, in); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Flush(); err != nil {
+				t.Fatal(err)
+			}
+			got, err := st.Unmarshal(p.NewReader(&b))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %#v, want %#v", got, want)
+			}
+		})
+	}
+}
+
+// owner is the Go type of shared.Owner.
+type owner struct {
+	Name  string `thrift:"1,required"`
+	Level int32  `thrift:"2"`
+}
+
+func TestStructGoType(t *testing.T) {
+	s := load(t)
+	st, err := s.Struct("shared.Owner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	w := thriftbinary.Protocol.NewWriter(&b)
+	if err := thrift.Marshal(w, &Value{Struct: st, Fields: map[string]any{"name": "me", "level": "HIGH"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	var want bytes.Buffer
+	w = thriftbinary.Protocol.NewWriter(&want)
+	if err := thrift.Marshal(w, &owner{Name: "me", Level: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b.Bytes(), want.Bytes()) {
+		t.Errorf("got %x, want %x", b.Bytes(), want.Bytes())
+	}
+
+	v := &Value{Struct: st}
+	if err := thrift.Unmarshal(thriftbinary.Protocol.NewReader(&want), v); err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]any{"name": "me", "level": "HIGH"}; !reflect.DeepEqual(v.Fields, want) {
+		t.Errorf("got %v, want %v", v.Fields, want)
+	}
+}
+
+func TestMethod(t *testing.T) {
+	s := load(t)
+	for _, tt := range []struct {
+		name   string
+		args   []string
+		result []string
+		oneWay bool
+	}{
+		{"Store.get", []string{"id"}, []string{"success", "notFound"}, false},
+		{"Store.put", []string{"item", "value"}, []string{}, false},
+		{"Store.touch", []string{"id"}, nil, true},
+		{"Store.ping", []string{}, []string{}, false},
+		{"shared.Pinger.ping", []string{}, []string{}, false},
+	} {
+		m, err := s.Method(tt.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.OneWay() != tt.oneWay {
+			t.Errorf("%s: OneWay() = %v", tt.name, m.OneWay())
+		}
+		if got := fieldNames(m.Args()); !reflect.DeepEqual(got, tt.args) {
+			t.Errorf("%s: args %v, want %v", tt.name, got, tt.args)
+		}
+		if m.Result() == nil {
+			if tt.result != nil {
+				t.Errorf("%s: no result", tt.name)
+			}
+		} else if got := fieldNames(m.Result()); !reflect.DeepEqual(got, tt.result) {
+			t.Errorf("%s: result %v, want %v", tt.name, got, tt.result)
+		}
+	}
+	for _, name := range []string{"get", "Store.delete", "Nope.get", "Item.get"} {
+		if _, err := s.Method(name); err == nil {
+			t.Errorf("found method %s", name)
+		}
+	}
+}
+
+func fieldNames(st *Struct) []string {
+	names := []string{}
+	for _, f := range st.fields {
+		names = append(names, f.Name())
+	}
+	return names
+}
+
+func TestUndeclaredEnumValue(t *testing.T) {
+	s := load(t)
+	st, err := s.Struct("Item")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want bytes.Buffer
+	in := decodeJSON(t, `{"id": "00000000-0000-0000-0000-000000000000", "color": 3}`)
+	w := thriftbinary.Protocol.NewWriter(&want)
+	if err := st.Marshal(w, in); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	v, err := st.Unmarshal(thriftbinary.Protocol.NewReader(bytes.NewReader(want.Bytes())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := v["color"]; got != int32(3) {
+		t.Fatalf("got color %#v, want int32(3)", got)
+	}
+
+	// Encode the decoded value again, as after a round trip through JSON.
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got bytes.Buffer
+	w = thriftbinary.Protocol.NewWriter(&got)
+	if err := st.Marshal(w, decodeJSON(t, string(b))); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got.Bytes(), want.Bytes()) {
+		t.Errorf("got %x, want %x", got.Bytes(), want.Bytes())
+	}
+}
+
+func TestMarshalErrors(t *testing.T) {
+	s := load(t)
+	for _, tt := range []struct {
+		name string
+		json string
+		err  string
+	}{
+		{"Item", `{}`, "Item: missing required field id"},
+		{"Item", `{"id": "x"}`, `Item.id: invalid UUID "x"`},
+		{"Item", `{"id": "00000000-0000-0000-0000-000000000000", "nope": 1}`, "Item: unknown field nope"},
+		{"Item", `{"id": "00000000-0000-0000-0000-000000000000", "color": "PINK"}`, "Item.color: PINK is not a value of enum Color"},
+		{"Item", `{"id": "00000000-0000-0000-0000-000000000000", "flags": 128}`, "Item.flags: 128 overflows byte"},
+		{"Item", `{"id": "00000000-0000-0000-0000-000000000000", "created": 1.5}`, "Item.created: cannot use 1.5 as i64"},
+		{"Item", `{"id": "00000000-0000-0000-0000-000000000000", "tags": ["a", 1]}`, "Item.tags[1]: cannot use json.Number 1 as string"},
+		{"Item", `{"id": "00000000-0000-0000-0000-000000000000", "children": [{}]}`, "Item.children[0]: missing required field id"},
+		{"Item", `{"id": "00000000-0000-0000-0000-000000000000", "weights": {"PINK": 1}}`, `Item.weights["PINK"]: cannot use PINK as Color`},
+		{"Item", `{"id": "00000000-0000-0000-0000-000000000000", "groups": {"a": true}}`, "Item.groups: cannot use object as map<list<i16>,bool> with list<i16> keys"},
+		{"Value", `{}`, "Value: 0 fields are set, want exactly one"},
+		{"Value", `{"flag": true, "text": "x"}`, "Value: 2 fields are set, want exactly one"},
+	} {
+		st, err := s.Struct(tt.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = st.Marshal(thriftbinary.Protocol.NewWriter(new(bytes.Buffer)), decodeJSON(t, tt.json))
+		var e *Error
+		if !errors.As(err, &e) || e.Error() != "thriftschema: "+tt.err {
+			t.Errorf("%s: got %v, want %s", tt.json, err, tt.err)
+		}
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	s := load(t)
+	st, err := s.Struct("shared.Owner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		v   any
+		err string
+	}{
+		{&struct{}{}, "Owner: missing required field name"},
+		{&struct {
+			Name int32 `thrift:"1"`
+		}{Name: 1}, "Owner: missing required field name"},
+	} {
+		var b bytes.Buffer
+		w := thriftbinary.Protocol.NewWriter(&b)
+		if err := thrift.Marshal(w, tt.v); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		_, err := st.Unmarshal(thriftbinary.Protocol.NewReader(&b))
+		if err == nil || err.Error() != "thriftschema: "+tt.err {
+			t.Errorf("got %v, want %s", err, tt.err)
+		}
+	}
+}
+
+func TestUnmarshalMismatch(t *testing.T) {
+	s := load(t)
+	st, err := s.Struct("shared.Owner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	w := thriftbinary.Protocol.NewWriter(&b)
+	if err := thrift.Marshal(w, &struct {
+		Name  string `thrift:"1"`
+		Level string `thrift:"2"`
+	}{"me", "HIGH"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	got, err := st.Unmarshal(thriftbinary.Protocol.NewReader(&b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]any{"name": "me"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestSchemaErrors(t *testing.T) {
+	s := load(t)
+	for _, name := range []string{"Color", "Weights", "Nope", "shared.Nope"} {
+		if _, err := s.Struct(name); err == nil {
+			t.Errorf("found struct %s", name)
+		}
+	}
+	if _, err := Load("testdata/nope.thrift"); err == nil {
+		t.Errorf("loaded a missing file")
+	}
+}
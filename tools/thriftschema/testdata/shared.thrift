@@ -0,0 +1,15 @@
+namespace go shared
+
+enum Level {
+  LOW,
+  HIGH,
+}
+
+struct Owner {
+  1: required string name,
+  2: Level level,
+}
+
+service Pinger {
+  void ping(),
+}
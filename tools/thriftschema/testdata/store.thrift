@@ -0,0 +1,43 @@
+include "shared.thrift"
+
+typedef binary Blob
+typedef map<Color, double> Weights
+
+enum Color {
+  RED = 1,
+  GREEN,
+  BLUE = 0x10,
+}
+
+struct Item {
+  1: required uuid id,
+  2: string name,
+  3: optional Color color,
+  4: set<string> tags,
+  5: list<Item> children,
+  6: Weights weights,
+  7: i64 created,
+  8: optional Blob data,
+  9: shared.Owner owner,
+  10: byte flags,
+  11: map<list<i16>, bool> groups,
+}
+
+typedef Item Entry
+
+union Value {
+  1: bool flag,
+  2: i64 number,
+  3: string text,
+}
+
+exception NotFound {
+  1: uuid id,
+  2: string message,
+}
+
+service Store extends shared.Pinger {
+  Entry get(1: uuid id) throws (1: NotFound notFound),
+  void put(1: Item item, 2: Value value),
+  oneway void touch(1: uuid id),
+}